@@ -9,11 +9,19 @@ import (
 	"syscall"
 	"time"
 
+	"nms/pkg/Services/alerting"
+	"nms/pkg/Services/availability"
 	"nms/pkg/Services/discovery"
+	"nms/pkg/Services/escalation"
+	"nms/pkg/Services/exporter"
 	"nms/pkg/Services/monitorFailure"
+	"nms/pkg/Services/notification"
 	"nms/pkg/Services/persistence"
 	"nms/pkg/Services/polling"
+	"nms/pkg/Services/recovery"
+	"nms/pkg/Services/retention"
 	"nms/pkg/Services/scheduling"
+	"nms/pkg/Services/stream"
 
 	"nms/pkg/config"
 
@@ -34,12 +42,31 @@ type services struct {
 	metricsService *persistence.MetricsService
 	entityService  *persistence.EntityService
 	failureService *monitorFailure.FailureService
+	recovery       *recovery.RecoveryService
+	availability   *availability.AvailabilityService
+	alerts         *alerting.AlertService
+	notifications  *notification.NotificationService
+	escalation     *escalation.EscalationService
+	retention      *retention.RetentionService
+	rollups        *persistence.RollupService
+	exports        *exporter.ExportService
+	stream         *stream.StreamService
 }
 
 // apiChannels holds request channels used by API handlers
 type apiChannels struct {
 	crudRequest       chan models.Request
 	metricRequest     chan models.Request
+	schedulerRequest  chan models.Request
+	pollRequest       chan models.Request
+	availabilityReq   chan models.Request
+	failureRequest    chan models.Request
+	alertRequest      chan models.Request
+	notifyRequest     chan models.Request
+	oncallRequest     chan models.Request
+	retentionRequest  chan models.Request
+	exportRequest     chan models.Request
+	streamRequest     chan models.Request
 	provisioningEvent chan models.Event
 }
 
@@ -67,7 +94,7 @@ func main() {
 	services, channels := initServices(conf, db, fpingPath)
 
 	// Load caches in EntityService and initialize Scheduler queue
-	loadInitialData(services.entityService, services.sched)
+	loadInitialData(services.entityService, services.sched, services.availability, services.retention, services.metricsService)
 
 	// Create context that cancels on SIGINT or SIGTERM
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -154,9 +181,26 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 	pollResultChan := make(chan []plugin.Result, DataBufferSize)
 	schedulerToPollerChan := make(chan []*models.Device, ControlBufferSize)
 	failureChan := make(chan models.Event, EventBufferSize) // Shared by Scheduler + MetricsWriter
+	pollOutcomeChan := make(chan models.Event, DataBufferSize)
+	availabilityChan := make(chan models.Event, DataBufferSize)
+	alertResultChan := make(chan []plugin.Result, DataBufferSize)
+	alertRuleChan := make(chan models.Event, EventBufferSize)
+	notificationChan := make(chan models.Event, EventBufferSize) // Shared by EntityService + AlertService + EscalationService
+	exportBatchChan := make(chan exporter.PollBatch, DataBufferSize)
+	streamEventChan := make(chan models.StreamEvent, DataBufferSize) // Shared by MetricsService + EntityService + FailureService
 
 	crudRequestChan := make(chan models.Request, EventBufferSize)
 	metricRequestChan := make(chan models.Request, EventBufferSize)
+	schedulerRequestChan := make(chan models.Request, EventBufferSize)
+	pollRequestChan := make(chan models.Request, ControlBufferSize)
+	availabilityRequestChan := make(chan models.Request, EventBufferSize)
+	failureRequestChan := make(chan models.Request, ControlBufferSize)
+	alertRequestChan := make(chan models.Request, EventBufferSize)
+	notifyRequestChan := make(chan models.Request, ControlBufferSize)
+	oncallRequestChan := make(chan models.Request, ControlBufferSize)
+	retentionRequestChan := make(chan models.Request, ControlBufferSize)
+	exportRequestChan := make(chan models.Request, ControlBufferSize)
+	streamRequestChan := make(chan models.Request, ControlBufferSize)
 	provisioningEventChan := make(chan models.Event, EventBufferSize)
 
 	// ══════════════════════════════════════════════════════════════
@@ -171,6 +215,9 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		db,
 		discProfileChan,
 		deviceChan,
+		alertRuleChan,
+		notificationChan,
+		streamEventChan,
 	)
 
 	// Scheduler uses crudRequestChan to request devices from EntityService
@@ -179,10 +226,17 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		crudRequestChan,
 		schedulerToPollerChan,
 		failureChan,
+		pollOutcomeChan,
+		provisioningEventChan,
+		schedulerRequestChan,
 		fpingPath,
 		conf.PollIntervalSec,
 		conf.AvCheckTimeoutMs,
 		conf.AvCheckRetries,
+		conf.SchedulerOverloadPolicy,
+		conf.SchedulerLateToleranceSec,
+		conf.BackoffMultiplier,
+		conf.BackoffMaxIntervalSec,
 	)
 
 	// Poller uses crudRequestChan to request credentials from EntityService
@@ -194,6 +248,10 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		crudRequestChan,
 		schedulerToPollerChan,
 		pollResultChan,
+		pollRequestChan,
+		fpingPath,
+		conf.AvCheckTimeoutMs,
+		conf.AvCheckRetries,
 	)
 
 	// Create separate DB pools for metrics components
@@ -215,6 +273,28 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		os.Exit(1)
 	}
 
+	// ExportService forwards stored poll batches to remote_write / OTLP backends
+	exportService := exporter.NewExportService(
+		exportBatchChan,
+		crudRequestChan,
+		exportRequestChan,
+		exportSinks(conf),
+		conf.ExportQueueSize,
+		conf.ExportMaxAttempts,
+		conf.ExportRetryBaseSec,
+		conf.ExportTimeoutSec,
+	)
+	var exportChan chan<- exporter.PollBatch
+	if exportService.Enabled() {
+		exportChan = exportBatchChan
+	}
+
+	rollupRetention := persistence.RollupRetention{
+		Raw:    conf.MetricsRetentionDays,
+		Minute: conf.MetricsRollup1mRetentionDays,
+		Hour:   conf.MetricsRollup1hRetentionDays,
+		Day:    conf.MetricsRollup1dRetentionDays,
+	}
 	metricsService := persistence.NewMetricsService(
 		pollResultChan,
 		metricRequestChan,
@@ -222,10 +302,18 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		metricsReadDB,
 		conf.MetricsWorkerCount,
 		failureChan,
+		pollOutcomeChan,
+		alertResultChan,
+		exportChan,
+		streamEventChan,
 		conf.MetricsDefaultLimit,
 		conf.MetricsDefaultLookbackHours,
+		rollupRetention,
 	)
 
+	// RollupService aggregates numeric metrics into 1m/1h/1d tables for long-range queries
+	rollupService := persistence.NewRollupService(metricsWriteDB, conf.MetricsRollupIntervalSec, rollupRetention)
+
 	discService := discovery.NewDiscoveryService(
 		discProfileChan,
 		discResultChan,
@@ -235,12 +323,104 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		EventBufferSize,
 	)
 
-	// FailureService tracks failures and deactivates devices
+	// FailureService applies failure policies (deactivate, degrade, alert)
 	healthMonitor := monitorFailure.NewHealthMonitor(
 		failureChan,
 		crudRequestChan,
+		availabilityChan,
+		failureRequestChan,
+		provisioningEventChan,
+		streamEventChan,
 		conf.FailureWindowMin,
 		conf.FailureThreshold,
+		conf.FlapWindowChecks,
+		conf.FlapHighThreshold,
+		conf.FlapLowThreshold,
+	)
+
+	// RecoveryService probes inactive devices and reactivates them via EntityService
+	recoveryService := recovery.NewRecoveryService(
+		crudRequestChan,
+		conf.PluginsDir,
+		conf.EncryptionKey,
+		fpingPath,
+		conf.AvCheckTimeoutMs,
+		conf.AvCheckRetries,
+		conf.RecoveryWorkerCount,
+		ControlBufferSize,
+		conf.RecoveryIntervalSec,
+		conf.RecoverySuccessThreshold,
+	)
+
+	// AvailabilityService records outcomes forwarded by FailureService and serves SLA reports
+	availabilityService := availability.NewAvailabilityService(
+		availabilityChan,
+		availabilityRequestChan,
+		db,
+		conf.AvailabilityDefaultRangeHours,
+	)
+
+	// AlertService evaluates threshold and anomaly rules against poll results forwarded by MetricsService
+	alertService := alerting.NewAlertService(
+		alertResultChan,
+		alertRuleChan,
+		alertRequestChan,
+		crudRequestChan,
+		notificationChan,
+		db,
+		alerting.AnomalyConfig{
+			Paths:       conf.AnomalyPathList(),
+			Alpha:       conf.AnomalyAlpha,
+			Deviations:  conf.AnomalyDeviations,
+			MinSamples:  conf.AnomalyMinSamples,
+			Seasonality: conf.AnomalySeasonality,
+		},
+	)
+
+	// NotificationService routes device status changes and alerts to webhook, chat and email channels
+	notificationService := notification.NewNotificationService(
+		notificationChan,
+		notifyRequestChan,
+		db,
+		conf.EncryptionKey,
+		notification.SMTPConfig{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.SMTPFrom,
+		},
+		conf.NotifyWorkerCount,
+		conf.NotifyMaxAttempts,
+		conf.NotifyRetryBaseSec,
+		conf.NotifyHTTPTimeoutSec,
+	)
+
+	// EscalationService re-notifies unacknowledged alerts step by step, resolving on-call recipients
+	escalationService := escalation.NewEscalationService(
+		notificationChan,
+		oncallRequestChan,
+		db,
+		conf.EscalationCheckIntervalSec,
+	)
+
+	// RetentionService keeps metrics partitions ahead of time and drops the ones past retention
+	retentionService := retention.NewRetentionService(
+		retentionRequestChan,
+		db,
+		conf.MetricsPartitionInterval,
+		conf.MetricsPartitionPremake,
+		conf.MetricsRetentionDays,
+		conf.MetricsRetentionCheckMin,
+	)
+
+	// StreamService fans poll results and device events out to SSE subscribers
+	streamService := stream.NewStreamService(
+		streamEventChan,
+		streamRequestChan,
+		conf.StreamReplaySize,
+		conf.StreamSubscriberBuffer,
+		conf.StreamMaxSubscribers,
 	)
 
 	svc := &services{
@@ -250,18 +430,37 @@ func initServices(conf *config.Config, db *sqlx.DB, fpingPath string) (*services
 		metricsService: metricsService,
 		entityService:  entityService,
 		failureService: healthMonitor,
+		recovery:       recoveryService,
+		availability:   availabilityService,
+		alerts:         alertService,
+		notifications:  notificationService,
+		escalation:     escalationService,
+		retention:      retentionService,
+		rollups:        rollupService,
+		exports:        exportService,
+		stream:         streamService,
 	}
 
 	channels := &apiChannels{
 		crudRequest:       crudRequestChan,
 		metricRequest:     metricRequestChan,
+		schedulerRequest:  schedulerRequestChan,
+		pollRequest:       pollRequestChan,
+		availabilityReq:   availabilityRequestChan,
+		failureRequest:    failureRequestChan,
+		alertRequest:      alertRequestChan,
+		notifyRequest:     notifyRequestChan,
+		oncallRequest:     oncallRequestChan,
+		retentionRequest:  retentionRequestChan,
+		exportRequest:     exportRequestChan,
+		streamRequest:     streamRequestChan,
 		provisioningEvent: provisioningEventChan,
 	}
 
 	return svc, channels
 }
 
-func loadInitialData(entityService *persistence.EntityService, sched *scheduling.Scheduler) {
+func loadInitialData(entityService *persistence.EntityService, sched *scheduling.Scheduler, availabilityService *availability.AvailabilityService, retentionService *retention.RetentionService, metricsService *persistence.MetricsService) {
 	// Load caches in EntityService
 	if err := entityService.LoadCaches(context.Background()); err != nil {
 		slog.Error("Failed to load EntityService caches", "error", err)
@@ -272,6 +471,23 @@ func loadInitialData(entityService *persistence.EntityService, sched *scheduling
 	deviceIDs := entityService.GetActiveDeviceIDs()
 	sched.InitQueue(deviceIDs)
 	slog.Info("Scheduler queue initialized", "device_count", len(deviceIDs))
+
+	// Continue availability history from each device's last recorded state
+	if err := availabilityService.LoadOpenIntervals(context.Background()); err != nil {
+		slog.Error("Failed to load availability intervals", "error", err)
+		os.Exit(1)
+	}
+
+	// Metrics can only be written once a partition covers the current time
+	if err := retentionService.EnsurePartitions(context.Background()); err != nil {
+		slog.Error("Failed to create metrics partitions", "error", err)
+		os.Exit(1)
+	}
+
+	// Current values are served from memory; a failed load only leaves them empty until the next polls
+	if err := metricsService.LoadLatestValues(context.Background()); err != nil {
+		slog.Error("Failed to load latest metric values", "error", err)
+	}
 }
 
 func startServices(ctx context.Context, svc *services) {
@@ -281,6 +497,15 @@ func startServices(ctx context.Context, svc *services) {
 	go svc.metricsService.Run(ctx)
 	go svc.entityService.Run(ctx)
 	go svc.failureService.Run(ctx)
+	go svc.recovery.Run(ctx)
+	go svc.availability.Run(ctx)
+	go svc.alerts.Run(ctx)
+	go svc.notifications.Run(ctx)
+	go svc.escalation.Run(ctx)
+	go svc.retention.Run(ctx)
+	go svc.rollups.Run(ctx)
+	go svc.exports.Run(ctx)
+	go svc.stream.Run(ctx)
 }
 
 func initRouter(conf *config.Config, auth *api.JwtAuth, channels *apiChannels) *gin.Engine {
@@ -290,6 +515,13 @@ func initRouter(conf *config.Config, auth *api.JwtAuth, channels *apiChannels) *
 	// Public routes (no auth)
 	router.POST("/login", auth.LoginHandler)
 
+	// Prometheus scrapes authenticate with their own token
+	api.RegisterPrometheusRoutes(&router.RouterGroup, conf.PrometheusScrapeToken,
+		time.Duration(conf.PrometheusMaxAgeSec)*time.Second, prometheusMapper(conf), channels.metricRequest)
+
+	// Grafana JSON datasources authenticate with their own token
+	api.RegisterGrafanaRoutes(&router.RouterGroup, conf.GrafanaAPIToken, channels.metricRequest, channels.crudRequest)
+
 	// Protected routes
 	apiGroup := router.Group("/api/v1")
 	apiGroup.Use(auth.JWTMiddleware())
@@ -297,11 +529,69 @@ func initRouter(conf *config.Config, auth *api.JwtAuth, channels *apiChannels) *
 		api.RegisterEntityRoutes[models.CredentialProfile](apiGroup, "/credentials", "CredentialProfile", conf.EncryptionKey, channels.crudRequest)
 		api.RegisterEntityRoutes[models.Device](apiGroup, "/devices", "Device", conf.EncryptionKey, channels.crudRequest)
 		api.RegisterEntityRoutes[models.DiscoveryProfile](apiGroup, "/discovery_profiles", "DiscoveryProfile", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.FailurePolicy](apiGroup, "/failure_policies", "FailurePolicy", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.AlertRule](apiGroup, "/alert_rules", "AlertRule", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.NotificationChannel](apiGroup, "/notification_channels", "NotificationChannel", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.NotificationRoute](apiGroup, "/notification_routes", "NotificationRoute", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.EscalationPolicy](apiGroup, "/escalation_policies", "EscalationPolicy", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.OnCallSchedule](apiGroup, "/oncall_schedules", "OnCallSchedule", conf.EncryptionKey, channels.crudRequest)
+		api.RegisterEntityRoutes[models.OnCallOverride](apiGroup, "/oncall_overrides", "OnCallOverride", conf.EncryptionKey, channels.crudRequest)
 		api.RegisterMetricsRoute(apiGroup, channels.metricRequest)
+		api.RegisterTopMetricsRoutes(apiGroup, channels.metricRequest, channels.crudRequest)
+		api.RegisterLatestMetricsRoutes(apiGroup, channels.metricRequest)
+		api.RegisterSchedulerRoutes(apiGroup, channels.schedulerRequest)
+		api.RegisterDependencyRoutes(apiGroup, channels.crudRequest)
+		api.RegisterAvailabilityRoutes(apiGroup, channels.availabilityReq)
+		api.RegisterFailurePolicyRoutes(apiGroup, channels.crudRequest, channels.failureRequest)
+		api.RegisterFlapRoutes(apiGroup, channels.crudRequest, channels.failureRequest)
+		api.RegisterAlertRoutes(apiGroup, channels.alertRequest)
+		api.RegisterAnomalyRoutes(apiGroup, channels.alertRequest)
+		api.RegisterNotificationRoutes(apiGroup, channels.notifyRequest)
+		api.RegisterOnCallRoutes(apiGroup, channels.oncallRequest)
+		api.RegisterRetentionRoutes(apiGroup, channels.retentionRequest)
+		api.RegisterExportRoutes(apiGroup, channels.exportRequest)
+		api.RegisterStreamRoutes(apiGroup, channels.streamRequest)
 
 		apiGroup.POST("/discovery_profiles/:id/run", api.RunDiscoveryHandler(channels.provisioningEvent))
 		apiGroup.POST("/devices/:id/provision", api.ProvisionDeviceHandler(channels.provisioningEvent))
+		apiGroup.GET("/devices/:id/transitions", api.DeviceTransitionsHandler(channels.crudRequest))
+		apiGroup.POST("/devices/:id/poll", api.PollNowHandler(channels.pollRequest, conf.PollNowTimeoutSec))
 	}
 
 	return router
 }
+
+// prometheusMapper builds the exposition mapper from PROMETHEUS_MAPPINGS.
+func prometheusMapper(conf *config.Config) *exporter.Mapper {
+	return exporter.NewMapper(exporterMappings(conf.PrometheusMappings))
+}
+
+// exportSinks builds the configured export sinks. A sink without its own mappings uses PROMETHEUS_MAPPINGS.
+func exportSinks(conf *config.Config) []exporter.SinkConfig {
+	var sinks []exporter.SinkConfig
+	add := func(kind, url, token string, mappings map[string]config.PrometheusMapping) {
+		if url == "" {
+			return
+		}
+		if len(mappings) == 0 {
+			mappings = conf.PrometheusMappings
+		}
+		sinks = append(sinks, exporter.SinkConfig{Kind: kind, URL: url, BearerToken: token, Mappings: exporterMappings(mappings)})
+	}
+	add(exporter.SinkRemoteWrite, conf.ExportRemoteWriteURL, conf.ExportRemoteWriteBearerToken, conf.ExportRemoteWriteMappings)
+	add(exporter.SinkOTLP, conf.ExportOTLPURL, conf.ExportOTLPBearerToken, conf.ExportOTLPMappings)
+	return sinks
+}
+
+// exporterMappings converts configured mappings to the exporter's form.
+func exporterMappings(configured map[string]config.PrometheusMapping) map[string]exporter.Mapping {
+	mappings := make(map[string]exporter.Mapping, len(configured))
+	for pluginID, mapping := range configured {
+		rules := make([]exporter.LabelRule, 0, len(mapping.Labels))
+		for _, rule := range mapping.Labels {
+			rules = append(rules, exporter.LabelRule{Match: rule.Match, Labels: rule.Labels})
+		}
+		mappings[pluginID] = exporter.Mapping{Prefix: mapping.Prefix, Labels: rules, Exclude: mapping.Exclude}
+	}
+	return mappings
+}
@@ -0,0 +1,302 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"nms/pkg/database"
+	"nms/pkg/models"
+	"nms/pkg/plugin"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// silenceCheckInterval is how often ended silences are swept and their alerts unmuted.
+const silenceCheckInterval = 30 * time.Second
+
+// alertKey identifies the alert state of one rule on one device.
+type alertKey struct {
+	ruleID   int64
+	deviceID int64
+}
+
+// pendingAlert is a breach that has not yet lasted the rule's "for" duration.
+type pendingAlert struct {
+	since time.Time
+	value float64
+}
+
+// AlertService evaluates threshold and anomaly alert rules against poll results and manages the
+// alert lifecycle (firing, acknowledged, silenced, resolved). It also owns the anomaly detector,
+// which keeps rolling baselines of the tracked metric paths.
+// Breaches are pending in memory until they last the rule's "for" duration; only open
+// and resolved instances are stored in Postgres.
+type AlertService struct {
+	results       <-chan []plugin.Result // Input: poll results forwarded by MetricsService
+	ruleEvents    <-chan models.Event    // Input: alert rule changes from EntityService
+	requests      <-chan models.Request  // Input: list and lifecycle requests from the API
+	entityReqChan chan<- models.Request  // Output: rule and device lookups to EntityService
+	notifications chan<- models.Event    // Output: firing/resolved alerts to NotificationService
+
+	db           *sqlx.DB
+	instanceRepo database.Repository[models.AlertInstance]
+	silenceRepo  database.Repository[models.AlertSilence]
+	anomalyRepo  database.Repository[models.MetricAnomaly]
+
+	// Owned by the Run goroutine
+	rules    map[int64]*models.AlertRule
+	pending  map[alertKey]*pendingAlert
+	open     map[alertKey]*models.AlertInstance
+	silences map[int64]*models.AlertSilence // Silences that have not ended
+	devices  map[int64]*models.Device       // Last seen copy of each evaluated device, for notifications
+	detector *detector
+}
+
+// NewAlertService creates a new AlertService instance.
+func NewAlertService(
+	results <-chan []plugin.Result,
+	ruleEvents <-chan models.Event,
+	requests <-chan models.Request,
+	entityReqChan chan<- models.Request,
+	notifications chan<- models.Event,
+	db *sqlx.DB,
+	anomaly AnomalyConfig,
+) *AlertService {
+	return &AlertService{
+		results:       results,
+		ruleEvents:    ruleEvents,
+		requests:      requests,
+		entityReqChan: entityReqChan,
+		notifications: notifications,
+		db:            db,
+		instanceRepo:  database.NewSqlxRepository[models.AlertInstance](db),
+		silenceRepo:   database.NewSqlxRepository[models.AlertSilence](db),
+		anomalyRepo:   database.NewSqlxRepository[models.MetricAnomaly](db),
+		rules:         make(map[int64]*models.AlertRule),
+		pending:       make(map[alertKey]*pendingAlert),
+		open:          make(map[alertKey]*models.AlertInstance),
+		silences:      make(map[int64]*models.AlertSilence),
+		devices:       make(map[int64]*models.Device),
+		detector:      newDetector(anomaly),
+	}
+}
+
+// Run starts the alert service's main loop.
+func (svc *AlertService) Run(ctx context.Context) {
+	slog.Info("Starting alert service", "component", "AlertService")
+
+	svc.loadRules()
+	svc.loadOpen(ctx)
+	svc.loadSilences(ctx)
+	svc.loadBaselines(ctx)
+
+	ticker := time.NewTicker(silenceCheckInterval)
+	defer ticker.Stop()
+	snapshotTicker := time.NewTicker(baselineSnapshotInterval)
+	defer snapshotTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping alert service", "component", "AlertService")
+			return
+		case results := <-svc.results:
+			svc.evaluateResults(ctx, results)
+		case event := <-svc.ruleEvents:
+			svc.handleRuleEvent(ctx, event)
+		case req := <-svc.requests:
+			svc.handleRequest(ctx, req)
+		case now := <-ticker.C:
+			svc.expireSilences(ctx, now)
+		case now := <-snapshotTicker.C:
+			svc.saveBaselines(ctx, now)
+		}
+	}
+}
+
+// loadRules fetches all alert rules from EntityService.
+func (svc *AlertService) loadRules() {
+	replyCh := make(chan models.Response, 1)
+	svc.entityReqChan <- models.Request{
+		Operation:  models.OpList,
+		EntityType: "AlertRule",
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	rules, ok := resp.Data.([]*models.AlertRule)
+	if resp.Error != nil || !ok {
+		slog.Error("Failed to load alert rules", "component", "AlertService", "error", resp.Error)
+		return
+	}
+	for _, rule := range rules {
+		svc.rules[rule.ID] = rule
+	}
+	svc.detector.track(svc.rules)
+	slog.Info("Loaded alert rules", "component", "AlertService", "count", len(svc.rules))
+}
+
+// loadOpen restores open instances so they resolve normally after a restart.
+func (svc *AlertService) loadOpen(ctx context.Context) {
+	var instances []*models.AlertInstance
+	err := svc.db.SelectContext(ctx, &instances, "SELECT * FROM alert_instances WHERE state <> $1", models.AlertStateResolved)
+	if err != nil {
+		slog.Error("Failed to load open alerts", "component", "AlertService", "error", err)
+		return
+	}
+	for _, instance := range instances {
+		svc.open[alertKey{instance.RuleID, instance.DeviceID}] = instance
+	}
+	slog.Info("Loaded open alerts", "component", "AlertService", "count", len(svc.open))
+}
+
+// handleRuleEvent keeps the rule set in sync. A changed or deleted rule resolves its open
+// instances: they were raised under conditions that no longer apply.
+func (svc *AlertService) handleRuleEvent(ctx context.Context, event models.Event) {
+	rule, ok := event.Payload.(*models.AlertRule)
+	if !ok {
+		slog.Error("Invalid payload type in alert rule event", "component", "AlertService", "type", event.Type)
+		return
+	}
+
+	switch event.Type {
+	case models.EventCreate:
+		svc.rules[rule.ID] = rule
+	case models.EventUpdate:
+		svc.rules[rule.ID] = rule
+		svc.clearRule(ctx, rule.ID, true)
+	case models.EventDelete:
+		delete(svc.rules, rule.ID)
+		svc.clearRule(ctx, rule.ID, false) // Instances and silences are removed by ON DELETE CASCADE
+		for id, silence := range svc.silences {
+			if silence.RuleID != nil && *silence.RuleID == rule.ID {
+				delete(svc.silences, id)
+			}
+		}
+	}
+	svc.detector.track(svc.rules)
+}
+
+// clearRule drops the pending state of a rule and, if requested, resolves its open instances.
+func (svc *AlertService) clearRule(ctx context.Context, ruleID int64, resolve bool) {
+	for key := range svc.pending {
+		if key.ruleID == ruleID {
+			delete(svc.pending, key)
+		}
+	}
+	now := time.Now()
+	for key, instance := range svc.open {
+		if key.ruleID != ruleID {
+			continue
+		}
+		if resolve {
+			svc.resolve(ctx, key, instance, instance.Value, "", now)
+		} else {
+			delete(svc.open, key)
+		}
+	}
+}
+
+// handleRequest answers list and lifecycle requests.
+// Lifecycle changes run here because they touch open instances; reads go to a goroutine.
+func (svc *AlertService) handleRequest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpListAlerts:
+		filter, ok := req.Payload.(*models.AlertListRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for alert list")
+			break
+		}
+		go func() {
+			data, err := svc.list(ctx, filter)
+			req.ReplyCh <- models.Response{Data: data, Error: err}
+		}()
+		return
+	case models.OpGetAlert:
+		resp.Data, resp.Error = svc.getAlert(ctx, req.ID)
+	case models.OpAcknowledgeAlert:
+		username, _ := req.Payload.(string)
+		resp.Data, resp.Error = svc.acknowledge(ctx, req.ID, username)
+	case models.OpResolveAlert:
+		username, _ := req.Payload.(string)
+		resp.Data, resp.Error = svc.resolveByHand(ctx, req.ID, username)
+	case models.OpCreateSilence:
+		silence, ok := req.Payload.(*models.AlertSilence)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for silence")
+			break
+		}
+		resp.Data, resp.Error = svc.createSilence(ctx, silence)
+	case models.OpListSilences:
+		resp.Data = svc.listSilences()
+	case models.OpExpireSilence:
+		resp.Data, resp.Error = svc.expireSilence(ctx, req.ID)
+	case models.OpListAnomalies:
+		filter, ok := req.Payload.(*models.AnomalyListRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for anomaly list")
+			break
+		}
+		go func() {
+			data, err := svc.listAnomalies(ctx, filter)
+			req.ReplyCh <- models.Response{Data: data, Error: err}
+		}()
+		return
+	case models.OpGetBaselines:
+		resp.Data = svc.detector.baselines(req.ID, time.Now())
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// list returns one page of alert instances matching the filter, newest first.
+func (svc *AlertService) list(ctx context.Context, filter *models.AlertListRequest) (*models.AlertPage, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(column string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s $%d", column, len(args)))
+	}
+	if filter.State != "" {
+		addCondition("state =", filter.State)
+	}
+	if filter.RuleID != 0 {
+		addCondition("rule_id =", filter.RuleID)
+	}
+	if filter.DeviceID != 0 {
+		addCondition("device_id =", filter.DeviceID)
+	}
+	if filter.Severity != "" {
+		addCondition("severity =", filter.Severity)
+	}
+	if !filter.Start.IsZero() {
+		addCondition("fired_at >=", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		addCondition("fired_at <=", filter.End)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &models.AlertPage{Alerts: make([]*models.AlertInstance, 0), Limit: filter.Limit, Offset: filter.Offset}
+	if err := svc.db.GetContext(ctx, &page.Total, "SELECT COUNT(*) FROM alert_instances"+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to count alerts: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM alert_instances%s ORDER BY fired_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		where, len(args)+1, len(args)+2)
+	if err := svc.db.SelectContext(ctx, &page.Alerts, query, append(args, filter.Limit, filter.Offset)...); err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	return page, nil
+}
@@ -0,0 +1,363 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+)
+
+const (
+	baselineSnapshotInterval = 10 * time.Minute   // How often baselines are saved to metric_baselines
+	baselineRetention        = 7 * 24 * time.Hour // Baselines of series not seen for this long are dropped
+	hoursPerWeek             = 7 * 24
+)
+
+// AnomalyConfig configures the baseline anomaly detector.
+type AnomalyConfig struct {
+	Paths       []string // Metric paths tracked for every device, in addition to anomaly rule paths
+	Alpha       float64  // EWMA smoothing factor (0..1], higher adapts faster
+	Deviations  float64  // Standard deviations that flag an anomaly, also the default for anomaly rules
+	MinSamples  int      // Samples before a baseline (or seasonal bucket) is used
+	Seasonality bool     // Keep a separate baseline per hour of the week
+}
+
+// ewma is an exponentially weighted moving mean and variance.
+type ewma struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Samples  int     `json:"samples"`
+}
+
+// update folds a sample into the statistics (West's incremental EWMA variance).
+func (e *ewma) update(value, alpha float64) {
+	if e.Samples == 0 {
+		e.Mean = value
+		e.Variance = 0
+	} else {
+		diff := value - e.Mean
+		incr := alpha * diff
+		e.Mean += incr
+		e.Variance = (1 - alpha) * (e.Variance + diff*incr)
+	}
+	e.Samples++
+}
+
+// stdDev returns the standard deviation, floored so a flat series doesn't turn
+// every small change into an infinite z-score.
+func (e *ewma) stdDev() float64 {
+	floor := math.Max(math.Abs(e.Mean)*0.01, 1e-6)
+	return math.Max(math.Sqrt(e.Variance), floor)
+}
+
+// series is the baseline of one metric path on one device.
+type series struct {
+	Global   ewma      `json:"global"`
+	Buckets  []ewma    `json:"buckets,omitempty"` // Hour-of-week baselines when seasonality is enabled
+	LastSeen time.Time `json:"last_seen"`
+
+	episode *models.MetricAnomaly // Open anomaly episode, if any
+}
+
+// seriesKey identifies one metric path on one device.
+type seriesKey struct {
+	deviceID int64
+	path     string
+}
+
+// observation is the outcome of scoring one sample against its baseline.
+type observation struct {
+	value  float64
+	mean   float64
+	stdDev float64
+	zscore float64
+	ready  bool // The baseline had enough samples to score the value
+}
+
+// detector keeps per-device, per-path baselines and scores new samples against them.
+// Owned by the AlertService goroutine.
+type detector struct {
+	config AnomalyConfig
+	series map[seriesKey]*series
+	paths  []string // Tracked paths: configured paths plus anomaly rule paths
+}
+
+func newDetector(config AnomalyConfig) *detector {
+	return &detector{
+		config: config,
+		series: make(map[seriesKey]*series),
+		paths:  config.Paths,
+	}
+}
+
+// track recomputes the tracked paths from the configuration and the active anomaly rules.
+func (d *detector) track(rules map[int64]*models.AlertRule) {
+	seen := make(map[string]bool)
+	paths := make([]string, 0, len(d.config.Paths))
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range d.config.Paths {
+		add(path)
+	}
+	for _, rule := range rules {
+		if rule.Condition == models.ConditionAnomaly && !rule.Disabled {
+			add(rule.Path)
+		}
+	}
+	sort.Strings(paths)
+	d.paths = paths
+}
+
+// observe scores a sample against the current baseline, then folds it in.
+// The seasonal bucket for the sample's hour of week is used once it has enough samples.
+func (d *detector) observe(key seriesKey, value float64, now time.Time) (*series, observation) {
+	s, exists := d.series[key]
+	if !exists {
+		s = &series{}
+		d.series[key] = s
+	}
+
+	baseline := &s.Global
+	var bucket *ewma
+	if d.config.Seasonality {
+		if len(s.Buckets) != hoursPerWeek {
+			s.Buckets = make([]ewma, hoursPerWeek)
+		}
+		bucket = &s.Buckets[hourOfWeek(now)]
+		if bucket.Samples >= d.config.MinSamples {
+			baseline = bucket
+		}
+	}
+
+	obs := observation{
+		value:  value,
+		mean:   baseline.Mean,
+		stdDev: baseline.stdDev(),
+		ready:  s.Global.Samples >= d.config.MinSamples,
+	}
+	if obs.ready {
+		obs.zscore = (value - obs.mean) / obs.stdDev
+	}
+
+	s.Global.update(value, d.config.Alpha)
+	if bucket != nil {
+		bucket.update(value, d.config.Alpha)
+	}
+	s.LastSeen = now
+	return s, obs
+}
+
+// baselines returns the current baselines of a device, sorted by path.
+func (d *detector) baselines(deviceID int64, now time.Time) []*models.MetricBaseline {
+	result := make([]*models.MetricBaseline, 0)
+	for key, s := range d.series {
+		if key.deviceID != deviceID {
+			continue
+		}
+		baseline := &models.MetricBaseline{
+			DeviceID: key.deviceID,
+			Path:     key.path,
+			Mean:     s.Global.Mean,
+			StdDev:   s.Global.stdDev(),
+			Samples:  s.Global.Samples,
+			Ready:    s.Global.Samples >= d.config.MinSamples,
+			LastSeen: s.LastSeen,
+		}
+		if d.config.Seasonality && len(s.Buckets) == hoursPerWeek {
+			if bucket := s.Buckets[hourOfWeek(now)]; bucket.Samples >= d.config.MinSamples {
+				baseline.Mean = bucket.Mean
+				baseline.StdDev = bucket.stdDev()
+				baseline.Seasonal = true
+			}
+		}
+		result = append(result, baseline)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// hourOfWeek returns 0 for Sunday 00:00-00:59 UTC through 167 for Saturday 23:00-23:59 UTC.
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// observeAnomalies scores the tracked paths of one poll result and records anomaly episodes.
+// It returns the observations keyed by path for anomaly rule evaluation.
+func (svc *AlertService) observeAnomalies(ctx context.Context, deviceID int64, data any, now time.Time) map[string]observation {
+	if len(svc.detector.paths) == 0 {
+		return nil
+	}
+
+	observations := make(map[string]observation, len(svc.detector.paths))
+	for _, path := range svc.detector.paths {
+		value, ok := lookupValue(data, path)
+		if !ok {
+			continue
+		}
+		s, obs := svc.detector.observe(seriesKey{deviceID, path}, value, now)
+		observations[path] = obs
+		svc.trackEpisode(ctx, seriesKey{deviceID, path}, s, obs, now)
+	}
+	return observations
+}
+
+// trackEpisode opens an anomaly episode when a sample leaves the baseline, keeps the sample
+// with the largest deviation (and its baseline) while it lasts, and ends it on the first sample back inside.
+func (svc *AlertService) trackEpisode(ctx context.Context, key seriesKey, s *series, obs observation, now time.Time) {
+	anomalous := obs.ready && math.Abs(obs.zscore) > svc.detector.config.Deviations
+
+	switch {
+	case anomalous && s.episode == nil:
+		episode := &models.MetricAnomaly{
+			DeviceID:  key.deviceID,
+			Path:      key.path,
+			Value:     obs.value,
+			Mean:      obs.mean,
+			StdDev:    obs.stdDev,
+			ZScore:    obs.zscore,
+			StartedAt: now,
+		}
+		episode, err := svc.anomalyRepo.Create(ctx, episode)
+		if err != nil {
+			slog.Error("Failed to store anomaly", "component", "AlertService", "device_id", key.deviceID, "path", key.path, "error", err)
+			return
+		}
+		s.episode = episode
+		slog.Info("Metric anomaly detected", "component", "AlertService", "device_id", key.deviceID,
+			"path", key.path, "value", obs.value, "mean", obs.mean, "zscore", obs.zscore)
+
+	case anomalous && math.Abs(obs.zscore) > math.Abs(s.episode.ZScore):
+		s.episode.Value = obs.value
+		s.episode.Mean = obs.mean
+		s.episode.StdDev = obs.stdDev
+		s.episode.ZScore = obs.zscore
+		if _, err := svc.anomalyRepo.Update(ctx, s.episode.ID, s.episode); err != nil {
+			slog.Error("Failed to update anomaly", "component", "AlertService", "anomaly_id", s.episode.ID, "error", err)
+		}
+
+	case !anomalous && s.episode != nil:
+		s.episode.EndedAt = &now
+		if _, err := svc.anomalyRepo.Update(ctx, s.episode.ID, s.episode); err != nil {
+			slog.Error("Failed to end anomaly", "component", "AlertService", "anomaly_id", s.episode.ID, "error", err)
+		}
+		s.episode = nil
+	}
+}
+
+// loadBaselines restores saved baselines and open anomaly episodes after a restart.
+func (svc *AlertService) loadBaselines(ctx context.Context) {
+	var rows []struct {
+		DeviceID int64  `db:"device_id"`
+		Path     string `db:"path"`
+		State    []byte `db:"state"`
+	}
+	if err := svc.db.SelectContext(ctx, &rows, "SELECT device_id, path, state FROM metric_baselines"); err != nil {
+		slog.Error("Failed to load metric baselines", "component", "AlertService", "error", err)
+		return
+	}
+	for _, row := range rows {
+		s := &series{}
+		if err := json.Unmarshal(row.State, s); err != nil {
+			slog.Warn("Skipping unreadable metric baseline", "component", "AlertService", "device_id", row.DeviceID, "path", row.Path, "error", err)
+			continue
+		}
+		svc.detector.series[seriesKey{row.DeviceID, row.Path}] = s
+	}
+
+	var episodes []*models.MetricAnomaly
+	if err := svc.db.SelectContext(ctx, &episodes, "SELECT * FROM metric_anomalies WHERE ended_at IS NULL"); err != nil {
+		slog.Error("Failed to load open anomalies", "component", "AlertService", "error", err)
+	}
+	for _, episode := range episodes {
+		key := seriesKey{episode.DeviceID, episode.Path}
+		s, exists := svc.detector.series[key]
+		if !exists {
+			s = &series{}
+			svc.detector.series[key] = s
+		}
+		s.episode = episode
+	}
+	slog.Info("Loaded metric baselines", "component", "AlertService", "count", len(rows), "open_anomalies", len(episodes))
+}
+
+// saveBaselines drops stale series and writes a snapshot of the rest in the background.
+func (svc *AlertService) saveBaselines(ctx context.Context, now time.Time) {
+	type snapshot struct {
+		key   seriesKey
+		state []byte
+	}
+	snapshots := make([]snapshot, 0, len(svc.detector.series))
+	for key, s := range svc.detector.series {
+		if now.Sub(s.LastSeen) > baselineRetention {
+			delete(svc.detector.series, key)
+			continue
+		}
+		state, err := json.Marshal(s)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{key, state})
+	}
+
+	go func() {
+		saved := 0
+		for _, snap := range snapshots {
+			_, err := svc.db.ExecContext(ctx, `
+				INSERT INTO metric_baselines (device_id, path, state, updated_at)
+				VALUES ($1, $2, $3, NOW())
+				ON CONFLICT (device_id, path) DO UPDATE SET state = EXCLUDED.state, updated_at = NOW()`,
+				snap.key.deviceID, snap.key.path, snap.state)
+			if err != nil {
+				// Usually the device was deleted since the sample was taken
+				slog.Debug("Failed to save metric baseline", "component", "AlertService", "device_id", snap.key.deviceID, "path", snap.key.path, "error", err)
+				continue
+			}
+			saved++
+		}
+		if _, err := svc.db.ExecContext(ctx, "DELETE FROM metric_baselines WHERE updated_at < $1", now.Add(-baselineRetention)); err != nil {
+			slog.Error("Failed to prune metric baselines", "component", "AlertService", "error", err)
+		}
+		slog.Debug("Saved metric baselines", "component", "AlertService", "count", saved)
+	}()
+}
+
+// listAnomalies returns anomaly episodes matching the filter, newest first.
+func (svc *AlertService) listAnomalies(ctx context.Context, filter *models.AnomalyListRequest) ([]*models.MetricAnomaly, error) {
+	var conditions []string
+	var args []any
+	if filter.DeviceID != 0 {
+		args = append(args, filter.DeviceID)
+		conditions = append(conditions, fmt.Sprintf("device_id = $%d", len(args)))
+	}
+	if filter.Path != "" {
+		args = append(args, filter.Path)
+		conditions = append(conditions, fmt.Sprintf("path = $%d", len(args)))
+	}
+	if filter.Active {
+		conditions = append(conditions, "ended_at IS NULL")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf("SELECT * FROM metric_anomalies%s ORDER BY started_at DESC, id DESC LIMIT $%d", where, len(args))
+
+	anomalies := make([]*models.MetricAnomaly, 0)
+	if err := svc.db.SelectContext(ctx, &anomalies, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list anomalies: %w", err)
+	}
+	return anomalies, nil
+}
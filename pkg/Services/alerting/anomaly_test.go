@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// anomalyRepo is an in-memory anomaly store; only Create and Update are used.
+type anomalyRepo struct {
+	rows map[int64]models.MetricAnomaly
+}
+
+func (r *anomalyRepo) Create(ctx context.Context, entity *models.MetricAnomaly) (*models.MetricAnomaly, error) {
+	entity.ID = int64(len(r.rows) + 1)
+	r.rows[entity.ID] = *entity
+	return entity, nil
+}
+
+func (r *anomalyRepo) Update(ctx context.Context, id int64, entity *models.MetricAnomaly) (*models.MetricAnomaly, error) {
+	r.rows[id] = *entity
+	return entity, nil
+}
+
+func (r *anomalyRepo) Get(ctx context.Context, id int64) (*models.MetricAnomaly, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *anomalyRepo) List(ctx context.Context) ([]*models.MetricAnomaly, error) { return nil, nil }
+func (r *anomalyRepo) GetByFields(ctx context.Context, filters map[string]any) (*models.MetricAnomaly, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *anomalyRepo) ListByFields(ctx context.Context, filters map[string]any) ([]*models.MetricAnomaly, error) {
+	return nil, nil
+}
+func (r *anomalyRepo) Delete(ctx context.Context, id int64) error { return nil }
+
+func TestEpisodeKeepsPeakSampleWithItsBaseline(t *testing.T) {
+	repo := &anomalyRepo{rows: make(map[int64]models.MetricAnomaly)}
+	svc := &AlertService{anomalyRepo: repo, detector: newDetector(AnomalyConfig{Deviations: 3})}
+	key := seriesKey{deviceID: 7, path: "cpu.usage"}
+	s := &series{}
+	ctx := context.Background()
+	now := time.Now()
+
+	svc.trackEpisode(ctx, key, s, observation{value: 80, mean: 40, stdDev: 10, zscore: 4, ready: true}, now)
+	svc.trackEpisode(ctx, key, s, observation{value: 95, mean: 42, stdDev: 9, zscore: 5.9, ready: true}, now.Add(time.Minute))
+	svc.trackEpisode(ctx, key, s, observation{value: 85, mean: 45, stdDev: 8, zscore: 5, ready: true}, now.Add(2*time.Minute))
+
+	episode := repo.rows[1]
+	if episode.Value != 95 || episode.Mean != 42 || episode.StdDev != 9 || episode.ZScore != 5.9 {
+		t.Errorf("episode = %+v, want the peak sample 95 with baseline 42±9", episode)
+	}
+	if !episode.StartedAt.Equal(now) || episode.EndedAt != nil {
+		t.Errorf("episode started %s ended %v, want started at the first sample and still open", episode.StartedAt, episode.EndedAt)
+	}
+
+	svc.trackEpisode(ctx, key, s, observation{value: 50, mean: 46, stdDev: 8, zscore: 0.5, ready: true}, now.Add(3*time.Minute))
+	if episode := repo.rows[1]; episode.EndedAt == nil || episode.Value != 95 {
+		t.Errorf("ended episode = %+v, want it closed with the peak kept", episode)
+	}
+}
@@ -0,0 +1,198 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+	"nms/pkg/plugin"
+)
+
+// evaluateResults feeds the tracked paths of a batch of successful poll results to the anomaly
+// detector, then checks every active rule against them.
+func (svc *AlertService) evaluateResults(ctx context.Context, results []plugin.Result) {
+	if len(svc.rules) == 0 && len(svc.detector.paths) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(results))
+	for _, result := range results {
+		if result.Success {
+			ids = append(ids, result.DeviceID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	devices := svc.fetchDevices(ids)
+
+	now := time.Now()
+	for _, result := range results {
+		device, exists := devices[result.DeviceID]
+		if !result.Success || !exists {
+			continue
+		}
+
+		var data any
+		if err := json.Unmarshal(result.Data, &data); err != nil {
+			slog.Debug("Skipping undecodable poll result", "component", "AlertService", "device_id", result.DeviceID, "error", err)
+			continue
+		}
+
+		observations := svc.observeAnomalies(ctx, device.ID, data, now)
+
+		for _, rule := range svc.rules {
+			if rule.Disabled || !rule.Matches(device) {
+				continue
+			}
+			if rule.Condition == models.ConditionAnomaly {
+				obs, ok := observations[rule.Path]
+				if !ok || !obs.ready {
+					continue // Metric absent or baseline still learning; keep the current state
+				}
+				svc.evaluate(ctx, rule, device, obs.value, rule.AnomalyBreached(obs.zscore, svc.deviations(rule)), now)
+				continue
+			}
+			value, ok := lookupValue(data, rule.Path)
+			if !ok {
+				continue // Metric absent from this result; keep the current state
+			}
+			svc.evaluate(ctx, rule, device, value, rule.Breached(value), now)
+		}
+	}
+}
+
+// evaluate advances the state of one rule on one device: ok -> pending -> open -> resolved.
+// Open alerts resolve automatically once the condition clears, whether acknowledged or silenced.
+func (svc *AlertService) evaluate(ctx context.Context, rule *models.AlertRule, device *models.Device, value float64, breached bool, now time.Time) {
+	key := alertKey{rule.ID, device.ID}
+
+	if !breached {
+		delete(svc.pending, key)
+		if instance, open := svc.open[key]; open {
+			svc.resolve(ctx, key, instance, value, "", now)
+		}
+		return
+	}
+
+	if instance, open := svc.open[key]; open {
+		instance.Value = value
+		return
+	}
+
+	pending, exists := svc.pending[key]
+	if !exists {
+		pending = &pendingAlert{since: now}
+		svc.pending[key] = pending
+	}
+	pending.value = value
+
+	if now.Sub(pending.since) >= time.Duration(rule.ForSeconds)*time.Second {
+		svc.fire(ctx, key, rule, device, pending, now)
+	}
+}
+
+// fire persists a new instance, already silenced if an active silence covers it.
+func (svc *AlertService) fire(ctx context.Context, key alertKey, rule *models.AlertRule, device *models.Device, pending *pendingAlert, now time.Time) {
+	instance := &models.AlertInstance{
+		RuleID:    rule.ID,
+		DeviceID:  key.deviceID,
+		Severity:  rule.Severity,
+		State:     models.AlertStateFiring,
+		Value:     pending.value,
+		StartedAt: pending.since,
+		FiredAt:   now,
+	}
+	if silence := svc.matchSilence(rule.ID, device, now); silence != nil {
+		instance.State = models.AlertStateSilenced
+		instance.SilenceID = &silence.ID
+	}
+
+	instance, err := svc.instanceRepo.Create(ctx, instance)
+	if err != nil {
+		// Pending state is kept, so the next breaching sample retries
+		slog.Error("Failed to store firing alert", "component", "AlertService", "rule_id", rule.ID, "device_id", key.deviceID, "error", err)
+		return
+	}
+
+	delete(svc.pending, key)
+	svc.open[key] = instance
+	slog.Warn("Alert firing",
+		"component", "AlertService",
+		"rule", rule.Name,
+		"device_id", key.deviceID,
+		"severity", rule.Severity,
+		"path", rule.Path,
+		"value", pending.value,
+		"condition", rule.Condition,
+		"threshold", rule.Threshold,
+		"state", instance.State,
+	)
+	if instance.State == models.AlertStateFiring {
+		svc.notify(models.NotificationAlertFiring, instance)
+	}
+}
+
+// deviations returns the number of standard deviations an anomaly rule tolerates.
+func (svc *AlertService) deviations(rule *models.AlertRule) float64 {
+	if rule.Deviations > 0 {
+		return rule.Deviations
+	}
+	return svc.detector.config.Deviations
+}
+
+// fetchDevices looks up the polled devices in EntityService for rule scoping.
+func (svc *AlertService) fetchDevices(ids []int64) map[int64]*models.Device {
+	replyCh := make(chan models.Response, 1)
+	svc.entityReqChan <- models.Request{
+		Operation: models.OpGetBatch,
+		IDs:       ids,
+		ReplyCh:   replyCh,
+	}
+
+	devices := make(map[int64]*models.Device, len(ids))
+	resp := <-replyCh
+	batch, ok := resp.Data.(*models.BatchDeviceResponse)
+	if resp.Error != nil || !ok {
+		slog.Error("Failed to fetch devices for alert evaluation", "component", "AlertService", "error", resp.Error)
+		return devices
+	}
+	for _, dev := range append(batch.ToPing, batch.ToSkip...) {
+		devices[dev.ID] = dev
+		svc.devices[dev.ID] = dev
+	}
+	return devices
+}
+
+// lookupValue walks a dotted path through decoded JSON and returns the value as a number.
+// Booleans map to 1/0 and numeric strings are parsed; anything else is absent.
+func lookupValue(data any, path string) (float64, bool) {
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return 0, false
+		}
+		if current, ok = object[segment]; !ok {
+			return 0, false
+		}
+	}
+
+	switch v := current.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,106 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// findOpen returns the open instance with the given ID.
+func (svc *AlertService) findOpen(id int64) (alertKey, *models.AlertInstance, bool) {
+	for key, instance := range svc.open {
+		if instance.ID == id {
+			return key, instance, true
+		}
+	}
+	return alertKey{}, nil, false
+}
+
+// getAlert returns an alert instance from the history.
+func (svc *AlertService) getAlert(ctx context.Context, id int64) (*models.AlertInstance, error) {
+	instance, err := svc.instanceRepo.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", models.ErrAlertNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert %d: %w", id, err)
+	}
+	return instance, nil
+}
+
+// notOpenError explains why there is no open instance with the given ID: unknown or already resolved.
+func (svc *AlertService) notOpenError(ctx context.Context, id int64) error {
+	if _, err := svc.getAlert(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %d", models.ErrAlertNotOpen, id)
+}
+
+// acknowledge records who took ownership of an open alert. Silenced alerts stay silenced
+// and come back as acknowledged when the silence ends.
+func (svc *AlertService) acknowledge(ctx context.Context, id int64, username string) (*models.AlertInstance, error) {
+	_, instance, exists := svc.findOpen(id)
+	if !exists {
+		return nil, svc.notOpenError(ctx, id)
+	}
+	if instance.AcknowledgedAt != nil {
+		return instance, nil
+	}
+
+	now := time.Now()
+	previous := *instance
+	instance.AcknowledgedBy = username
+	instance.AcknowledgedAt = &now
+	if instance.State == models.AlertStateFiring {
+		instance.State = models.AlertStateAcknowledged
+	}
+	if _, err := svc.instanceRepo.Update(ctx, instance.ID, instance); err != nil {
+		*instance = previous
+		return nil, fmt.Errorf("failed to acknowledge alert %d: %w", id, err)
+	}
+
+	slog.Info("Alert acknowledged", "component", "AlertService", "alert_id", id, "by", username)
+	return instance, nil
+}
+
+// resolveByHand closes an open alert on an operator's behalf.
+// If the condition persists, the rule fires a new alert after its "for" duration.
+func (svc *AlertService) resolveByHand(ctx context.Context, id int64, username string) (*models.AlertInstance, error) {
+	key, instance, exists := svc.findOpen(id)
+	if !exists {
+		return nil, svc.notOpenError(ctx, id)
+	}
+	delete(svc.pending, key)
+	if err := svc.resolve(ctx, key, instance, instance.Value, username, time.Now()); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// resolve marks an open instance as resolved. An empty username means the condition cleared.
+func (svc *AlertService) resolve(ctx context.Context, key alertKey, instance *models.AlertInstance, value float64, username string, now time.Time) error {
+	wasSilenced := instance.State == models.AlertStateSilenced
+	instance.State = models.AlertStateResolved
+	instance.Value = value
+	instance.ResolvedBy = username
+	instance.ResolvedAt = &now
+	delete(svc.open, key)
+
+	if _, err := svc.instanceRepo.Update(ctx, instance.ID, instance); err != nil {
+		// Usually the device or rule was deleted and the row went with it
+		slog.Error("Failed to resolve alert", "component", "AlertService", "alert_id", instance.ID, "error", err)
+		return fmt.Errorf("failed to resolve alert %d: %w", instance.ID, err)
+	}
+
+	slog.Info("Alert resolved", "component", "AlertService", "alert_id", instance.ID,
+		"rule_id", key.ruleID, "device_id", key.deviceID, "by", username)
+	if !wasSilenced {
+		svc.notify(models.NotificationAlertResolved, instance)
+	}
+	return nil
+}
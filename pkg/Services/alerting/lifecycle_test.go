@@ -0,0 +1,85 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"nms/pkg/models"
+)
+
+// instanceRepo is an in-memory alert history; Get fails with err when it is set.
+type instanceRepo struct {
+	rows map[int64]models.AlertInstance
+	err  error
+}
+
+func (r *instanceRepo) Get(ctx context.Context, id int64) (*models.AlertInstance, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	row, ok := r.rows[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &row, nil
+}
+
+func (r *instanceRepo) Update(ctx context.Context, id int64, entity *models.AlertInstance) (*models.AlertInstance, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.rows[id] = *entity
+	return entity, nil
+}
+
+func (r *instanceRepo) List(ctx context.Context) ([]*models.AlertInstance, error) { return nil, nil }
+func (r *instanceRepo) GetByFields(ctx context.Context, filters map[string]any) (*models.AlertInstance, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *instanceRepo) ListByFields(ctx context.Context, filters map[string]any) ([]*models.AlertInstance, error) {
+	return nil, nil
+}
+func (r *instanceRepo) Create(ctx context.Context, entity *models.AlertInstance) (*models.AlertInstance, error) {
+	return entity, nil
+}
+func (r *instanceRepo) Delete(ctx context.Context, id int64) error { return nil }
+
+func TestLifecycleErrors(t *testing.T) {
+	repo := &instanceRepo{rows: map[int64]models.AlertInstance{
+		1: {ID: 1, RuleID: 1, DeviceID: 7, State: models.AlertStateFiring},
+		2: {ID: 2, RuleID: 1, DeviceID: 8, State: models.AlertStateResolved},
+	}}
+	open := repo.rows[1]
+	svc := &AlertService{
+		instanceRepo: repo,
+		pending:      make(map[alertKey]*pendingAlert),
+		open:         map[alertKey]*models.AlertInstance{{ruleID: 1, deviceID: 7}: &open},
+	}
+	ctx := context.Background()
+
+	if _, err := svc.acknowledge(ctx, 1, "alice"); err != nil {
+		t.Fatalf("acknowledge of an open alert failed: %v", err)
+	}
+	if _, err := svc.acknowledge(ctx, 2, "alice"); !errors.Is(err, models.ErrAlertNotOpen) {
+		t.Errorf("acknowledge of a resolved alert: %v, want ErrAlertNotOpen", err)
+	}
+	if _, err := svc.resolveByHand(ctx, 99, "alice"); !errors.Is(err, models.ErrAlertNotFound) {
+		t.Errorf("resolve of an unknown alert: %v, want ErrAlertNotFound", err)
+	}
+	if _, err := svc.getAlert(ctx, 99); !errors.Is(err, models.ErrAlertNotFound) {
+		t.Errorf("get of an unknown alert: %v, want ErrAlertNotFound", err)
+	}
+
+	// Database failures are neither
+	repo.err = errors.New("connection refused")
+	for name, err := range map[string]error{
+		"acknowledge resolved": func() error { _, err := svc.acknowledge(ctx, 2, "bob"); return err }(),
+		"resolve open":         func() error { _, err := svc.resolveByHand(ctx, 1, "bob"); return err }(),
+	} {
+		if err == nil || errors.Is(err, models.ErrAlertNotOpen) || errors.Is(err, models.ErrAlertNotFound) {
+			t.Errorf("%s with a failing database: %v", name, err)
+		}
+	}
+}
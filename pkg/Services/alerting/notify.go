@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// operatorSymbols renders rule operators in notification text.
+var operatorSymbols = map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<=", "eq": "==", "ne": "!="}
+
+// anomalyDirections renders anomaly rule operators in notification text.
+var anomalyDirections = map[string]string{"gt": "above", "gte": "above", "lt": "below", "lte": "below", "ne": "away from"}
+
+// notify sends an alert notification to NotificationService without blocking evaluation.
+func (svc *AlertService) notify(kind string, instance *models.AlertInstance) {
+	notification := &models.Notification{
+		Kind:      kind,
+		Severity:  instance.Severity,
+		DeviceID:  instance.DeviceID,
+		AlertID:   instance.ID,
+		RuleID:    instance.RuleID,
+		State:     instance.State,
+		Value:     instance.Value,
+		Timestamp: time.Now(),
+	}
+
+	target := fmt.Sprintf("device %d", instance.DeviceID)
+	if device, exists := svc.devices[instance.DeviceID]; exists {
+		notification.Hostname = device.Hostname
+		notification.IPAddress = device.IPAddress
+		notification.PluginID = device.PluginID
+		notification.Tags = device.Tags
+		target = device.Hostname
+		if target == "" {
+			target = device.IPAddress
+		}
+	}
+
+	ruleName := fmt.Sprintf("rule %d", instance.RuleID)
+	if rule, exists := svc.rules[instance.RuleID]; exists {
+		ruleName = rule.Name
+		if rule.Condition == models.ConditionAnomaly {
+			notification.Message = fmt.Sprintf("%s = %g (more than %g standard deviations %s baseline)",
+				rule.Path, instance.Value, svc.deviations(rule), anomalyDirections[rule.Operator])
+		} else {
+			notification.Message = fmt.Sprintf("%s = %g (threshold %s %g)", rule.Path, instance.Value, operatorSymbols[rule.Operator], rule.Threshold)
+		}
+	}
+
+	if kind == models.NotificationAlertResolved {
+		notification.Title = fmt.Sprintf("Resolved: %s on %s", ruleName, target)
+	} else {
+		notification.Title = fmt.Sprintf("%s on %s", ruleName, target)
+	}
+
+	select {
+	case svc.notifications <- models.Event{Type: models.EventNotification, Payload: notification}:
+	default:
+		slog.Warn("Notification channel full, dropping alert notification", "component", "AlertService", "alert_id", instance.ID)
+	}
+}
@@ -0,0 +1,151 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// loadSilences restores silences that have not ended yet.
+func (svc *AlertService) loadSilences(ctx context.Context) {
+	var silences []*models.AlertSilence
+	err := svc.db.SelectContext(ctx, &silences, "SELECT * FROM alert_silences WHERE ends_at > NOW()")
+	if err != nil {
+		slog.Error("Failed to load alert silences", "component", "AlertService", "error", err)
+		return
+	}
+	for _, silence := range silences {
+		svc.silences[silence.ID] = silence
+	}
+	slog.Info("Loaded alert silences", "component", "AlertService", "count", len(svc.silences))
+}
+
+// createSilence stores a silence and mutes the open alerts it matches.
+func (svc *AlertService) createSilence(ctx context.Context, silence *models.AlertSilence) (*models.AlertSilence, error) {
+	if silence.DeviceID == nil && silence.RuleID == nil && silence.Label == "" {
+		return nil, fmt.Errorf("a silence needs at least one of device_id, rule_id or label")
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return nil, fmt.Errorf("silence must end after it starts")
+	}
+	if silence.RuleID != nil {
+		if _, exists := svc.rules[*silence.RuleID]; !exists {
+			return nil, fmt.Errorf("alert rule %d not found", *silence.RuleID)
+		}
+	}
+
+	created, err := svc.silenceRepo.Create(ctx, silence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create silence: %w", err)
+	}
+	svc.silences[created.ID] = created
+	slog.Info("Silence created", "component", "AlertService", "silence_id", created.ID,
+		"by", created.CreatedBy, "ends_at", created.EndsAt)
+
+	svc.applySilences(ctx, time.Now())
+	return created, nil
+}
+
+// listSilences returns the silences that have not ended.
+func (svc *AlertService) listSilences() []*models.AlertSilence {
+	silences := make([]*models.AlertSilence, 0, len(svc.silences))
+	for _, silence := range svc.silences {
+		copied := *silence
+		silences = append(silences, &copied)
+	}
+	return silences
+}
+
+// expireSilence ends a silence immediately and unmutes its alerts.
+func (svc *AlertService) expireSilence(ctx context.Context, id int64) (*models.AlertSilence, error) {
+	silence, exists := svc.silences[id]
+	if !exists {
+		return nil, fmt.Errorf("silence %d not found or already ended", id)
+	}
+
+	now := time.Now()
+	silence.EndsAt = now
+	if _, err := svc.silenceRepo.Update(ctx, id, silence); err != nil {
+		return nil, fmt.Errorf("failed to expire silence %d: %w", id, err)
+	}
+
+	delete(svc.silences, id)
+	svc.applySilences(ctx, now)
+	return silence, nil
+}
+
+// expireSilences drops ended silences and unmutes alerts no other silence covers.
+func (svc *AlertService) expireSilences(ctx context.Context, now time.Time) {
+	expired := false
+	for id, silence := range svc.silences {
+		if !now.Before(silence.EndsAt) {
+			delete(svc.silences, id)
+			expired = true
+		}
+	}
+	if expired {
+		svc.applySilences(ctx, now)
+	}
+}
+
+// applySilences brings every open alert in line with the active silences.
+func (svc *AlertService) applySilences(ctx context.Context, now time.Time) {
+	if len(svc.open) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(svc.open))
+	for key := range svc.open {
+		ids = append(ids, key.deviceID)
+	}
+	devices := svc.fetchDevices(ids)
+
+	for key, instance := range svc.open {
+		device, exists := devices[key.deviceID]
+		if !exists {
+			continue // Device no longer monitored; leave the alert as it is
+		}
+		svc.updateSilenced(ctx, instance, svc.matchSilence(key.ruleID, device, now))
+	}
+}
+
+// matchSilence returns an active silence covering the alert, or nil.
+func (svc *AlertService) matchSilence(ruleID int64, device *models.Device, now time.Time) *models.AlertSilence {
+	for _, silence := range svc.silences {
+		if silence.Active(now) && silence.Matches(ruleID, device) {
+			return silence
+		}
+	}
+	return nil
+}
+
+// updateSilenced mutes or unmutes an open alert. Unmuted alerts return to acknowledged if someone owned them.
+func (svc *AlertService) updateSilenced(ctx context.Context, instance *models.AlertInstance, silence *models.AlertSilence) {
+	previous := *instance
+	switch {
+	case silence != nil && (instance.SilenceID == nil || *instance.SilenceID != silence.ID):
+		instance.State = models.AlertStateSilenced
+		instance.SilenceID = &silence.ID
+	case silence == nil && instance.State == models.AlertStateSilenced:
+		instance.State = models.AlertStateFiring
+		if instance.AcknowledgedAt != nil {
+			instance.State = models.AlertStateAcknowledged
+		}
+		instance.SilenceID = nil
+	default:
+		return
+	}
+
+	if _, err := svc.instanceRepo.Update(ctx, instance.ID, instance); err != nil {
+		*instance = previous
+		slog.Error("Failed to update silenced alert", "component", "AlertService", "alert_id", instance.ID, "error", err)
+		return
+	}
+	slog.Info("Alert silence changed", "component", "AlertService", "alert_id", instance.ID, "state", instance.State)
+	if instance.State == models.AlertStateFiring {
+		svc.notify(models.NotificationAlertFiring, instance) // Still firing once the silence ended
+	}
+}
@@ -0,0 +1,154 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/database"
+	"nms/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AvailabilityService persists device availability as state-change intervals
+// and computes uptime/MTTR/MTBF reports from them.
+// Outcomes arrive from FailureService; only changes of state touch the database.
+type AvailabilityService struct {
+	outcomes <-chan models.Event   // Input: failure/success events forwarded by FailureService
+	requests <-chan models.Request // Input: report requests from the API
+
+	db           *sqlx.DB
+	intervalRepo database.Repository[models.AvailabilityInterval]
+	deviceRepo   database.Repository[models.Device]
+
+	// Open interval per device (owned by the Run goroutine)
+	open map[int64]*models.AvailabilityInterval
+
+	defaultRange time.Duration
+}
+
+// NewAvailabilityService creates a new AvailabilityService instance.
+func NewAvailabilityService(
+	outcomes <-chan models.Event,
+	requests <-chan models.Request,
+	db *sqlx.DB,
+	defaultRangeHours int,
+) *AvailabilityService {
+	return &AvailabilityService{
+		outcomes:     outcomes,
+		requests:     requests,
+		db:           db,
+		intervalRepo: database.NewSqlxRepository[models.AvailabilityInterval](db),
+		deviceRepo:   database.NewSqlxRepository[models.Device](db),
+		open:         make(map[int64]*models.AvailabilityInterval),
+		defaultRange: time.Duration(defaultRangeHours) * time.Hour,
+	}
+}
+
+// LoadOpenIntervals restores each device's current interval so a restart continues the history.
+func (svc *AvailabilityService) LoadOpenIntervals(ctx context.Context) error {
+	var intervals []*models.AvailabilityInterval
+	err := svc.db.SelectContext(ctx, &intervals,
+		"SELECT * FROM device_availability WHERE ended_at IS NULL ORDER BY started_at")
+	if err != nil {
+		return err
+	}
+
+	// Later rows win if an unclean shutdown left several open intervals for a device
+	for _, interval := range intervals {
+		svc.open[interval.DeviceID] = interval
+	}
+	slog.Info("Loaded open availability intervals", "component", "AvailabilityService", "count", len(svc.open))
+	return nil
+}
+
+// Run starts the availability service's main loop.
+func (svc *AvailabilityService) Run(ctx context.Context) {
+	slog.Info("Starting availability service", "component", "AvailabilityService")
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping availability service", "component", "AvailabilityService")
+			return
+		case event := <-svc.outcomes:
+			svc.handleOutcome(ctx, event)
+		case req := <-svc.requests:
+			// Reports only read the database, so they don't hold up recording
+			go svc.handleRequest(ctx, req)
+		}
+	}
+}
+
+// handleOutcome maps a check outcome to an availability state and records it.
+func (svc *AvailabilityService) handleOutcome(ctx context.Context, event models.Event) {
+	switch payload := event.Payload.(type) {
+	case *models.DeviceFailureEvent:
+		state := models.AvailabilityDown
+		if payload.Reason == "dependency" {
+			state = models.AvailabilityUnreachable
+		}
+		svc.record(ctx, payload.DeviceID, state, payload.Reason, payload.Timestamp)
+	case *models.DeviceSuccessEvent:
+		svc.record(ctx, payload.DeviceID, models.AvailabilityUp, payload.Reason, payload.Timestamp)
+	default:
+		slog.Error("Invalid payload type in availability event", "component", "AvailabilityService", "type", event.Type)
+	}
+}
+
+// record closes the device's open interval and starts a new one when the state changes.
+// A successful ping does not end a poll outage: the device answers but its service does not.
+func (svc *AvailabilityService) record(ctx context.Context, deviceID int64, state, reason string, at time.Time) {
+	current, exists := svc.open[deviceID]
+	if exists {
+		if current.State == state {
+			return
+		}
+		if state == models.AvailabilityUp && reason == "ping" && current.State == models.AvailabilityDown && current.Reason == "poll" {
+			return
+		}
+
+		current.EndedAt = &at
+		if _, err := svc.intervalRepo.Update(ctx, current.ID, current); err != nil {
+			slog.Error("Failed to close availability interval", "component", "AvailabilityService", "device_id", deviceID, "error", err)
+			return
+		}
+	}
+
+	next, err := svc.intervalRepo.Create(ctx, &models.AvailabilityInterval{
+		DeviceID:  deviceID,
+		State:     state,
+		Reason:    reason,
+		StartedAt: at,
+	})
+	if err != nil {
+		// Usually a device deleted while its last checks were in flight
+		slog.Error("Failed to open availability interval", "component", "AvailabilityService", "device_id", deviceID, "error", err)
+		delete(svc.open, deviceID)
+		return
+	}
+
+	svc.open[deviceID] = next
+	slog.Debug("Availability changed", "component", "AvailabilityService", "device_id", deviceID, "state", state, "reason", reason)
+}
+
+// handleRequest answers report requests.
+func (svc *AvailabilityService) handleRequest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpAvailabilityReport:
+		query, ok := req.Payload.(*models.AvailabilityReportRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for availability report")
+			break
+		}
+		resp.Data, resp.Error = svc.report(ctx, query)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
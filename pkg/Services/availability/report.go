@@ -0,0 +1,114 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// report builds availability reports for one device or every device of a discovery profile.
+func (svc *AvailabilityService) report(ctx context.Context, query *models.AvailabilityReportRequest) ([]*models.AvailabilityReport, error) {
+	end := query.End
+	if end.IsZero() || end.After(time.Now()) {
+		end = time.Now()
+	}
+	start := query.Start
+	if start.IsZero() {
+		start = end.Add(-svc.defaultRange)
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	var devices []*models.Device
+	if query.DiscoveryProfileID != 0 {
+		list, err := svc.deviceRepo.ListByFields(ctx, map[string]any{"discovery_profile_id": query.DiscoveryProfileID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list devices of discovery profile %d: %w", query.DiscoveryProfileID, err)
+		}
+		devices = list
+	} else {
+		device, err := svc.deviceRepo.Get(ctx, query.DeviceID)
+		if err != nil {
+			return nil, fmt.Errorf("device %d not found: %w", query.DeviceID, err)
+		}
+		devices = []*models.Device{device}
+	}
+
+	reports := make([]*models.AvailabilityReport, 0, len(devices))
+	for _, device := range devices {
+		var intervals []*models.AvailabilityInterval
+		err := svc.db.SelectContext(ctx, &intervals, `
+			SELECT * FROM device_availability
+			WHERE device_id = $1
+			  AND started_at < $3
+			  AND (ended_at IS NULL OR ended_at > $2)
+			ORDER BY started_at`, device.ID, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load availability for device %d: %w", device.ID, err)
+		}
+
+		report := summarize(intervals, start, end)
+		report.DeviceID = device.ID
+		report.Hostname = device.Hostname
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// summarize computes state durations and outages for intervals clipped to [start, end].
+// Adjacent down intervals (e.g. ping then poll failures) form a single outage.
+func summarize(intervals []*models.AvailabilityInterval, start, end time.Time) *models.AvailabilityReport {
+	report := &models.AvailabilityReport{Start: start, End: end, Outages: make([]models.Outage, 0)}
+
+	var covered time.Duration
+	var outage *models.Outage
+	for _, interval := range intervals {
+		from, to := interval.StartedAt, end
+		if interval.EndedAt != nil && interval.EndedAt.Before(end) {
+			to = *interval.EndedAt
+		}
+		if from.Before(start) {
+			from = start
+		}
+		span := to.Sub(from)
+		covered += span
+
+		switch interval.State {
+		case models.AvailabilityUp:
+			report.UpSeconds += int64(span.Seconds())
+		case models.AvailabilityDown:
+			report.DownSeconds += int64(span.Seconds())
+		case models.AvailabilityUnreachable:
+			report.UnreachableSeconds += int64(span.Seconds())
+		}
+
+		if interval.State != models.AvailabilityDown {
+			outage = nil
+			continue
+		}
+		if outage == nil {
+			report.Outages = append(report.Outages, models.Outage{StartedAt: interval.StartedAt, Reason: interval.Reason})
+			outage = &report.Outages[len(report.Outages)-1]
+		}
+		outage.EndedAt = interval.EndedAt
+		outage.DurationSeconds += int64(span.Seconds())
+	}
+
+	report.UnknownSeconds = int64((end.Sub(start) - covered).Seconds())
+	report.OutageCount = len(report.Outages)
+
+	if observed := report.UpSeconds + report.DownSeconds; observed > 0 {
+		uptime := float64(report.UpSeconds) / float64(observed) * 100
+		report.UptimePercent = &uptime
+	}
+	if report.OutageCount > 0 {
+		mttr := report.DownSeconds / int64(report.OutageCount)
+		mtbf := report.UpSeconds / int64(report.OutageCount)
+		report.MTTRSeconds = &mttr
+		report.MTBFSeconds = &mtbf
+	}
+	return report
+}
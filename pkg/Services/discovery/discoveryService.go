@@ -4,8 +4,6 @@ import (
 	"context"
 	"log/slog"
 	"net"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -176,15 +174,10 @@ func (discovery *DiscoveryService) runDiscovery(ctx context.Context, profile *mo
 	}
 
 	// Try pluginDir/protocol (standalone) then pluginDir/protocol/protocol (nested)
-	binPath := filepath.Join(discovery.pluginDir, protocol)
-	info, err := os.Stat(binPath)
-	if err != nil || info.IsDir() {
-		binPath = filepath.Join(discovery.pluginDir, protocol, protocol)
-		info, err = os.Stat(binPath)
-		if err != nil || info.IsDir() {
-			slog.Error("Plugin binary not found or is a directory", "component", "DiscoveryService", "protocol", protocol, "bin_path", binPath)
-			return
-		}
+	binPath, err := pluginWorker.ResolveBinary(discovery.pluginDir, protocol)
+	if err != nil {
+		slog.Error("Plugin binary not found or is a directory", "component", "DiscoveryService", "protocol", protocol, "error", err)
+		return
 	}
 
 	// 4. Register pending discoveries and build tasks
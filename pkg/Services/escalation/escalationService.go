@@ -0,0 +1,233 @@
+package escalation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/database"
+	"nms/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// escalationCandidate is a firing, unacknowledged alert whose rule has an escalation policy.
+type escalationCandidate struct {
+	AlertID          int64     `db:"alert_id"`
+	RuleID           int64     `db:"rule_id"`
+	RuleName         string    `db:"rule_name"`
+	Path             string    `db:"path"`
+	DeviceID         int64     `db:"device_id"`
+	Hostname         string    `db:"hostname"`
+	IPAddress        string    `db:"ip_address"`
+	PluginID         string    `db:"plugin_id"`
+	Severity         string    `db:"severity"`
+	Value            float64   `db:"value"`
+	FiredAt          time.Time `db:"fired_at"`
+	PolicyID         int64     `db:"policy_id"`
+	Step             int       `db:"step"`               // Steps already notified
+	ProgressPolicyID *int64    `db:"progress_policy_id"` // Policy the progress was recorded under
+}
+
+// EscalationService walks unacknowledged alerts through their rule's escalation policy.
+// It reads alert state from Postgres, so it never blocks AlertService, and records its own
+// progress per alert in alert_escalations. Acknowledged, silenced and resolved alerts stop escalating.
+type EscalationService struct {
+	notifications chan<- models.Event   // Output: escalation steps to NotificationService
+	requests      <-chan models.Request // Input: on-call lookups from the API
+
+	db           *sqlx.DB
+	policyRepo   database.Repository[models.EscalationPolicy]
+	scheduleRepo database.Repository[models.OnCallSchedule]
+
+	interval time.Duration
+}
+
+// NewEscalationService creates a new EscalationService instance.
+func NewEscalationService(
+	notifications chan<- models.Event,
+	requests <-chan models.Request,
+	db *sqlx.DB,
+	intervalSec int,
+) *EscalationService {
+	return &EscalationService{
+		notifications: notifications,
+		requests:      requests,
+		db:            db,
+		policyRepo:    database.NewSqlxRepository[models.EscalationPolicy](db),
+		scheduleRepo:  database.NewSqlxRepository[models.OnCallSchedule](db),
+		interval:      time.Duration(intervalSec) * time.Second,
+	}
+}
+
+// Run starts the escalation service's main loop.
+func (svc *EscalationService) Run(ctx context.Context) {
+	slog.Info("Starting escalation service", "component", "EscalationService", "interval", svc.interval.String())
+
+	ticker := time.NewTicker(svc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping escalation service", "component", "EscalationService")
+			return
+		case now := <-ticker.C:
+			svc.escalate(ctx, now)
+		case req := <-svc.requests:
+			go svc.handleRequest(ctx, req)
+		}
+	}
+}
+
+// escalate notifies the latest due step of every escalating alert.
+// Steps that became due together (e.g. after downtime) collapse into the last one.
+func (svc *EscalationService) escalate(ctx context.Context, now time.Time) {
+	var candidates []*escalationCandidate
+	err := svc.db.SelectContext(ctx, &candidates, `
+		SELECT a.id AS alert_id, a.rule_id, r.name AS rule_name, r.path, a.device_id,
+		       COALESCE(d.hostname, '') AS hostname, host(d.ip_address) AS ip_address, d.plugin_id,
+		       a.severity, a.value, a.fired_at, r.escalation_policy_id AS policy_id,
+		       COALESCE(e.step, 0) AS step, e.policy_id AS progress_policy_id
+		FROM alert_instances a
+		JOIN alert_rules r ON r.id = a.rule_id
+		JOIN devices d ON d.id = a.device_id
+		LEFT JOIN alert_escalations e ON e.alert_id = a.id
+		WHERE a.state = $1 AND r.escalation_policy_id IS NOT NULL`, models.AlertStateFiring)
+	if err != nil {
+		slog.Error("Failed to load escalating alerts", "component", "EscalationService", "error", err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	policies, err := svc.policyRepo.List(ctx)
+	if err != nil {
+		slog.Error("Failed to load escalation policies", "component", "EscalationService", "error", err)
+		return
+	}
+	byID := make(map[int64]*models.EscalationPolicy, len(policies))
+	for _, policy := range policies {
+		byID[policy.ID] = policy
+	}
+
+	for _, alert := range candidates {
+		policy, exists := byID[alert.PolicyID]
+		if !exists {
+			continue
+		}
+		if alert.ProgressPolicyID != nil && *alert.ProgressPolicyID != alert.PolicyID {
+			alert.Step = 0 // The rule moved to another policy; start it from the top
+		}
+
+		due := -1
+		elapsed := now.Sub(alert.FiredAt)
+		for i := alert.Step; i < len(policy.Steps); i++ {
+			if elapsed >= time.Duration(policy.Steps[i].DelayMinutes)*time.Minute {
+				due = i
+			}
+		}
+		if due < 0 {
+			continue
+		}
+
+		if err := svc.notifyStep(ctx, alert, policy, due, now); err != nil {
+			slog.Error("Failed to escalate alert", "component", "EscalationService", "alert_id", alert.AlertID, "error", err)
+		}
+	}
+}
+
+// notifyStep sends one escalation step and records the alert's progress.
+func (svc *EscalationService) notifyStep(ctx context.Context, alert *escalationCandidate, policy *models.EscalationPolicy, index int, now time.Time) error {
+	step := policy.Steps[index]
+
+	var recipient string
+	if step.ScheduleID != nil {
+		shift, err := svc.onCall(ctx, *step.ScheduleID, now)
+		if err != nil {
+			slog.Warn("On-call lookup failed, escalating to the channel only", "component", "EscalationService",
+				"schedule_id", *step.ScheduleID, "error", err)
+		} else {
+			recipient = shift.Recipient
+		}
+	}
+
+	target := alert.Hostname
+	if target == "" {
+		target = alert.IPAddress
+	}
+	notification := &models.Notification{
+		Kind:      models.NotificationAlertEscalation,
+		Severity:  alert.Severity,
+		Title:     fmt.Sprintf("Unacknowledged: %s on %s (escalation step %d)", alert.RuleName, target, index+1),
+		Message:   fmt.Sprintf("%s = %g, firing since %s", alert.Path, alert.Value, alert.FiredAt.Format(time.RFC3339)),
+		DeviceID:  alert.DeviceID,
+		Hostname:  alert.Hostname,
+		IPAddress: alert.IPAddress,
+		PluginID:  alert.PluginID,
+		AlertID:   alert.AlertID,
+		RuleID:    alert.RuleID,
+		State:     models.AlertStateFiring,
+		Value:     alert.Value,
+		Timestamp: now,
+		ChannelID: step.ChannelID,
+		Recipient: recipient,
+		Step:      index + 1,
+	}
+
+	select {
+	case svc.notifications <- models.Event{Type: models.EventNotification, Payload: notification}:
+	default:
+		return fmt.Errorf("notification channel full")
+	}
+
+	// Recorded after sending: a failed write repeats the step rather than skipping it
+	_, err := svc.db.ExecContext(ctx, `
+		INSERT INTO alert_escalations (alert_id, policy_id, step, escalated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (alert_id) DO UPDATE SET policy_id = EXCLUDED.policy_id, step = EXCLUDED.step, escalated_at = EXCLUDED.escalated_at`,
+		alert.AlertID, policy.ID, index+1, now)
+	if err != nil {
+		return fmt.Errorf("failed to record escalation progress: %w", err)
+	}
+
+	slog.Warn("Alert escalated", "component", "EscalationService", "alert_id", alert.AlertID,
+		"policy", policy.Name, "step", index+1, "channel_id", step.ChannelID, "recipient", recipient)
+	return nil
+}
+
+// onCall resolves who is on call for a schedule at an instant.
+func (svc *EscalationService) onCall(ctx context.Context, scheduleID int64, at time.Time) (*models.OnCallShift, error) {
+	schedule, err := svc.scheduleRepo.Get(ctx, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("on-call schedule %d not found", scheduleID)
+	}
+
+	var overrides []*models.OnCallOverride
+	err = svc.db.SelectContext(ctx, &overrides,
+		"SELECT * FROM oncall_overrides WHERE schedule_id = $1 AND starts_at <= $2 AND ends_at > $2", scheduleID, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load overrides: %w", err)
+	}
+	return schedule.OnCallAt(at, overrides), nil
+}
+
+// handleRequest answers on-call lookups.
+func (svc *EscalationService) handleRequest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpResolveOnCall:
+		at, ok := req.Payload.(time.Time)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for on-call lookup")
+			break
+		}
+		resp.Data, resp.Error = svc.onCall(ctx, req.ID, at)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
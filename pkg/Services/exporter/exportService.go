@@ -0,0 +1,334 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nms/pkg/models"
+	"nms/pkg/plugin"
+)
+
+// Sink kinds
+const (
+	SinkRemoteWrite = "remote_write"
+	SinkOTLP        = "otlp"
+)
+
+// maxRetryDelay caps the doubling delay between attempts to send a batch.
+const maxRetryDelay = time.Minute
+
+// PollBatch is a stored poll batch handed over by MetricsService.
+type PollBatch struct {
+	Results  []plugin.Result
+	PolledAt time.Time
+}
+
+// Batch is a poll batch flattened with one sink's mapping.
+type Batch struct {
+	PolledAt time.Time
+	Samples  []Sample
+}
+
+// SinkConfig is one external time-series backend.
+type SinkConfig struct {
+	Kind        string             // SinkRemoteWrite or SinkOTLP
+	URL         string             // remote_write endpoint, or OTLP/HTTP metrics endpoint (.../v1/metrics)
+	BearerToken string             // Sent as Authorization: Bearer when set
+	Mappings    map[string]Mapping // Per plugin ID
+}
+
+// sink holds the retry queue and counters of one backend.
+type sink struct {
+	config SinkConfig
+	mapper *Mapper
+	queue  chan *Batch
+
+	sentBatches    atomic.Int64
+	sentSamples    atomic.Int64
+	failedAttempts atomic.Int64
+	droppedBatches atomic.Int64
+
+	mu          sync.Mutex // Guards the fields below
+	lagSeconds  float64
+	lastSuccess time.Time
+	lastError   string
+}
+
+// ExportService forwards stored poll batches to external time-series backends. Each sink
+// flattens batches with its own mapping and sends them from its own bounded queue, so a
+// slow or failing backend only delays and drops its own batches.
+type ExportService struct {
+	batches  <-chan PollBatch
+	crudReqs chan<- models.Request // Device hostnames and plugins from the EntityService cache
+	requests <-chan models.Request
+
+	sinks       []*sink
+	maxAttempts int
+	retryBase   time.Duration
+	httpClient  *http.Client
+}
+
+// NewExportService creates a new ExportService instance.
+func NewExportService(
+	batches <-chan PollBatch,
+	crudReqs chan<- models.Request,
+	requests <-chan models.Request,
+	sinks []SinkConfig,
+	queueSize int,
+	maxAttempts int,
+	retryBaseSec int,
+	timeoutSec int,
+) *ExportService {
+	svc := &ExportService{
+		batches:     batches,
+		crudReqs:    crudReqs,
+		requests:    requests,
+		maxAttempts: maxAttempts,
+		retryBase:   time.Duration(retryBaseSec) * time.Second,
+		httpClient:  &http.Client{Timeout: time.Duration(timeoutSec) * time.Second},
+	}
+	for _, config := range sinks {
+		svc.sinks = append(svc.sinks, &sink{
+			config: config,
+			mapper: NewMapper(config.Mappings),
+			queue:  make(chan *Batch, queueSize),
+		})
+	}
+	return svc
+}
+
+// Enabled reports whether any sink is configured.
+func (svc *ExportService) Enabled() bool {
+	return len(svc.sinks) > 0
+}
+
+// Run starts the export service's main loop.
+func (svc *ExportService) Run(ctx context.Context) {
+	slog.Info("Starting export service", "component", "ExportService", "sinks", len(svc.sinks))
+
+	var wg sync.WaitGroup
+	for _, s := range svc.sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.drain(ctx, s)
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			slog.Info("Stopping export service", "component", "ExportService")
+			return
+		case batch := <-svc.batches:
+			svc.fanOut(ctx, batch)
+		case req := <-svc.requests:
+			svc.handleRequest(req)
+		}
+	}
+}
+
+// fanOut flattens a poll batch for every sink and queues it.
+func (svc *ExportService) fanOut(ctx context.Context, batch PollBatch) {
+	devices := svc.lookupDevices(ctx, batch.Results)
+	if len(devices) == 0 {
+		return
+	}
+
+	for _, s := range svc.sinks {
+		flat := &Batch{PolledAt: batch.PolledAt}
+		for _, result := range batch.Results {
+			device, ok := devices[result.DeviceID]
+			if !result.Success || !ok {
+				continue
+			}
+			flat.Samples = append(flat.Samples, s.mapper.DeviceSamples(device.ID, device.Hostname, device.PluginID, batch.PolledAt, result.Data)...)
+		}
+		if len(flat.Samples) > 0 {
+			s.enqueue(flat)
+		}
+	}
+}
+
+// enqueue adds a batch to the sink's queue, dropping the oldest batch while it is full.
+func (s *sink) enqueue(batch *Batch) {
+	for {
+		select {
+		case s.queue <- batch:
+			return
+		default:
+		}
+		select {
+		case <-s.queue:
+			s.droppedBatches.Add(1)
+			slog.Warn("Export queue full, dropped oldest batch", "component", "ExportService", "kind", s.config.Kind, "url", s.config.URL)
+		default:
+		}
+	}
+}
+
+// lookupDevices fetches the devices of the successful results from the EntityService cache.
+func (svc *ExportService) lookupDevices(ctx context.Context, results []plugin.Result) map[int64]models.Device {
+	ids := make([]int64, 0, len(results))
+	for _, result := range results {
+		if result.Success {
+			ids = append(ids, result.DeviceID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	replyCh := make(chan models.Response, 1)
+	select {
+	case svc.crudReqs <- models.Request{Operation: models.OpLookupDevices, EntityType: "Device", IDs: ids, ReplyCh: replyCh}:
+	case <-ctx.Done():
+		return nil
+	}
+	select {
+	case resp := <-replyCh:
+		devices, _ := resp.Data.(map[int64]models.Device)
+		return devices
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// drain sends a sink's queued batches one at a time, oldest first.
+func (svc *ExportService) drain(ctx context.Context, s *sink) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-s.queue:
+			svc.deliver(ctx, s, batch)
+		}
+	}
+}
+
+// deliver sends a batch, retrying with a doubling delay until it succeeds, the backend
+// rejects it, or maxAttempts is reached.
+func (svc *ExportService) deliver(ctx context.Context, s *sink, batch *Batch) {
+	delay := svc.retryBase
+	for attempt := 1; ; attempt++ {
+		retryable, err := svc.send(ctx, s, batch)
+		if err == nil {
+			s.sentBatches.Add(1)
+			s.sentSamples.Add(int64(len(batch.Samples)))
+			s.mu.Lock()
+			s.lastSuccess = time.Now()
+			s.lagSeconds = s.lastSuccess.Sub(batch.PolledAt).Seconds()
+			s.mu.Unlock()
+			return
+		}
+
+		s.failedAttempts.Add(1)
+		s.mu.Lock()
+		s.lastError = err.Error()
+		s.mu.Unlock()
+		if !retryable || attempt >= svc.maxAttempts {
+			s.droppedBatches.Add(1)
+			slog.Error("Export failed, dropped batch", "component", "ExportService",
+				"kind", s.config.Kind, "url", s.config.URL, "attempts", attempt, "samples", len(batch.Samples), "error", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// send POSTs a batch in the sink's wire format. Client errors other than 429 mean the
+// backend rejected the data, so the batch is not retried.
+func (svc *ExportService) send(ctx context.Context, s *sink, batch *Batch) (retryable bool, err error) {
+	var body []byte
+	var headers map[string]string
+	switch s.config.Kind {
+	case SinkRemoteWrite:
+		body, headers = encodeRemoteWrite(batch), remoteWriteHeaders
+	case SinkOTLP:
+		if body, err = encodeOTLP(batch); err != nil {
+			return false, err
+		}
+		headers = otlpHeaders
+	default:
+		return false, fmt.Errorf("unknown sink kind %q", s.config.Kind)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if s.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BearerToken)
+	}
+
+	resp, err := svc.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return false, nil
+}
+
+// handleRequest answers stats requests.
+func (svc *ExportService) handleRequest(req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpExportStats:
+		resp.Data = svc.stats()
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// stats returns the counters of every sink.
+func (svc *ExportService) stats() []*models.ExporterStats {
+	stats := make([]*models.ExporterStats, 0, len(svc.sinks))
+	for _, s := range svc.sinks {
+		st := &models.ExporterStats{
+			Kind:           s.config.Kind,
+			URL:            s.config.URL,
+			QueueLength:    len(s.queue),
+			QueueCapacity:  cap(s.queue),
+			SentBatches:    s.sentBatches.Load(),
+			SentSamples:    s.sentSamples.Load(),
+			FailedAttempts: s.failedAttempts.Load(),
+			DroppedBatches: s.droppedBatches.Load(),
+		}
+		s.mu.Lock()
+		st.LagSeconds, st.LastError = s.lagSeconds, s.lastError
+		if !s.lastSuccess.IsZero() {
+			lastSuccess := s.lastSuccess
+			st.LastSuccessAt = &lastSuccess
+		}
+		s.mu.Unlock()
+		stats = append(stats, st)
+	}
+	return stats
+}
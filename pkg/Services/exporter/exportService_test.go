@@ -0,0 +1,203 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// newTestService returns an ExportService with one sink pointed at url and millisecond retries.
+func newTestService(kind, url string, queueSize, maxAttempts int) *ExportService {
+	svc := NewExportService(nil, nil, nil, []SinkConfig{{Kind: kind, URL: url, BearerToken: "secret"}}, queueSize, maxAttempts, 1, 5)
+	svc.retryBase = time.Millisecond
+	return svc
+}
+
+func testBatch() *Batch {
+	return &Batch{
+		PolledAt: time.Unix(1_700_000_000, 0),
+		Samples: []Sample{
+			{Name: "nms_cpu_usage", Labels: []Label{{Name: "device_id", Value: "1"}}, Value: 10},
+			{Name: "nms_cpu_usage", Labels: []Label{{Name: "device_id", Value: "2"}}, Value: 20},
+			{Name: "nms_mem_free", Labels: []Label{{Name: "device_id", Value: "1"}}, Value: 512},
+		},
+	}
+}
+
+func TestDeliverRemoteWrite(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	svc := newTestService(SinkRemoteWrite, server.URL, 10, 3)
+	s := svc.sinks[0]
+	svc.deliver(context.Background(), s, testBatch())
+
+	if s.sentBatches.Load() != 1 || s.sentSamples.Load() != 3 {
+		t.Fatalf("sent batches=%d samples=%d, want 1 and 3", s.sentBatches.Load(), s.sentSamples.Load())
+	}
+	for name, want := range map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+		"Authorization":                     "Bearer secret",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("body is not a snappy block: %v", err)
+	}
+	if series := decodeWriteRequest(t, raw); len(series) != 3 {
+		t.Errorf("got %d series, want 3", len(series))
+	}
+}
+
+func TestDeliverOTLP(t *testing.T) {
+	var request otlpRequest
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := newTestService(SinkOTLP, server.URL, 10, 3)
+	batch := testBatch()
+	svc.deliver(context.Background(), svc.sinks[0], batch)
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if len(request.ResourceMetrics) != 1 {
+		t.Fatalf("got %d resourceMetrics, want 1", len(request.ResourceMetrics))
+	}
+	resource := request.ResourceMetrics[0]
+	if attrs := resource.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || attrs[0].Value.StringValue != "nms" {
+		t.Errorf("resource attributes = %+v", attrs)
+	}
+	if len(resource.ScopeMetrics) != 1 {
+		t.Fatalf("got %d scopeMetrics, want 1", len(resource.ScopeMetrics))
+	}
+	metrics := resource.ScopeMetrics[0].Metrics
+	if len(metrics) != 2 || metrics[0].Name != "nms_cpu_usage" || metrics[1].Name != "nms_mem_free" {
+		t.Fatalf("metrics = %+v, want one gauge per name", metrics)
+	}
+	points := metrics[0].Gauge.DataPoints
+	if len(points) != 2 {
+		t.Fatalf("nms_cpu_usage has %d points, want 2", len(points))
+	}
+	wantTime := strconv.FormatInt(batch.PolledAt.UnixNano(), 10)
+	if p := points[1]; p.AsDouble != 20 || p.TimeUnixNano != wantTime || len(p.Attributes) != 1 ||
+		p.Attributes[0].Key != "device_id" || p.Attributes[0].Value.StringValue != "2" {
+		t.Errorf("second data point = %+v", p)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) < 3 {
+					w.WriteHeader(status)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			svc := newTestService(SinkRemoteWrite, server.URL, 10, 5)
+			s := svc.sinks[0]
+			svc.deliver(context.Background(), s, testBatch())
+
+			if calls.Load() != 3 {
+				t.Errorf("backend called %d times, want 3", calls.Load())
+			}
+			if s.failedAttempts.Load() != 2 || s.sentBatches.Load() != 1 || s.droppedBatches.Load() != 0 {
+				t.Errorf("failed=%d sent=%d dropped=%d, want 2/1/0", s.failedAttempts.Load(), s.sentBatches.Load(), s.droppedBatches.Load())
+			}
+		})
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	svc := newTestService(SinkRemoteWrite, server.URL, 10, 4)
+	s := svc.sinks[0]
+	svc.deliver(context.Background(), s, testBatch())
+
+	if calls.Load() != 4 || s.droppedBatches.Load() != 1 {
+		t.Errorf("calls=%d dropped=%d, want 4 and 1", calls.Load(), s.droppedBatches.Load())
+	}
+}
+
+func TestDeliverDropsOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	svc := newTestService(SinkOTLP, server.URL, 10, 5)
+	s := svc.sinks[0]
+	svc.deliver(context.Background(), s, testBatch())
+
+	if calls.Load() != 1 {
+		t.Errorf("backend called %d times, want 1 (400 is not retried)", calls.Load())
+	}
+	if s.droppedBatches.Load() != 1 || s.sentBatches.Load() != 0 {
+		t.Errorf("dropped=%d sent=%d, want 1 and 0", s.droppedBatches.Load(), s.sentBatches.Load())
+	}
+	if stats := svc.stats(); stats[0].LastError == "" {
+		t.Error("last error not recorded")
+	}
+}
+
+func TestEnqueueDropsOldestWhenFull(t *testing.T) {
+	svc := newTestService(SinkRemoteWrite, "http://127.0.0.1:0", 2, 1)
+	s := svc.sinks[0]
+
+	var batches []*Batch
+	for i := 0; i < 5; i++ {
+		batch := &Batch{PolledAt: time.Unix(int64(i), 0)}
+		batches = append(batches, batch)
+		s.enqueue(batch)
+	}
+
+	if s.droppedBatches.Load() != 3 {
+		t.Errorf("dropped %d batches, want 3", s.droppedBatches.Load())
+	}
+	if first, second := <-s.queue, <-s.queue; first != batches[3] || second != batches[4] {
+		t.Error("queue should keep the two newest batches")
+	}
+	if stats := svc.stats(); stats[0].DroppedBatches != 3 || stats[0].QueueCapacity != 2 {
+		t.Errorf("stats = %+v", stats[0])
+	}
+}
@@ -0,0 +1,188 @@
+// Package exporter turns stored poll results into samples for external monitoring
+// systems: flattened metric names with labels, mapped per plugin.
+package exporter
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPrefix starts every exported metric name unless a plugin mapping overrides it.
+const DefaultPrefix = "nms"
+
+// invalidNameChars are replaced with underscores in metric names.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// LabelRule turns the keys at the "*" segments of Match into labels: with Match
+// "disk.drives.*" and Labels ["drive"], disk.drives.c.free becomes disk_drives_free{drive="c"}.
+type LabelRule struct {
+	Match  string   // Dotted path prefix, "*" segments become labels
+	Labels []string // One label name per "*" in Match
+}
+
+// Mapping is how the poll results of one plugin are exported.
+type Mapping struct {
+	Prefix  string      // Metric name prefix, DefaultPrefix when empty
+	Labels  []LabelRule // First matching rule wins; paths no rule matches are plain names
+	Exclude []string    // Dotted path prefixes, "*" matching any key, that are not exported
+}
+
+// Label is one name/value pair of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one numeric leaf of a poll result.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+// Mapper flattens poll results with the mapping of each device's plugin.
+type Mapper struct {
+	mappings map[string]Mapping
+}
+
+// NewMapper creates a Mapper. Plugins without a mapping use the default one.
+func NewMapper(mappings map[string]Mapping) *Mapper {
+	return &Mapper{mappings: mappings}
+}
+
+// Flatten returns a sample per numeric leaf of data, each carrying the given base labels.
+// Non-numeric leaves are skipped. Samples are sorted by name.
+func (m *Mapper) Flatten(pluginID string, data json.RawMessage, base []Label) []Sample {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil
+	}
+
+	mapping := m.mappings[pluginID]
+	prefix := mapping.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	var samples []Sample
+	var walk func(node any, path []string)
+	walk = func(node any, path []string) {
+		switch value := node.(type) {
+		case map[string]any:
+			for key, child := range value {
+				walk(child, append(path[:len(path):len(path)], key))
+			}
+		case float64:
+			if len(path) == 0 || excluded(mapping.Exclude, path) {
+				return
+			}
+			samples = append(samples, mapping.sample(prefix, path, base, value))
+		}
+	}
+	walk(root, nil)
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Name != samples[j].Name {
+			return samples[i].Name < samples[j].Name
+		}
+		return labelKey(samples[i].Labels) < labelKey(samples[j].Labels)
+	})
+	return samples
+}
+
+// DeviceSamples flattens a device's poll result with the device_id, hostname and plugin_id
+// labels, and adds a <prefix>_device_last_poll_timestamp_seconds sample for the poll time.
+func (m *Mapper) DeviceSamples(deviceID int64, hostname, pluginID string, polledAt time.Time, data json.RawMessage) []Sample {
+	base := []Label{
+		{Name: "device_id", Value: strconv.FormatInt(deviceID, 10)},
+		{Name: "hostname", Value: hostname},
+		{Name: "plugin_id", Value: pluginID},
+	}
+	prefix := m.mappings[pluginID].Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	samples := m.Flatten(pluginID, data, base)
+	return append(samples, Sample{
+		Name:   prefix + "_device_last_poll_timestamp_seconds",
+		Labels: base,
+		Value:  float64(polledAt.UnixMilli()) / 1000,
+	})
+}
+
+// sample names a leaf by its path, moving the keys at a matching rule's "*" segments into labels.
+func (mapping Mapping) sample(prefix string, path []string, base []Label, value float64) Sample {
+	labels := append([]Label{}, base...)
+	nameParts := []string{prefix}
+	rest := path
+
+	for _, rule := range mapping.Labels {
+		pattern := strings.Split(rule.Match, ".")
+		if !matchesPrefix(pattern, path) {
+			continue
+		}
+		wildcard := 0
+		for i, segment := range pattern {
+			if segment == "*" {
+				labels = append(labels, Label{Name: rule.Labels[wildcard], Value: path[i]})
+				wildcard++
+			} else {
+				nameParts = append(nameParts, segment)
+			}
+		}
+		rest = path[len(pattern):]
+		break
+	}
+
+	nameParts = append(nameParts, rest...)
+	return Sample{Name: metricName(nameParts), Labels: labels, Value: value}
+}
+
+// matchesPrefix reports whether the pattern matches the start of the path.
+func matchesPrefix(pattern, path []string) bool {
+	if len(pattern) > len(path) {
+		return false
+	}
+	for i, segment := range pattern {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// excluded reports whether any exclude pattern matches the path.
+func excluded(patterns []string, path []string) bool {
+	for _, pattern := range patterns {
+		if matchesPrefix(strings.Split(pattern, "."), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricName joins name parts with underscores and replaces characters Prometheus doesn't
+// allow. A name may not start with a digit.
+func metricName(parts []string) string {
+	name := invalidNameChars.ReplaceAllString(strings.Join(parts, "_"), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// labelKey orders label sets for stable output.
+func labelKey(labels []Label) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label.Name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(label.Value))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// OTLP/HTTP request headers; the JSON encoding of ExportMetricsServiceRequest.
+var otlpHeaders = map[string]string{
+	"Content-Type": "application/json",
+}
+
+// otlpServiceName identifies NMS as the resource every exported metric belongs to.
+const otlpServiceName = "nms"
+
+// OTLP JSON message shapes, limited to the gauge fields NMS sends.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name  string    `json:"name"`
+		Gauge otlpGauge `json:"gauge"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		Attributes   []otlpAttribute `json:"attributes"`
+		TimeUnixNano string          `json:"timeUnixNano"` // 64-bit integers are strings in OTLP JSON
+		AsDouble     float64         `json:"asDouble"`
+	}
+	otlpAttribute struct {
+		Key   string          `json:"key"`
+		Value otlpStringValue `json:"value"`
+	}
+	otlpStringValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// encodeOTLP builds an OTLP/HTTP JSON metrics request: one gauge per metric name, one data
+// point per sample with its labels as attributes, all stamped with the poll time.
+func encodeOTLP(batch *Batch) ([]byte, error) {
+	timestamp := strconv.FormatInt(batch.PolledAt.UnixNano(), 10)
+
+	var metrics []otlpMetric
+	index := make(map[string]int)
+	for _, sample := range batch.Samples {
+		i, ok := index[sample.Name]
+		if !ok {
+			i = len(metrics)
+			index[sample.Name] = i
+			metrics = append(metrics, otlpMetric{Name: sample.Name})
+		}
+		attributes := make([]otlpAttribute, 0, len(sample.Labels))
+		for _, label := range sample.Labels {
+			attributes = append(attributes, otlpAttribute{Key: label.Name, Value: otlpStringValue{StringValue: label.Value}})
+		}
+		metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, otlpDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: timestamp,
+			AsDouble:     sample.Value,
+		})
+	}
+
+	return json.Marshal(otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpStringValue{StringValue: otlpServiceName}},
+		}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: otlpServiceName},
+			Metrics: metrics,
+		}},
+	}}})
+}
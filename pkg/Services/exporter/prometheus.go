@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// labelValueEscaper escapes label values for the Prometheus text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes samples in the Prometheus text exposition format (version 0.0.4).
+// Samples of one metric are written together under a single "# TYPE" line, as the format
+// requires, so the input may come from many devices in any order.
+func WritePrometheus(w io.Writer, samples []Sample) error {
+	sorted := append([]Sample{}, samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var b strings.Builder
+	for i, sample := range sorted {
+		if i == 0 || sorted[i-1].Name != sample.Name {
+			b.WriteString("# TYPE ")
+			b.WriteString(sample.Name)
+			b.WriteString(" gauge\n")
+		}
+		b.WriteString(sample.Name)
+		if len(sample.Labels) > 0 {
+			b.WriteByte('{')
+			for j, label := range sample.Labels {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(label.Name)
+				b.WriteString(`="`)
+				b.WriteString(labelValueEscaper.Replace(label.Value))
+				b.WriteByte('"')
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		b.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Prometheus remote_write 1.0 request headers.
+var remoteWriteHeaders = map[string]string{
+	"Content-Type":                      "application/x-protobuf",
+	"Content-Encoding":                  "snappy",
+	"X-Prometheus-Remote-Write-Version": "0.1.0",
+}
+
+// encodeRemoteWrite builds a snappy-compressed prometheus.WriteRequest with one series per
+// sample, all stamped with the poll time:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; } // milliseconds
+func encodeRemoteWrite(batch *Batch) []byte {
+	timestamp := batch.PolledAt.UnixMilli()
+
+	var request []byte
+	for _, sample := range batch.Samples {
+		labels := append([]Label{{Name: "__name__", Value: sample.Name}}, sample.Labels...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		var series []byte
+		for _, label := range labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Name)
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Value)
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, encoded)
+		}
+
+		var point []byte
+		point = protowire.AppendTag(point, 1, protowire.Fixed64Type)
+		point = protowire.AppendFixed64(point, math.Float64bits(sample.Value))
+		point = protowire.AppendTag(point, 2, protowire.VarintType)
+		point = protowire.AppendVarint(point, uint64(timestamp))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, point)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return snappy.Encode(nil, request)
+}
@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodedSeries is one TimeSeries of a decoded WriteRequest.
+type decodedSeries struct {
+	Labels  []Label
+	Samples []decodedSample
+}
+
+type decodedSample struct {
+	Value     float64
+	Timestamp int64
+}
+
+func TestEncodeRemoteWrite(t *testing.T) {
+	polledAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	batch := &Batch{
+		PolledAt: polledAt,
+		Samples: []Sample{
+			{Name: "nms_cpu_usage", Labels: []Label{{Name: "hostname", Value: "router-1"}, {Name: "device_id", Value: "42"}}, Value: 12.5},
+			{Name: "nms_disk_free", Labels: []Label{{Name: "drive", Value: "C"}, {Name: "device_id", Value: "42"}}, Value: -1},
+		},
+	}
+
+	raw, err := snappy.Decode(nil, encodeRemoteWrite(batch))
+	if err != nil {
+		t.Fatalf("snappy decode failed: %v", err)
+	}
+	got := decodeWriteRequest(t, raw)
+
+	want := []decodedSeries{
+		{
+			Labels:  []Label{{"__name__", "nms_cpu_usage"}, {"device_id", "42"}, {"hostname", "router-1"}},
+			Samples: []decodedSample{{Value: 12.5, Timestamp: polledAt.UnixMilli()}},
+		},
+		{
+			Labels:  []Label{{"__name__", "nms_disk_free"}, {"device_id", "42"}, {"drive", "C"}},
+			Samples: []decodedSample{{Value: -1, Timestamp: polledAt.UnixMilli()}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded WriteRequest mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+// decodeWriteRequest parses a prometheus.WriteRequest, failing on any malformed or unexpected field.
+func decodeWriteRequest(t *testing.T, b []byte) []decodedSeries {
+	t.Helper()
+	var series []decodedSeries
+	for len(b) > 0 {
+		field := consumeBytesField(t, &b, 1)
+		var s decodedSeries
+		for len(field) > 0 {
+			num, typ, n := protowire.ConsumeTag(field)
+			if n < 0 || typ != protowire.BytesType {
+				t.Fatalf("TimeSeries: bad tag (n=%d, type=%d)", n, typ)
+			}
+			value, m := protowire.ConsumeBytes(field[n:])
+			if m < 0 {
+				t.Fatalf("TimeSeries: bad length")
+			}
+			field = field[n+m:]
+			switch num {
+			case 1:
+				s.Labels = append(s.Labels, decodeLabel(t, value))
+			case 2:
+				s.Samples = append(s.Samples, decodeSample(t, value))
+			default:
+				t.Fatalf("TimeSeries: unexpected field %d", num)
+			}
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+func decodeLabel(t *testing.T, b []byte) Label {
+	t.Helper()
+	name := consumeBytesField(t, &b, 1)
+	value := consumeBytesField(t, &b, 2)
+	if len(b) > 0 {
+		t.Fatalf("Label: %d trailing bytes", len(b))
+	}
+	return Label{Name: string(name), Value: string(value)}
+}
+
+func decodeSample(t *testing.T, b []byte) decodedSample {
+	t.Helper()
+	num, typ, n := protowire.ConsumeTag(b)
+	if n < 0 || num != 1 || typ != protowire.Fixed64Type {
+		t.Fatalf("Sample: expected double field 1")
+	}
+	bits, m := protowire.ConsumeFixed64(b[n:])
+	if m < 0 {
+		t.Fatalf("Sample: bad value")
+	}
+	b = b[n+m:]
+
+	num, typ, n = protowire.ConsumeTag(b)
+	if n < 0 || num != 2 || typ != protowire.VarintType {
+		t.Fatalf("Sample: expected int64 field 2")
+	}
+	timestamp, m := protowire.ConsumeVarint(b[n:])
+	if m < 0 || len(b) != n+m {
+		t.Fatalf("Sample: bad timestamp")
+	}
+	return decodedSample{Value: math.Float64frombits(bits), Timestamp: int64(timestamp)}
+}
+
+// consumeBytesField reads a length-delimited field with the given number from the front of b.
+func consumeBytesField(t *testing.T, b *[]byte, want protowire.Number) []byte {
+	t.Helper()
+	num, typ, n := protowire.ConsumeTag(*b)
+	if n < 0 || num != want || typ != protowire.BytesType {
+		t.Fatalf("expected bytes field %d, got field %d type %d", want, num, typ)
+	}
+	value, m := protowire.ConsumeBytes((*b)[n:])
+	if m < 0 {
+		t.Fatalf("field %d: bad length", want)
+	}
+	*b = (*b)[n+m:]
+	return value
+}
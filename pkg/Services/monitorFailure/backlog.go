@@ -0,0 +1,52 @@
+package monitorFailure
+
+import (
+	"log/slog"
+
+	"nms/pkg/models"
+)
+
+// maxBacklog bounds each output backlog; beyond it the oldest event is dropped.
+const maxBacklog = 10000
+
+// eventBacklog holds events an output channel couldn't take yet, delivered in order by Run.
+// Sending through it never blocks the FailureService loop on a slow consumer.
+type eventBacklog struct {
+	name    string // For logging
+	events  []models.Event
+	dropped int64
+}
+
+// send delivers the event at once when ch has room and nothing is waiting, otherwise queues it.
+func (backlog *eventBacklog) send(ch chan<- models.Event, event models.Event) {
+	if len(backlog.events) == 0 {
+		select {
+		case ch <- event:
+			return
+		default:
+		}
+	}
+
+	if len(backlog.events) >= maxBacklog {
+		backlog.dropped++
+		slog.Warn("Backlog full, dropping oldest event", "component", "FailureService", "backlog", backlog.name,
+			"event_type", backlog.events[0].Type, "dropped_total", backlog.dropped)
+		backlog.pop()
+	}
+	backlog.events = append(backlog.events, event)
+}
+
+// next returns ch and the oldest waiting event for Run's select.
+// With nothing waiting the channel is nil, so the send case never fires.
+func (backlog *eventBacklog) next(ch chan<- models.Event) (chan<- models.Event, models.Event) {
+	if len(backlog.events) == 0 {
+		return nil, models.Event{}
+	}
+	return ch, backlog.events[0]
+}
+
+// pop removes the oldest waiting event.
+func (backlog *eventBacklog) pop() {
+	backlog.events[0] = models.Event{}
+	backlog.events = backlog.events[1:]
+}
@@ -0,0 +1,53 @@
+package monitorFailure
+
+import (
+	"testing"
+
+	"nms/pkg/models"
+)
+
+func TestEventBacklogKeepsOrderWhileChannelIsFull(t *testing.T) {
+	ch := make(chan models.Event, 1)
+	var backlog eventBacklog
+
+	for _, eventType := range []models.EventType{"a", "b", "c"} {
+		backlog.send(ch, models.Event{Type: eventType})
+	}
+	if len(backlog.events) != 2 {
+		t.Fatalf("backlog has %d events, want 2", len(backlog.events))
+	}
+
+	var got []models.EventType
+	for len(got) < 3 {
+		event := <-ch
+		got = append(got, event.Type)
+		if out, next := backlog.next(ch); out != nil {
+			out <- next
+			backlog.pop()
+		}
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("delivered %v, want [a b c]", got)
+	}
+	if out, _ := backlog.next(ch); out != nil {
+		t.Error("empty backlog returned a channel")
+	}
+}
+
+func TestEventBacklogDropsOldestOnOverflow(t *testing.T) {
+	ch := make(chan models.Event)
+	var backlog eventBacklog
+
+	backlog.send(ch, models.Event{Type: "oldest"})
+	for i := 1; i < maxBacklog; i++ {
+		backlog.send(ch, models.Event{Type: "queued"})
+	}
+	backlog.send(ch, models.Event{Type: "newest"})
+
+	if len(backlog.events) != maxBacklog || backlog.dropped != 1 {
+		t.Fatalf("backlog has %d events and dropped %d, want %d and 1", len(backlog.events), backlog.dropped, maxBacklog)
+	}
+	if backlog.events[0].Type != "queued" || backlog.events[maxBacklog-1].Type != "newest" {
+		t.Error("overflow should drop the oldest event")
+	}
+}
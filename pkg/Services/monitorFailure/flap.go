@@ -0,0 +1,145 @@
+package monitorFailure
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// flapState tracks the recent check results of a device for flap detection.
+type flapState struct {
+	checks   []bool // true = up, oldest first, at most flapWindow entries
+	down     bool   // Current availability state
+	downPoll bool   // Down because of a failed poll (a successful ping does not clear it)
+	score    float64
+	flapping bool
+	since    time.Time
+}
+
+// recordCheck folds a check outcome into the device's flap state and returns whether it is flapping.
+// Like AvailabilityService, a successful ping doesn't end a poll failure, so a device whose
+// ping works but poll fails every cycle reads as steadily down rather than flapping.
+func (failService *FailureService) recordCheck(deviceID int64, success bool, reason string, at time.Time) bool {
+	state, exists := failService.flaps[deviceID]
+	if !exists {
+		state = &flapState{}
+		failService.flaps[deviceID] = state
+	}
+
+	switch {
+	case !success:
+		state.down, state.downPoll = true, reason == "poll"
+	case reason == "poll" || !state.downPoll:
+		state.down, state.downPoll = false, false
+	}
+
+	state.checks = append(state.checks, !state.down)
+	if len(state.checks) > failService.flapWindow {
+		state.checks = state.checks[len(state.checks)-failService.flapWindow:]
+	}
+	state.score = flapScore(state.checks)
+
+	// Hysteresis: start above the high threshold, stop below the low one over a full window
+	// (a state restored at startup has no checks yet)
+	switch {
+	case !state.flapping && state.score >= failService.flapHigh:
+		state.flapping, state.since = true, at
+		slog.Warn("Device started flapping", "component", "FailureService", "device_id", deviceID, "score", state.score)
+		failService.publishFlap(deviceID, state)
+	case state.flapping && len(state.checks) == failService.flapWindow && state.score < failService.flapLow:
+		state.flapping, state.since = false, at
+		slog.Info("Device stopped flapping", "component", "FailureService", "device_id", deviceID, "score", state.score)
+		failService.publishFlap(deviceID, state)
+	}
+	return state.flapping
+}
+
+// flapScore computes the Nagios-style weighted percent state change over a series of checks.
+// Transitions are weighted linearly from 0.8 (oldest) to 1.2 (newest).
+func flapScore(checks []bool) float64 {
+	transitions := len(checks) - 1
+	if transitions < 1 {
+		return 0
+	}
+
+	var changed float64
+	for i := 1; i <= transitions; i++ {
+		if checks[i] == checks[i-1] {
+			continue
+		}
+		weight := 1.0
+		if transitions > 1 {
+			weight = 0.8 + 0.4*float64(i-1)/float64(transitions-1)
+		}
+		changed += weight
+	}
+	return changed / float64(transitions) * 100
+}
+
+// publishFlap sends a flap state change to EntityService without blocking.
+// Changes wait in order in the flap backlog while EntityService is busy.
+func (failService *FailureService) publishFlap(deviceID int64, state *flapState) {
+	event := models.Event{
+		Type: models.EventFlapUpdate,
+		Payload: &models.DeviceFlap{
+			DeviceID: deviceID,
+			Flapping: state.flapping,
+			Score:    state.score,
+			Since:    state.since,
+		},
+	}
+
+	failService.flapBacklog.send(failService.entityEvents, event)
+}
+
+// loadFlapping restores devices that were flapping when the previous run stopped.
+// They stay flapping until a full window of checks scores below the low threshold.
+func (failService *FailureService) loadFlapping() {
+	replyCh := make(chan models.Response, 1)
+	failService.entityReqChan <- models.Request{
+		Operation: models.OpGetFlapping,
+		ReplyCh:   replyCh,
+	}
+
+	resp := <-replyCh
+	flaps, ok := resp.Data.([]models.DeviceFlap)
+	if resp.Error != nil || !ok {
+		slog.Error("Failed to load flapping devices", "component", "FailureService", "error", resp.Error)
+		return
+	}
+	for _, flap := range flaps {
+		failService.flaps[flap.DeviceID] = &flapState{flapping: true, score: flap.Score, since: flap.Since}
+	}
+}
+
+// flapStatus returns a copy of a device's live flap state.
+func (failService *FailureService) flapStatus(deviceID int64) (*models.FlapStatus, error) {
+	state, exists := failService.flaps[deviceID]
+	if !exists {
+		return nil, fmt.Errorf("no checks recorded for device %d", deviceID)
+	}
+
+	checks := make([]string, len(state.checks))
+	for i, up := range state.checks {
+		checks[i] = models.AvailabilityDown
+		if up {
+			checks[i] = models.AvailabilityUp
+		}
+	}
+
+	status := &models.FlapStatus{
+		DeviceID:      deviceID,
+		Flapping:      state.flapping,
+		Score:         state.score,
+		RecentChecks:  checks,
+		WindowChecks:  failService.flapWindow,
+		HighThreshold: failService.flapHigh,
+		LowThreshold:  failService.flapLow,
+	}
+	if !state.since.IsZero() {
+		status.Since = state.since
+	}
+	return status, nil
+}
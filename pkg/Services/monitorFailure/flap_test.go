@@ -0,0 +1,33 @@
+package monitorFailure
+
+import (
+	"testing"
+	"time"
+
+	"nms/pkg/models"
+)
+
+func TestRestoredFlapStopsAfterFullStableWindow(t *testing.T) {
+	entityEvents := make(chan models.Event, 1)
+	failService := NewHealthMonitor(nil, nil, nil, nil, entityEvents, nil, 5, 3, 5, 50, 25)
+	failService.flaps[7] = &flapState{flapping: true, score: 60, since: time.Now().Add(-time.Hour)}
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		if !failService.recordCheck(7, true, "ping", now) {
+			t.Fatalf("restored device stopped flapping after %d checks", i+1)
+		}
+	}
+	if failService.recordCheck(7, true, "ping", now) {
+		t.Fatal("device still flapping after a full stable window")
+	}
+
+	select {
+	case event := <-entityEvents:
+		if flap := event.Payload.(*models.DeviceFlap); flap.DeviceID != 7 || flap.Flapping {
+			t.Errorf("flap update = %+v, want device 7 stopped", flap)
+		}
+	default:
+		t.Error("flap stop not published")
+	}
+}
@@ -5,107 +5,293 @@ import (
 	"log/slog"
 	"time"
 
+	"nms/pkg/Services/stream"
 	"nms/pkg/models"
 )
 
-// FailureRecord tracks failure state for a single device.
+// History limits per device and reason; policy windows are capped at 24h
+const (
+	historyRetention = 24 * time.Hour
+	historyMaxLen    = 256
+)
+
+// FailureRecord tracks recent failures of a single device per reason ("ping", "poll").
 type FailureRecord struct {
-	LastTime time.Time
-	Count    int
+	Failures map[string][]time.Time // Failure timestamps within the retention horizon
+	Streaks  map[string]int         // Failures since the last success
 }
 
-// FailureService tracks device failures and deactivates devices that exceed the threshold.
+// FailureService evaluates device failures against failure policies and applies the policy action
+// (deactivate, degrade or alert), unless the device is flapping (flap.go).
+// Every outcome is also forwarded to AvailabilityService for persisted history.
 // It is fully decoupled from other services - only communicates via channels.
 type FailureService struct {
-	failures      map[int64]FailureRecord
-	failureChan   <-chan models.Event   // Input: failure events (EventDeviceFailure)
-	entityReqChan chan<- models.Request // Output: deactivation requests to EntityService
+	failures         map[int64]*FailureRecord
+	blocked          map[int64]time.Time // Devices last seen behind an unreachable parent
+	degraded         map[int64]bool      // Devices this service degraded, restored on the next successful poll
+	policies         map[int64]cachedPolicy
+	flaps            map[int64]*flapState
+	failureChan      <-chan models.Event       // Input: failure and success events (EventDeviceFailure, EventDeviceSuccess)
+	requests         <-chan models.Request     // Input: dry-run requests from the API
+	entityReqChan    chan<- models.Request     // Output: policy lookups and status changes to EntityService
+	availabilityChan chan<- models.Event       // Output: outcomes to AvailabilityService
+	entityEvents     chan<- models.Event       // Output: flap state changes to EntityService
+	streamChan       chan<- models.StreamEvent // Output: failures to StreamService
+
+	// Outcomes and flap changes the consumers couldn't take yet, so a slow consumer never stalls this loop
+	availabilityBacklog eventBacklog
+	flapBacklog         eventBacklog
+
+	// Fallback for devices without an assigned policy (FAILURE_WINDOW_MIN / FAILURE_THRESHOLD)
+	defaultPolicy *models.FailurePolicy
 	window        time.Duration
-	threshold     int
+
+	// Flap detection
+	flapWindow int     // Number of recent checks the flap score covers
+	flapHigh   float64 // Score at which a device starts flapping
+	flapLow    float64 // Score below which a flapping device is stable again
 }
 
 // NewHealthMonitor creates a new FailureService instance.
 func NewHealthMonitor(
 	failureChan <-chan models.Event,
 	entityReqChan chan<- models.Request,
+	availabilityChan chan<- models.Event,
+	requests <-chan models.Request,
+	entityEvents chan<- models.Event,
+	streamChan chan<- models.StreamEvent,
 	windowMin int,
 	threshold int,
+	flapWindow int,
+	flapHigh, flapLow float64,
 ) *FailureService {
+	window := time.Duration(windowMin) * time.Minute
 	return &FailureService{
-		failures:      make(map[int64]FailureRecord),
-		failureChan:   failureChan,
-		entityReqChan: entityReqChan,
-		window:        time.Duration(windowMin) * time.Minute,
-		threshold:     threshold,
+		failures:            make(map[int64]*FailureRecord),
+		blocked:             make(map[int64]time.Time),
+		degraded:            make(map[int64]bool),
+		policies:            make(map[int64]cachedPolicy),
+		flaps:               make(map[int64]*flapState),
+		failureChan:         failureChan,
+		requests:            requests,
+		entityReqChan:       entityReqChan,
+		availabilityChan:    availabilityChan,
+		entityEvents:        entityEvents,
+		streamChan:          streamChan,
+		availabilityBacklog: eventBacklog{name: "availability"},
+		flapBacklog:         eventBacklog{name: "flap"},
+		defaultPolicy: &models.FailurePolicy{
+			Name:          "default",
+			Mode:          models.FailureModeSliding,
+			WindowSeconds: int(window / time.Second),
+			PingThreshold: threshold,
+			PollThreshold: threshold,
+			Action:        models.FailureActionDeactivate,
+		},
+		window:     window,
+		flapWindow: flapWindow,
+		flapHigh:   flapHigh,
+		flapLow:    flapLow,
 	}
 }
 
 // Run starts the health monitor's main loop.
 func (failService *FailureService) Run(ctx context.Context) {
-	slog.Info("Starting health monitor", "component", "FailureService", "window", failService.window.String(), "threshold", failService.threshold)
+	slog.Info("Starting health monitor", "component", "FailureService", "default_window", failService.window.String(), "default_threshold", failService.defaultPolicy.PingThreshold)
+
+	failService.loadDegraded()
+	failService.loadFlapping()
 
 	for {
+		availabilityOut, nextOutcome := failService.availabilityBacklog.next(failService.availabilityChan)
+		flapOut, nextFlap := failService.flapBacklog.next(failService.entityEvents)
+
 		select {
 		case <-ctx.Done():
 			slog.Info("Stopping health monitor", "component", "FailureService")
 			return
+		case availabilityOut <- nextOutcome:
+			failService.availabilityBacklog.pop()
+		case flapOut <- nextFlap:
+			failService.flapBacklog.pop()
 		case event := <-failService.failureChan:
-			if event.Type != models.EventDeviceFailure {
-				continue // Ignore non-failure events
+			if event.Type != models.EventDeviceFailure && event.Type != models.EventDeviceSuccess {
+				continue // Ignore unrelated events
 			}
-			if payload, ok := event.Payload.(*models.DeviceFailureEvent); ok {
+			failService.availabilityBacklog.send(failService.availabilityChan, event)
+			switch payload := event.Payload.(type) {
+			case *models.DeviceFailureEvent:
 				failService.handleFailure(payload)
+			case *models.DeviceSuccessEvent:
+				failService.handleSuccess(payload)
 			}
+		case req := <-failService.requests:
+			failService.handleRequest(req)
 		}
 	}
 }
 
-// handleFailure processes a failure event and updates the failure count.
+// handleFailure records a failure and applies the device's policy action when it triggers.
 func (failService *FailureService) handleFailure(event *models.DeviceFailureEvent) {
-	record := failService.failures[event.DeviceID]
+	stream.Publish(failService.streamChan, models.StreamEvent{
+		Type:      models.StreamFailure,
+		DeviceID:  event.DeviceID,
+		Timestamp: event.Timestamp,
+		Data:      &models.StreamFailureInfo{Reason: event.Reason, BlockedBy: event.BlockedBy},
+	})
 
-	if event.Timestamp.Sub(record.LastTime) < failService.window {
-		// Within window: increment count
-		record.Count++
-		slog.Debug("Failure count increased",
-			"component", "FailureService",
-			"device_id", event.DeviceID,
-			"reason", event.Reason,
-			"count", record.Count,
-			"threshold", failService.threshold,
-		)
+	if failService.attributedToDependency(event) {
+		return
+	}
+
+	record := failService.record(event.DeviceID)
+	failures := append(record.Failures[event.Reason], event.Timestamp)
+	record.Failures[event.Reason] = trimHistory(failures, event.Timestamp)
+	record.Streaks[event.Reason]++
+	flapping := failService.recordCheck(event.DeviceID, false, event.Reason, event.Timestamp)
+
+	policy := failService.resolvePolicy(event.DeviceID)
+	eval := evaluate(policy, record, event.Timestamp)
+	eval.DeviceID = event.DeviceID
 
-		if record.Count >= failService.threshold {
-			slog.Warn("Device exceeded failure threshold, deactivating",
+	slog.Debug("Failure recorded",
+		"component", "FailureService",
+		"device_id", event.DeviceID,
+		"reason", event.Reason,
+		"policy", policy.Name,
+		"ping_failures", eval.PingFailures,
+		"poll_failures", eval.PollFailures,
+	)
+
+	if eval.WouldTrigger && flapping {
+		// Acting on a bouncing device only produces noise; wait until it stabilises
+		slog.Info("Failure policy suppressed, device is flapping", "component", "FailureService",
+			"device_id", event.DeviceID, "policy", policy.Name, "reason", eval.TriggerReason)
+		delete(failService.failures, event.DeviceID)
+		return
+	}
+	if eval.WouldTrigger {
+		failService.apply(eval)
+		delete(failService.failures, event.DeviceID) // Start counting afresh after acting
+	}
+}
+
+// handleSuccess resets failure streaks and restores degraded devices.
+// A successful ping resets only the ping streak; a successful poll proves the device is fully up.
+func (failService *FailureService) handleSuccess(event *models.DeviceSuccessEvent) {
+	failService.recordCheck(event.DeviceID, true, event.Reason, event.Timestamp)
+
+	if record, exists := failService.failures[event.DeviceID]; exists {
+		record.Streaks["ping"] = 0
+		if event.Reason == "poll" {
+			record.Streaks["poll"] = 0
+		}
+	}
+
+	if event.Reason != "poll" {
+		return
+	}
+	delete(failService.blocked, event.DeviceID)
+
+	if failService.degraded[event.DeviceID] {
+		delete(failService.degraded, event.DeviceID)
+		failService.requestStatus(models.OpActivateDevice, event.DeviceID, "restored after successful poll")
+	}
+}
+
+// attributedToDependency reports whether a failure belongs to an unreachable parent rather than the device.
+// Dependency failures clear the device's count; ping/poll failures within a window of the last one
+// (e.g. polls already in flight when the parent went down) are ignored as well.
+func (failService *FailureService) attributedToDependency(event *models.DeviceFailureEvent) bool {
+	if event.Reason == "dependency" {
+		if _, wasBlocked := failService.blocked[event.DeviceID]; !wasBlocked {
+			slog.Info("Failures attributed to unreachable parent",
 				"component", "FailureService",
 				"device_id", event.DeviceID,
-				"count", record.Count,
+				"blocked_by", event.BlockedBy,
 			)
-			failService.deactivateDevice(event.DeviceID)
-			delete(failService.failures, event.DeviceID) // Clean up after deactivation
-			return
 		}
-	} else {
-		// Outside window: reset count to 1
-		record.Count = 1
-		slog.Debug("Failure window reset",
+		failService.blocked[event.DeviceID] = event.Timestamp
+		delete(failService.failures, event.DeviceID)
+		return true
+	}
+
+	blockedAt, exists := failService.blocked[event.DeviceID]
+	if !exists {
+		return false
+	}
+	if event.Timestamp.Sub(blockedAt) < failService.window {
+		slog.Debug("Ignoring failure of device behind unreachable parent",
 			"component", "FailureService",
 			"device_id", event.DeviceID,
 			"reason", event.Reason,
 		)
+		return true
+	}
+	delete(failService.blocked, event.DeviceID)
+	return false
+}
+
+// apply carries out the action of a triggered policy.
+func (failService *FailureService) apply(eval *models.PolicyEvaluation) {
+	slog.Warn("Failure policy triggered",
+		"component", "FailureService",
+		"device_id", eval.DeviceID,
+		"policy", eval.Policy.Name,
+		"action", eval.Action,
+		"reason", eval.TriggerReason,
+	)
+
+	switch eval.Action {
+	case models.FailureActionDeactivate:
+		delete(failService.degraded, eval.DeviceID)
+		failService.requestStatus(models.OpDeactivateDevice, eval.DeviceID, eval.TriggerReason)
+	case models.FailureActionDegrade:
+		if failService.degraded[eval.DeviceID] {
+			return
+		}
+		failService.degraded[eval.DeviceID] = true
+		failService.requestStatus(models.OpDegradeDevice, eval.DeviceID, eval.TriggerReason)
+	case models.FailureActionAlert:
+		// Alert only: the device keeps its status, EntityService notifies with its details
+		failService.requestStatus(models.OpAlertDevice, eval.DeviceID, eval.TriggerReason)
+	}
+}
+
+// record returns the failure record of a device, creating it on first use.
+func (failService *FailureService) record(deviceID int64) *FailureRecord {
+	record, exists := failService.failures[deviceID]
+	if !exists {
+		record = &FailureRecord{
+			Failures: make(map[string][]time.Time),
+			Streaks:  make(map[string]int),
+		}
+		failService.failures[deviceID] = record
 	}
+	return record
+}
 
-	record.LastTime = event.Timestamp
-	failService.failures[event.DeviceID] = record
+// trimHistory drops failures older than the retention horizon and caps the list length.
+func trimHistory(failures []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-historyRetention)
+	start := 0
+	for start < len(failures) && failures[start].Before(cutoff) {
+		start++
+	}
+	if len(failures)-start > historyMaxLen {
+		start = len(failures) - historyMaxLen
+	}
+	return failures[start:]
 }
 
-// deactivateDevice sends a deactivation request to EntityService.
-func (failService *FailureService) deactivateDevice(deviceID int64) {
+// requestStatus sends a policy action (status change or alert) to EntityService.
+func (failService *FailureService) requestStatus(operation string, deviceID int64, reason string) {
 	replyCh := make(chan models.Response, 1)
 	failService.entityReqChan <- models.Request{
-		Operation:  models.OpDeactivateDevice,
+		Operation:  operation,
 		EntityType: "Device",
 		ID:         deviceID,
+		Payload:    reason,
 		ReplyCh:    replyCh,
 	}
 
@@ -113,16 +299,37 @@ func (failService *FailureService) deactivateDevice(deviceID int64) {
 	go func() {
 		resp := <-replyCh
 		if resp.Error != nil {
-			slog.Error("Failed to deactivate device",
+			slog.Error("Failed to apply failure policy action",
 				"component", "FailureService",
 				"device_id", deviceID,
+				"operation", operation,
 				"error", resp.Error,
 			)
 		} else {
-			slog.Info("Device deactivated successfully",
+			slog.Info("Failure policy action applied",
 				"component", "FailureService",
 				"device_id", deviceID,
+				"operation", operation,
 			)
 		}
 	}()
 }
+
+// loadDegraded restores the set of degraded devices so they can still be restored after a restart.
+func (failService *FailureService) loadDegraded() {
+	replyCh := make(chan models.Response, 1)
+	failService.entityReqChan <- models.Request{
+		Operation: models.OpGetDegradedDevices,
+		ReplyCh:   replyCh,
+	}
+
+	resp := <-replyCh
+	devices, ok := resp.Data.([]*models.Device)
+	if resp.Error != nil || !ok {
+		slog.Error("Failed to load degraded devices", "component", "FailureService", "error", resp.Error)
+		return
+	}
+	for _, dev := range devices {
+		failService.degraded[dev.ID] = true
+	}
+}
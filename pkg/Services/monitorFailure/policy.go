@@ -0,0 +1,163 @@
+package monitorFailure
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// policyCacheTTL bounds how long a resolved policy is reused before asking EntityService again,
+// so policy edits and assignments take effect without restart.
+const policyCacheTTL = time.Minute
+
+// cachedPolicy is a device's resolved failure policy.
+type cachedPolicy struct {
+	policy    *models.FailurePolicy
+	fetchedAt time.Time
+}
+
+// resolvePolicy returns the policy for a device (device, then discovery profile, then the default).
+func (failService *FailureService) resolvePolicy(deviceID int64) *models.FailurePolicy {
+	if cached, exists := failService.policies[deviceID]; exists && time.Since(cached.fetchedAt) < policyCacheTTL {
+		return cached.policy
+	}
+
+	replyCh := make(chan models.Response, 1)
+	failService.entityReqChan <- models.Request{
+		Operation:  models.OpResolveFailurePolicy,
+		EntityType: "Device",
+		ID:         deviceID,
+		ReplyCh:    replyCh,
+	}
+
+	policy := failService.defaultPolicy
+	resp := <-replyCh
+	if resp.Error != nil {
+		slog.Warn("Failed to resolve failure policy, using default", "component", "FailureService", "device_id", deviceID, "error", resp.Error)
+	} else if assigned, ok := resp.Data.(*models.FailurePolicy); ok && assigned != nil {
+		policy = assigned
+	}
+
+	failService.policies[deviceID] = cachedPolicy{policy: policy, fetchedAt: time.Now()}
+	return policy
+}
+
+// evaluate counts a device's failures per reason under a policy and decides whether it triggers.
+func evaluate(policy *models.FailurePolicy, record *FailureRecord, now time.Time) *models.PolicyEvaluation {
+	eval := &models.PolicyEvaluation{Policy: policy}
+	if record == nil {
+		return eval
+	}
+
+	window := time.Duration(policy.WindowSeconds) * time.Second
+	for _, reason := range []string{"ping", "poll"} {
+		var count int
+		if policy.Mode == models.FailureModeConsecutive {
+			count = record.Streaks[reason]
+		} else {
+			for _, at := range record.Failures[reason] {
+				if now.Sub(at) < window {
+					count++
+				}
+			}
+		}
+
+		if reason == "ping" {
+			eval.PingFailures = count
+		} else {
+			eval.PollFailures = count
+		}
+
+		threshold := policy.Threshold(reason)
+		if threshold == 0 || count < threshold || eval.WouldTrigger {
+			continue
+		}
+		eval.WouldTrigger = true
+		eval.Action = policy.Action
+		if policy.Mode == models.FailureModeConsecutive {
+			eval.TriggerReason = fmt.Sprintf("%d consecutive %s failures (policy %s)", count, reason, policy.Name)
+		} else {
+			eval.TriggerReason = fmt.Sprintf("%d %s failures within %s (policy %s)", count, reason, window, policy.Name)
+		}
+	}
+	return eval
+}
+
+// handleRequest answers dry-run and flap status requests from inside the Run goroutine.
+func (failService *FailureService) handleRequest(req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpDryRunPolicy:
+		dryRun, ok := req.Payload.(*models.PolicyDryRunRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for policy dry run")
+			break
+		}
+		resp.Data, resp.Error = failService.dryRun(dryRun)
+	case models.OpGetFlapStatus:
+		resp.Data, resp.Error = failService.flapStatus(req.ID)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// dryRun evaluates a policy against current failure history without acting on the result.
+// Without a policy each device is evaluated under its own resolved policy.
+func (failService *FailureService) dryRun(req *models.PolicyDryRunRequest) ([]*models.PolicyEvaluation, error) {
+	policy := req.Policy
+	if policy != nil && policy.Mode == models.FailureModeSliding && policy.WindowSeconds == 0 {
+		return nil, fmt.Errorf("window_seconds is required for sliding mode")
+	}
+	if policy == nil && req.PolicyID != 0 {
+		fetched, err := failService.fetchPolicy(req.PolicyID)
+		if err != nil {
+			return nil, err
+		}
+		policy = fetched
+	}
+
+	deviceIDs := req.DeviceIDs
+	if len(deviceIDs) == 0 {
+		for id := range failService.failures {
+			deviceIDs = append(deviceIDs, id)
+		}
+		sort.Slice(deviceIDs, func(i, j int) bool { return deviceIDs[i] < deviceIDs[j] })
+	}
+
+	now := time.Now()
+	evals := make([]*models.PolicyEvaluation, 0, len(deviceIDs))
+	for _, id := range deviceIDs {
+		devicePolicy := policy
+		if devicePolicy == nil {
+			devicePolicy = failService.resolvePolicy(id)
+		}
+		eval := evaluate(devicePolicy, failService.failures[id], now)
+		eval.DeviceID = id
+		evals = append(evals, eval)
+	}
+	return evals, nil
+}
+
+// fetchPolicy loads a stored policy from EntityService.
+func (failService *FailureService) fetchPolicy(policyID int64) (*models.FailurePolicy, error) {
+	replyCh := make(chan models.Response, 1)
+	failService.entityReqChan <- models.Request{
+		Operation:  models.OpGet,
+		EntityType: "FailurePolicy",
+		ID:         policyID,
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	policy, ok := resp.Data.(*models.FailurePolicy)
+	if resp.Error != nil || !ok {
+		return nil, fmt.Errorf("failure policy %d not found", policyID)
+	}
+	return policy, nil
+}
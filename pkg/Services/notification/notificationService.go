@@ -0,0 +1,419 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"sync"
+	"time"
+
+	"nms/pkg/Services/persistence"
+	"nms/pkg/api"
+	"nms/pkg/database"
+	"nms/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Retry sweep cadence and the longest delay between attempts
+const (
+	retryCheckInterval = 15 * time.Second
+	maxRetryDelay      = time.Hour
+)
+
+// SMTPConfig holds the server used by email channels.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// NotificationService routes notifications to channels, renders them with the channel templates
+// and delivers them with retries. Every delivery is logged in notification_deliveries, so retry
+// state survives a restart.
+type NotificationService struct {
+	notifications <-chan models.Event   // Input: notifications from EntityService and AlertService, flap changes from EntityService
+	requests      <-chan models.Request // Input: test sends and delivery log requests from the API
+
+	db           *sqlx.DB
+	channelRepo  database.Repository[models.NotificationChannel]
+	routeRepo    database.Repository[models.NotificationRoute]
+	deliveryRepo database.Repository[models.NotificationDelivery]
+
+	// Delivery worker pool
+	workerCount int
+	jobs        chan int64 // Delivery IDs
+	inFlight    sync.Map   // Delivery IDs queued or being sent
+
+	// Devices whose routed notifications are suppressed, only touched from the Run goroutine
+	flapping map[int64]bool
+
+	httpClient    *http.Client
+	smtp          SMTPConfig
+	encryptionKey string
+	maxAttempts   int
+	retryBase     time.Duration
+}
+
+// NewNotificationService creates a new NotificationService instance.
+func NewNotificationService(
+	notifications <-chan models.Event,
+	requests <-chan models.Request,
+	db *sqlx.DB,
+	encryptionKey string,
+	smtp SMTPConfig,
+	workerCount int,
+	maxAttempts int,
+	retryBaseSec int,
+	httpTimeoutSec int,
+) *NotificationService {
+	return &NotificationService{
+		notifications: notifications,
+		requests:      requests,
+		db:            db,
+		channelRepo:   database.NewSqlxRepository[models.NotificationChannel](db),
+		routeRepo:     database.NewSqlxRepository[models.NotificationRoute](db),
+		deliveryRepo:  database.NewSqlxRepository[models.NotificationDelivery](db),
+		workerCount:   workerCount,
+		jobs:          make(chan int64, workerCount*50),
+		httpClient:    &http.Client{Timeout: time.Duration(httpTimeoutSec) * time.Second},
+		smtp:          smtp,
+		encryptionKey: encryptionKey,
+		maxAttempts:   maxAttempts,
+		retryBase:     time.Duration(retryBaseSec) * time.Second,
+		flapping:      make(map[int64]bool),
+	}
+}
+
+// Run starts the notification service's main loop.
+func (svc *NotificationService) Run(ctx context.Context) {
+	slog.Info("Starting notification service", "component", "NotificationService", "workers", svc.workerCount)
+
+	var wg sync.WaitGroup
+	for i := 0; i < svc.workerCount; i++ {
+		wg.Add(1)
+		go svc.worker(ctx, &wg)
+	}
+
+	ticker := time.NewTicker(retryCheckInterval)
+	defer ticker.Stop()
+
+	// Pick up deliveries left pending or retrying by the previous run
+	svc.enqueueDue(ctx)
+	svc.loadFlapping(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(svc.jobs)
+			wg.Wait()
+			slog.Info("Stopping notification service", "component", "NotificationService")
+			return
+		case event := <-svc.notifications:
+			notification, ok := event.Payload.(*models.Notification)
+			if !ok {
+				slog.Error("Invalid payload type in notification event", "component", "NotificationService", "type", event.Type)
+				continue
+			}
+			if event.Type == models.EventFlapUpdate {
+				svc.updateFlapping(notification)
+			}
+			svc.dispatch(ctx, notification)
+		case req := <-svc.requests:
+			go svc.handleRequest(ctx, req)
+		case <-ticker.C:
+			svc.enqueueDue(ctx)
+		}
+	}
+}
+
+// loadFlapping restores suppression for devices that were flapping when the previous run stopped.
+func (svc *NotificationService) loadFlapping(ctx context.Context) {
+	rows, err := persistence.LatestFlapping(ctx, svc.db)
+	if err != nil {
+		slog.Error("Failed to load flapping devices", "component", "NotificationService", "error", err)
+		return
+	}
+	for _, row := range rows {
+		svc.flapping[row.DeviceID] = true
+	}
+}
+
+// updateFlapping starts or ends suppression for the device of a flap start/stop notification.
+func (svc *NotificationService) updateFlapping(notification *models.Notification) {
+	if notification.State == models.FlapStarted {
+		svc.flapping[notification.DeviceID] = true
+	} else {
+		delete(svc.flapping, notification.DeviceID)
+	}
+}
+
+// dispatch creates one delivery per matching route and queues it.
+// Notifications addressed to a channel (escalations) skip routing. Routed notifications of a
+// flapping device are dropped, except the flap start/stop notifications themselves.
+func (svc *NotificationService) dispatch(ctx context.Context, notification *models.Notification) {
+	if notification.ChannelID == 0 && svc.flapping[notification.DeviceID] && notification.State != models.FlapStarted {
+		slog.Debug("Suppressing notification of flapping device", "component", "NotificationService",
+			"device_id", notification.DeviceID, "kind", notification.Kind, "state", notification.State)
+		return
+	}
+
+	if notification.ChannelID != 0 {
+		channel, err := svc.channelRepo.Get(ctx, notification.ChannelID)
+		if err != nil {
+			slog.Error("Notification addressed to a missing channel", "component", "NotificationService", "channel_id", notification.ChannelID)
+			return
+		}
+		if !channel.Disabled {
+			svc.queueDelivery(ctx, channel, 0, notification)
+		}
+		return
+	}
+
+	routes, err := svc.routeRepo.List(ctx)
+	if err != nil {
+		slog.Error("Failed to load notification routes", "component", "NotificationService", "error", err)
+		return
+	}
+
+	channels := make(map[int64]*models.NotificationChannel)
+	for _, route := range routes {
+		if !route.Matches(notification) {
+			continue
+		}
+		if _, seen := channels[route.ChannelID]; seen {
+			continue // Several routes to one channel still send one message
+		}
+
+		channel, err := svc.channelRepo.Get(ctx, route.ChannelID)
+		if err != nil {
+			slog.Error("Notification route points to a missing channel", "component", "NotificationService", "route_id", route.ID, "channel_id", route.ChannelID)
+			continue
+		}
+		channels[route.ChannelID] = channel
+		if !channel.Disabled {
+			svc.queueDelivery(ctx, channel, route.ID, notification)
+		}
+	}
+}
+
+// queueDelivery renders a notification for a channel, logs the delivery and queues it.
+func (svc *NotificationService) queueDelivery(ctx context.Context, channel *models.NotificationChannel, routeID int64, notification *models.Notification) {
+	subject, body, err := render(channel, notification)
+	if err != nil {
+		slog.Error("Failed to render notification", "component", "NotificationService", "channel", channel.Name, "error", err)
+		return
+	}
+
+	now := time.Now()
+	delivery, err := svc.deliveryRepo.Create(ctx, &models.NotificationDelivery{
+		ChannelID:     channel.ID,
+		RouteID:       routeID,
+		Kind:          notification.Kind,
+		Severity:      notification.Severity,
+		DeviceID:      notification.DeviceID,
+		Recipient:     notification.Recipient,
+		Subject:       subject,
+		Body:          body,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	})
+	if err != nil {
+		slog.Error("Failed to log notification delivery", "component", "NotificationService", "channel", channel.Name, "error", err)
+		return
+	}
+	svc.enqueue(delivery.ID)
+}
+
+// enqueueDue queues pending and retrying deliveries whose next attempt is due.
+func (svc *NotificationService) enqueueDue(ctx context.Context) {
+	var ids []int64
+	err := svc.db.SelectContext(ctx, &ids,
+		"SELECT id FROM notification_deliveries WHERE status IN ($1, $2) AND next_attempt_at <= NOW() ORDER BY next_attempt_at",
+		models.DeliveryPending, models.DeliveryRetrying)
+	if err != nil {
+		slog.Error("Failed to load due deliveries", "component", "NotificationService", "error", err)
+		return
+	}
+	for _, id := range ids {
+		svc.enqueue(id)
+	}
+}
+
+// enqueue hands a delivery to the workers unless it is already queued.
+// A full queue leaves the delivery for the next sweep.
+func (svc *NotificationService) enqueue(id int64) {
+	if _, queued := svc.inFlight.LoadOrStore(id, true); queued {
+		return
+	}
+	select {
+	case svc.jobs <- id:
+	default:
+		svc.inFlight.Delete(id)
+		slog.Warn("Delivery queue full, deferring", "component", "NotificationService", "delivery_id", id)
+	}
+}
+
+// worker sends queued deliveries.
+func (svc *NotificationService) worker(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for id := range svc.jobs {
+		svc.attempt(ctx, id)
+		svc.inFlight.Delete(id)
+	}
+}
+
+// attempt sends one delivery and records the outcome and retry state.
+func (svc *NotificationService) attempt(ctx context.Context, id int64) {
+	delivery, err := svc.deliveryRepo.Get(ctx, id)
+	if err != nil {
+		slog.Error("Delivery not found", "component", "NotificationService", "delivery_id", id, "error", err)
+		return
+	}
+	channel, err := svc.channelRepo.Get(ctx, delivery.ChannelID)
+	if err != nil {
+		slog.Error("Delivery channel not found", "component", "NotificationService", "delivery_id", id, "error", err)
+		return
+	}
+
+	sendErr := svc.send(ctx, channel, delivery.Recipient, delivery.Subject, delivery.Body)
+
+	now := time.Now()
+	delivery.Attempts++
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySent
+		delivery.SentAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+	case delivery.Attempts >= svc.maxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = sendErr.Error()
+	default:
+		next := now.Add(svc.retryDelay(delivery.Attempts))
+		delivery.Status = models.DeliveryRetrying
+		delivery.NextAttemptAt = &next
+		delivery.LastError = sendErr.Error()
+	}
+
+	if _, err := svc.deliveryRepo.Update(ctx, id, delivery); err != nil {
+		slog.Error("Failed to update delivery", "component", "NotificationService", "delivery_id", id, "error", err)
+	}
+
+	if sendErr != nil {
+		slog.Warn("Notification delivery failed", "component", "NotificationService",
+			"delivery_id", id, "channel", channel.Name, "attempt", delivery.Attempts, "status", delivery.Status, "error", sendErr)
+		return
+	}
+	slog.Info("Notification delivered", "component", "NotificationService", "delivery_id", id, "channel", channel.Name)
+}
+
+// retryDelay doubles the base delay per failed attempt, capped at maxRetryDelay.
+func (svc *NotificationService) retryDelay(attempts int) time.Duration {
+	delay := svc.retryBase
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// send delivers a rendered message through a channel.
+// A recipient (the on-call person of an escalation) replaces the recipients of email channels.
+func (svc *NotificationService) send(ctx context.Context, channel *models.NotificationChannel, recipient, subject, body string) error {
+	switch channel.Type {
+	case models.ChannelWebhook:
+		decrypted, err := api.DecryptStruct(*channel, svc.encryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt channel secret: %w", err)
+		}
+		return svc.sendWebhook(ctx, channel.URL, decrypted.Secret, []byte(body))
+	case models.ChannelSlack, models.ChannelTeams:
+		return svc.sendChat(ctx, channel.URL, subject, body)
+	case models.ChannelEmail:
+		recipients := []string(channel.Recipients)
+		if _, err := mail.ParseAddress(recipient); err == nil {
+			recipients = []string{recipient}
+		}
+		return svc.sendEmail(recipients, subject, body)
+	}
+	return fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+// handleRequest answers test sends and delivery log requests.
+func (svc *NotificationService) handleRequest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpTestChannel:
+		resp.Error = svc.testChannel(ctx, req.ID)
+	case models.OpListDeliveries:
+		filter, ok := req.Payload.(*models.DeliveryListRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for delivery list")
+			break
+		}
+		resp.Data, resp.Error = svc.listDeliveries(ctx, filter)
+	case models.OpRetryDelivery:
+		resp.Data, resp.Error = svc.retryDelivery(ctx, req.ID)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// testChannel renders and sends a sample notification right away, without logging a delivery.
+func (svc *NotificationService) testChannel(ctx context.Context, channelID int64) error {
+	channel, err := svc.channelRepo.Get(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("notification channel %d not found", channelID)
+	}
+
+	subject, body, err := render(channel, &models.Notification{
+		Kind:      models.NotificationDeviceStatus,
+		Severity:  "info",
+		Title:     "Test notification from NMS",
+		Message:   fmt.Sprintf("Channel %q is configured correctly.", channel.Name),
+		State:     "test",
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return svc.send(ctx, channel, "", subject, body)
+}
+
+// listDeliveries returns the newest deliveries matching the filter.
+func (svc *NotificationService) listDeliveries(ctx context.Context, filter *models.DeliveryListRequest) ([]*models.NotificationDelivery, error) {
+	query := "SELECT * FROM notification_deliveries WHERE ($1 = '' OR status = $1) AND ($2 = 0 OR channel_id = $2) ORDER BY created_at DESC, id DESC LIMIT $3"
+	deliveries := make([]*models.NotificationDelivery, 0)
+	if err := svc.db.SelectContext(ctx, &deliveries, query, filter.Status, filter.ChannelID, filter.Limit); err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// retryDelivery makes a failed or retrying delivery due now.
+func (svc *NotificationService) retryDelivery(ctx context.Context, id int64) (*models.NotificationDelivery, error) {
+	delivery, err := svc.deliveryRepo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("delivery %d not found", id)
+	}
+	if delivery.Status == models.DeliverySent {
+		return nil, fmt.Errorf("delivery %d was already sent", id)
+	}
+
+	now := time.Now()
+	delivery.Status = models.DeliveryRetrying
+	delivery.NextAttemptAt = &now
+	if _, err := svc.deliveryRepo.Update(ctx, id, delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue delivery %d: %w", id, err)
+	}
+	svc.enqueue(id)
+	return delivery, nil
+}
@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nms/pkg/api"
+	"nms/pkg/models"
+)
+
+const testEncryptionKey = "1234567890123456789012345678901212345678901234567890123456789012"
+
+// memRepo is an in-memory database.Repository holding copies of the stored entities.
+type memRepo[T models.TableNamer] struct {
+	mu   sync.Mutex
+	rows map[int64]T
+}
+
+func newMemRepo[T models.TableNamer](rows map[int64]T) *memRepo[T] {
+	return &memRepo[T]{rows: rows}
+}
+
+func (r *memRepo[T]) Get(ctx context.Context, id int64) (*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	row, ok := r.rows[id]
+	if !ok {
+		return nil, fmt.Errorf("%d not found", id)
+	}
+	return &row, nil
+}
+
+func (r *memRepo[T]) Update(ctx context.Context, id int64, entity *T) (*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows[id] = *entity
+	return entity, nil
+}
+
+func (r *memRepo[T]) List(ctx context.Context) ([]*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rows := make([]*T, 0, len(r.rows))
+	for _, row := range r.rows {
+		rows = append(rows, &row)
+	}
+	return rows, nil
+}
+
+func (r *memRepo[T]) GetByFields(ctx context.Context, filters map[string]any) (*T, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (r *memRepo[T]) ListByFields(ctx context.Context, filters map[string]any) ([]*T, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+// Create stores the entity under the next free key; the entity's own ID field is left as is.
+func (r *memRepo[T]) Create(ctx context.Context, entity *T) (*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows[int64(len(r.rows)+1)] = *entity
+	return entity, nil
+}
+
+func (r *memRepo[T]) Delete(ctx context.Context, id int64) error { return fmt.Errorf("not supported") }
+
+// newDeliveryFixture returns a service whose webhook channel 1 posts to a server failing the
+// first `failures` requests, with delivery 10 pending on it.
+func newDeliveryFixture(t *testing.T, failures int32, maxAttempts int) (*NotificationService, *memRepo[models.NotificationDelivery], *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	channel, err := api.EncryptStruct(models.NotificationChannel{ID: 1, Name: "ops", Type: models.ChannelWebhook, URL: server.URL, Secret: "s3cret"}, testEncryptionKey)
+	if err != nil {
+		t.Fatalf("encrypt channel: %v", err)
+	}
+	now := time.Now()
+	deliveries := newMemRepo(map[int64]models.NotificationDelivery{
+		10: {ID: 10, ChannelID: 1, Body: `{"title":"CPU high"}`, Status: models.DeliveryPending, NextAttemptAt: &now},
+	})
+
+	svc := NewNotificationService(nil, nil, nil, testEncryptionKey, SMTPConfig{}, 1, maxAttempts, 60, 5)
+	svc.channelRepo = newMemRepo(map[int64]models.NotificationChannel{1: channel})
+	svc.deliveryRepo = deliveries
+	return svc, deliveries, &calls
+}
+
+func TestAttemptRecordsRetryStateUntilSent(t *testing.T) {
+	svc, deliveries, calls := newDeliveryFixture(t, 2, 5)
+	ctx := context.Background()
+
+	// Each failure doubles the delay from the 60s base
+	for i, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		svc.attempt(ctx, 10)
+
+		delivery, _ := deliveries.Get(ctx, 10)
+		if delivery.Status != models.DeliveryRetrying || delivery.Attempts != i+1 {
+			t.Fatalf("after attempt %d: status=%s attempts=%d, want retrying/%d", i+1, delivery.Status, delivery.Attempts, i+1)
+		}
+		if delivery.LastError == "" || delivery.SentAt != nil {
+			t.Errorf("after attempt %d: last_error=%q sent_at=%v", i+1, delivery.LastError, delivery.SentAt)
+		}
+		if delivery.NextAttemptAt == nil {
+			t.Fatalf("after attempt %d: next_attempt_at not set", i+1)
+		}
+		if delay := delivery.NextAttemptAt.Sub(before); delay < wantDelay || delay > wantDelay+5*time.Second {
+			t.Errorf("after attempt %d: next attempt in %s, want %s", i+1, delay, wantDelay)
+		}
+	}
+
+	svc.attempt(ctx, 10)
+	delivery, _ := deliveries.Get(ctx, 10)
+	if delivery.Status != models.DeliverySent || delivery.Attempts != 3 {
+		t.Fatalf("status=%s attempts=%d, want sent/3", delivery.Status, delivery.Attempts)
+	}
+	if delivery.SentAt == nil || delivery.NextAttemptAt != nil || delivery.LastError != "" {
+		t.Errorf("sent delivery: sent_at=%v next_attempt_at=%v last_error=%q", delivery.SentAt, delivery.NextAttemptAt, delivery.LastError)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("webhook called %d times, want 3", calls.Load())
+	}
+}
+
+func TestAttemptFailsAfterMaxAttempts(t *testing.T) {
+	svc, deliveries, _ := newDeliveryFixture(t, 100, 2)
+	ctx := context.Background()
+
+	svc.attempt(ctx, 10)
+	svc.attempt(ctx, 10)
+
+	delivery, _ := deliveries.Get(ctx, 10)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 {
+		t.Fatalf("status=%s attempts=%d, want failed/2", delivery.Status, delivery.Attempts)
+	}
+	if delivery.NextAttemptAt != nil || delivery.LastError == "" {
+		t.Errorf("failed delivery: next_attempt_at=%v last_error=%q", delivery.NextAttemptAt, delivery.LastError)
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	svc := newTestService()
+	svc.retryBase = 30 * time.Second
+
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		8:  maxRetryDelay,
+		50: maxRetryDelay,
+	} {
+		if got := svc.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatchSuppressesFlappingDevice(t *testing.T) {
+	svc := NewNotificationService(nil, nil, nil, testEncryptionKey, SMTPConfig{}, 1, 3, 60, 5)
+	deliveries := newMemRepo(map[int64]models.NotificationDelivery{})
+	svc.deliveryRepo = deliveries
+	svc.channelRepo = newMemRepo(map[int64]models.NotificationChannel{1: {ID: 1, Name: "ops", Type: models.ChannelSlack, URL: "http://127.0.0.1:0"}})
+	svc.routeRepo = newMemRepo(map[int64]models.NotificationRoute{1: {ID: 1, ChannelID: 1}})
+	ctx := context.Background()
+
+	sent := func() int {
+		rows, _ := deliveries.List(ctx)
+		return len(rows)
+	}
+	alert := &models.Notification{Kind: models.NotificationAlertFiring, Severity: "critical", DeviceID: 7, State: "firing"}
+	flap := func(state string) {
+		notification := &models.Notification{Kind: models.NotificationDeviceStatus, Severity: "warning", DeviceID: 7, State: state}
+		svc.updateFlapping(notification)
+		svc.dispatch(ctx, notification)
+	}
+
+	flap(models.FlapStarted)
+	if sent() != 1 {
+		t.Fatalf("flap start notification not delivered")
+	}
+
+	svc.dispatch(ctx, alert)
+	svc.dispatch(ctx, &models.Notification{Kind: models.NotificationDeviceStatus, Severity: "critical", DeviceID: 7, State: "inactive"})
+	if sent() != 1 {
+		t.Errorf("notifications of a flapping device were delivered")
+	}
+
+	svc.dispatch(ctx, &models.Notification{Kind: models.NotificationAlertFiring, Severity: "critical", DeviceID: 8, State: "firing"})
+	svc.dispatch(ctx, &models.Notification{Kind: models.NotificationAlertEscalation, DeviceID: 7, ChannelID: 1})
+	if sent() != 3 {
+		t.Errorf("other devices and escalations must not be suppressed, got %d deliveries", sent())
+	}
+
+	flap(models.FlapStopped)
+	svc.dispatch(ctx, alert)
+	if sent() != 5 {
+		t.Errorf("notifications not resumed after the device stabilised, got %d deliveries", sent())
+	}
+}
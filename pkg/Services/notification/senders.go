@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on signed webhook requests. The signature covers "<timestamp>.<body>".
+const (
+	signatureHeader = "X-NMS-Signature"
+	timestampHeader = "X-NMS-Timestamp"
+)
+
+// sendWebhook POSTs a JSON body, signing it with HMAC-SHA256 when a secret is configured.
+func (svc *NotificationService) sendWebhook(ctx context.Context, url, secret string, body []byte) error {
+	headers := map[string]string{}
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[timestampHeader] = timestamp
+		headers[signatureHeader] = "sha256=" + sign(secret, timestamp, body)
+	}
+	return svc.post(ctx, url, body, headers)
+}
+
+// sendChat posts to a Slack or Teams incoming webhook; both accept a plain "text" field.
+func (svc *NotificationService) sendChat(ctx context.Context, url, subject, body string) error {
+	text := body
+	if subject != "" {
+		text = "*" + subject + "*\n" + body
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return svc.post(ctx, url, payload, nil)
+}
+
+// post sends a JSON request and treats any non-2xx status as a failure.
+func (svc *NotificationService) post(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := svc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// sendEmail sends a plain-text message through the configured SMTP server.
+func (svc *NotificationService) sendEmail(recipients []string, subject, body string) error {
+	if svc.smtp.Host == "" {
+		return fmt.Errorf("SMTP_HOST is not configured")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", svc.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if svc.smtp.Username != "" {
+		auth = smtp.PlainAuth("", svc.smtp.Username, svc.smtp.Password, svc.smtp.Host)
+	}
+	addr := net.JoinHostPort(svc.smtp.Host, strconv.Itoa(svc.smtp.Port))
+	return smtp.SendMail(addr, auth, svc.smtp.From, recipients, msg.Bytes())
+}
+
+// sign returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,235 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestService() *NotificationService {
+	return NewNotificationService(nil, nil, nil, testEncryptionKey, SMTPConfig{}, 1, 3, 1, 5)
+}
+
+func TestSendWebhookSignsTimestampAndBody(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	payload := []byte(`{"kind":"alert","title":"CPU high"}`)
+	if err := newTestService().sendWebhook(context.Background(), server.URL, "s3cret", payload); err != nil {
+		t.Fatalf("sendWebhook failed: %v", err)
+	}
+
+	if string(body) != string(payload) {
+		t.Errorf("body = %s, want %s", body, payload)
+	}
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", header.Get("Content-Type"))
+	}
+
+	timestamp := header.Get(timestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Fatalf("%s = %q, want the current Unix time", timestampHeader, timestamp)
+	}
+
+	// Verify as a receiver would: HMAC-SHA256 over "<timestamp>.<body>"
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := header.Get(signatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %q, want %q", signatureHeader, got, want)
+	}
+}
+
+func TestSendWebhookWithoutSecretIsUnsigned(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	if err := newTestService().sendWebhook(context.Background(), server.URL, "", []byte(`{}`)); err != nil {
+		t.Fatalf("sendWebhook failed: %v", err)
+	}
+	if header.Get(signatureHeader) != "" || header.Get(timestampHeader) != "" {
+		t.Errorf("unsigned webhook carries signature headers: %v", header)
+	}
+}
+
+func TestSendWebhookRejectsNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := newTestService().sendWebhook(context.Background(), server.URL, "s3cret", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("error = %v, want status and response snippet", err)
+	}
+}
+
+func TestSendChat(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	if err := newTestService().sendChat(context.Background(), server.URL, "Device down", "router-1 is unreachable"); err != nil {
+		t.Fatalf("sendChat failed: %v", err)
+	}
+	if want := "*Device down*\nrouter-1 is unreachable"; payload["text"] != want {
+		t.Errorf("text = %q, want %q", payload["text"], want)
+	}
+}
+
+// smtpMessage is one message accepted by the stub server.
+type smtpMessage struct {
+	auth string // Decoded AUTH PLAIN credentials, "" when none were sent
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPStub runs a minimal SMTP server on 127.0.0.1 that accepts every message
+// (and AUTH PLAIN) and returns its port and the received messages.
+func startSMTPStub(t *testing.T) (int, func() []smtpMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	var messages []smtpMessage
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				msg := serveSMTP(conn)
+				mu.Lock()
+				messages = append(messages, msg)
+				mu.Unlock()
+			}()
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	return port, func() []smtpMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]smtpMessage(nil), messages...)
+	}
+}
+
+// serveSMTP handles one SMTP session.
+func serveSMTP(conn net.Conn) smtpMessage {
+	var msg smtpMessage
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return msg
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(command, "AUTH PLAIN "):
+			decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			msg.auth = string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.data = data.String()
+			reply("250 OK queued")
+		case command == "QUIT":
+			reply("221 Bye")
+			return msg
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	port, received := startSMTPStub(t)
+	svc := newTestService()
+	svc.smtp = SMTPConfig{Host: "127.0.0.1", Port: port, Username: "nms", Password: "pw", From: "nms@example.com"}
+
+	err := svc.sendEmail([]string{"ops@example.com", "oncall@example.com"}, "Alert firing:\nCPU", "line one\nline two")
+	if err != nil {
+		t.Fatalf("sendEmail failed: %v", err)
+	}
+
+	messages := received()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.auth != "\x00nms\x00pw" {
+		t.Errorf("AUTH PLAIN credentials = %q", msg.auth)
+	}
+	if msg.from != "nms@example.com" || strings.Join(msg.to, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("envelope from=%q to=%v", msg.from, msg.to)
+	}
+	for _, want := range []string{
+		"From: nms@example.com\r\n",
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Subject: Alert firing: CPU\r\n", // Newlines in the subject can't inject headers
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg.data)
+		}
+	}
+}
+
+func TestSendEmailWithoutHost(t *testing.T) {
+	if err := newTestService().sendEmail([]string{"ops@example.com"}, "s", "b"); err == nil {
+		t.Error("expected an error when SMTP_HOST is unset")
+	}
+}
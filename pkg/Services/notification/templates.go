@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"nms/pkg/models"
+)
+
+// Built-in templates for channels that don't define their own
+const (
+	defaultSubject = "[{{.Severity}}] {{.Title}}"
+	defaultBody    = `{{.Title}}
+{{if .Message}}{{.Message}}
+{{end}}{{if .DeviceID}}Device: {{if .Hostname}}{{.Hostname}} {{end}}({{.IPAddress}}, id {{.DeviceID}})
+{{end}}{{if .Recipient}}On call: {{.Recipient}}
+{{end}}State: {{.State}}
+Time: {{.Timestamp.Format "2006-01-02T15:04:05Z07:00"}}`
+)
+
+// render produces the subject and body of a notification for a channel.
+// Webhooks without a body template receive the notification as JSON.
+func render(channel *models.NotificationChannel, notification *models.Notification) (string, string, error) {
+	subject, err := execute("subject", channel.SubjectTemplate, defaultSubject, notification)
+	if err != nil {
+		return "", "", err
+	}
+
+	if channel.BodyTemplate == "" && channel.Type == models.ChannelWebhook {
+		body, err := json.Marshal(notification)
+		return subject, string(body), err
+	}
+
+	body, err := execute("body", channel.BodyTemplate, defaultBody, notification)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// execute runs a channel template, or the fallback when the channel has none.
+func execute(name, text, fallback string, notification *models.Notification) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, notification); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"nms/pkg/models"
+)
+
+// handleAlertRuleCRUD validates the metric path and condition before delegating to the generic handler.
+// Changes are published to AlertService, which keeps its own copy of the rules.
+func (writer *EntityService) handleAlertRuleCRUD(ctx context.Context, req models.Request) models.Response {
+	if rule, ok := req.Payload.(*models.AlertRule); ok {
+		if rule.Path == "" {
+			return models.Response{Error: fmt.Errorf("path is required")}
+		}
+		if err := validatePath(rule.Path); err != nil {
+			return models.Response{Error: err}
+		}
+		if hasWildcard(rule.Path) {
+			return models.Response{Error: fmt.Errorf("path must not contain wildcards")}
+		}
+		if err := validateCondition(rule); err != nil {
+			return models.Response{Error: err}
+		}
+		if rule.EscalationPolicyID != nil {
+			if _, err := writer.escalationRepo.Get(ctx, *rule.EscalationPolicyID); err != nil {
+				return models.Response{Error: fmt.Errorf("escalation policy %d not found", *rule.EscalationPolicyID)}
+			}
+		}
+	}
+	return handleCRUD(ctx, req, writer.alertRuleRepo, writer.alertRuleEvents)
+}
+
+// validateCondition defaults the condition type and checks the fields it uses.
+// Anomaly rules compare z-scores, so only directional operators make sense.
+func validateCondition(rule *models.AlertRule) error {
+	switch rule.Condition {
+	case "":
+		rule.Condition = models.ConditionThreshold
+	case models.ConditionThreshold:
+	case models.ConditionAnomaly:
+		if rule.Operator == "eq" {
+			return fmt.Errorf("operator eq is not supported for anomaly rules")
+		}
+	default:
+		return fmt.Errorf("unknown condition %q", rule.Condition)
+	}
+	return nil
+}
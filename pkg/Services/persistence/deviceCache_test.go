@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"nms/pkg/models"
+)
+
+// deviceRepo is an in-memory device repository; only Create and Get are used.
+type deviceRepo struct {
+	rows map[int64]models.Device
+}
+
+func (r *deviceRepo) Create(ctx context.Context, entity *models.Device) (*models.Device, error) {
+	entity.ID = int64(len(r.rows) + 1)
+	r.rows[entity.ID] = *entity
+	return entity, nil
+}
+
+func (r *deviceRepo) Get(ctx context.Context, id int64) (*models.Device, error) {
+	row, ok := r.rows[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &row, nil
+}
+
+func (r *deviceRepo) Update(ctx context.Context, id int64, entity *models.Device) (*models.Device, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *deviceRepo) List(ctx context.Context) ([]*models.Device, error) { return nil, nil }
+func (r *deviceRepo) GetByFields(ctx context.Context, filters map[string]any) (*models.Device, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *deviceRepo) ListByFields(ctx context.Context, filters map[string]any) ([]*models.Device, error) {
+	return nil, nil
+}
+func (r *deviceRepo) Delete(ctx context.Context, id int64) error { return nil }
+
+func newCacheTestService(events chan models.Event) *EntityService {
+	return &EntityService{
+		deviceRepo:      &deviceRepo{rows: map[int64]models.Device{}},
+		deviceEvents:    events,
+		deviceCache:     make(map[int64]*models.Device),
+		backoffState:    make(map[int64]models.DeviceBackoff),
+		dependencyState: make(map[int64]models.DeviceDependency),
+		flapState:       make(map[int64]models.DeviceFlap),
+	}
+}
+
+func TestDeviceResponseIsDecoratedCopy(t *testing.T) {
+	events := make(chan models.Event, 1)
+	writer := newCacheTestService(events)
+	writer.flapState[1] = models.DeviceFlap{DeviceID: 1, Flapping: true}
+
+	resp := writer.handleDeviceCRUD(context.Background(), models.Request{
+		Operation: models.OpCreate,
+		Payload: &models.Device{
+			CredentialProfileID: 1, DiscoveryProfileID: 1, IPAddress: "10.0.0.1", PluginID: "snmp",
+			Status: "active", PollingIntervalSeconds: 60,
+		},
+	})
+	if resp.Error != nil {
+		t.Fatalf("create failed: %v", resp.Error)
+	}
+
+	created := resp.Data.(*models.Device)
+	if created.Backoff == nil || created.Flap == nil {
+		t.Errorf("response not decorated: backoff=%v flap=%v", created.Backoff, created.Flap)
+	}
+	cached := writer.deviceCache[created.ID]
+	if cached == created {
+		t.Fatal("response shares the cached device")
+	}
+	if cached.Backoff != nil || cached.Flap != nil {
+		t.Error("decoration leaked into the cached device")
+	}
+	if event := <-events; event.Payload.(*models.Device).Backoff != nil {
+		t.Error("decoration leaked into the Scheduler event")
+	}
+}
+
+func TestDetachChildrenReplacesCachedDevices(t *testing.T) {
+	writer := newCacheTestService(nil)
+	parentID := int64(1)
+	child := &models.Device{ID: 2, ParentDeviceID: &parentID}
+	writer.deviceCache[2] = child
+
+	writer.detachChildren(1)
+
+	if child.ParentDeviceID == nil {
+		t.Error("cached device modified in place")
+	}
+	if writer.deviceCache[2].ParentDeviceID != nil {
+		t.Error("child still attached in the cache")
+	}
+}
@@ -0,0 +1,125 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"nms/pkg/models"
+)
+
+// maxDependencyDepth bounds parent chain walks during cycle validation.
+const maxDependencyDepth = 32
+
+// validateParent checks that parentID exists and that making it the parent of deviceID creates no cycle.
+// deviceID is 0 for devices that don't exist yet.
+func (writer *EntityService) validateParent(deviceID, parentID int64) error {
+	if parentID == deviceID {
+		return fmt.Errorf("device cannot be its own parent")
+	}
+
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	if _, exists := writer.deviceCache[parentID]; !exists {
+		return fmt.Errorf("parent device %d not found", parentID)
+	}
+
+	current := parentID
+	for depth := 0; depth < maxDependencyDepth; depth++ {
+		parent := writer.deviceCache[current]
+		if parent == nil || parent.ParentDeviceID == nil {
+			return nil
+		}
+		if *parent.ParentDeviceID == deviceID {
+			return fmt.Errorf("parent device %d would create a dependency cycle", parentID)
+		}
+		current = *parent.ParentDeviceID
+	}
+	return fmt.Errorf("dependency chain exceeds %d levels", maxDependencyDepth)
+}
+
+// keepManagedRelations copies the stored parent and failure policy into a device update.
+// Both are changed only through their own operations.
+func (writer *EntityService) keepManagedRelations(deviceID int64, device *models.Device) {
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	if cached, exists := writer.deviceCache[deviceID]; exists {
+		device.ParentDeviceID = cached.ParentDeviceID
+		device.FailurePolicyID = cached.FailurePolicyID
+	}
+}
+
+// handleSetParent sets or clears a device's parent. The update event lets the Scheduler refresh its topology.
+func (writer *EntityService) handleSetParent(ctx context.Context, req models.Request) models.Response {
+	parentID, _ := req.Payload.(*int64)
+	if parentID != nil {
+		if err := writer.validateParent(req.ID, *parentID); err != nil {
+			return models.Response{Error: err}
+		}
+	}
+
+	device, err := writer.deviceRepo.Get(ctx, req.ID)
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("device %d not found: %w", req.ID, err)}
+	}
+
+	device.ParentDeviceID = parentID
+	updatedDevice, err := writer.deviceRepo.Update(ctx, req.ID, device)
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failed to set parent of device %d: %w", req.ID, err)}
+	}
+
+	writer.updateDeviceCache(models.OpUpdate, updatedDevice)
+	go sendEvent(writer.deviceEvents, models.Event{
+		Type:    models.EventUpdate,
+		Payload: updatedDevice,
+	})
+
+	slog.Info("Device parent updated", "component", "EntityService", "device_id", req.ID, "parent_device_id", parentID)
+	return models.Response{Data: updatedDevice}
+}
+
+// handleListChildren returns the devices that directly depend on a device.
+func (writer *EntityService) handleListChildren(deviceID int64) models.Response {
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	if _, exists := writer.deviceCache[deviceID]; !exists {
+		return models.Response{Error: fmt.Errorf("device %d not found", deviceID)}
+	}
+
+	children := make([]*models.Device, 0)
+	for _, dev := range writer.deviceCache {
+		if dev.ParentDeviceID != nil && *dev.ParentDeviceID == deviceID {
+			children = append(children, dev)
+		}
+	}
+	return models.Response{Data: children}
+}
+
+// detachChildren mirrors ON DELETE SET NULL in the cache after a parent is deleted.
+// Cached devices may be held by other services, so children are replaced by copies rather than modified.
+func (writer *EntityService) detachChildren(parentID int64) {
+	writer.cacheMu.Lock()
+	defer writer.cacheMu.Unlock()
+
+	for id, dev := range writer.deviceCache {
+		if dev.ParentDeviceID != nil && *dev.ParentDeviceID == parentID {
+			detached := *dev
+			detached.ParentDeviceID = nil
+			writer.deviceCache[id] = &detached
+		}
+	}
+}
+
+// attachDependency populates the device's dependency verdict from Scheduler state.
+func (writer *EntityService) attachDependency(device *models.Device) {
+	if device == nil || !device.Monitored() {
+		return
+	}
+	if dependency, exists := writer.dependencyState[device.ID]; exists {
+		device.Dependency = &dependency
+	}
+}
@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"nms/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LatestFlapping returns the newest flap history row of every device whose last flap change was a start.
+// Services rebuild their flap state from it at startup.
+func LatestFlapping(ctx context.Context, db *sqlx.DB) ([]models.FlapHistory, error) {
+	var rows []models.FlapHistory
+	err := db.SelectContext(ctx, &rows, `
+		SELECT * FROM (
+			SELECT DISTINCT ON (device_id) * FROM device_flap_history
+			ORDER BY device_id, created_at DESC, id DESC
+		) latest
+		WHERE flapping`)
+	return rows, err
+}
+
+// loadFlapState restores the flap state of devices that were flapping when the previous run stopped.
+func (writer *EntityService) loadFlapState(ctx context.Context) error {
+	rows, err := LatestFlapping(ctx, writer.db)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		writer.flapState[row.DeviceID] = models.DeviceFlap{DeviceID: row.DeviceID, Flapping: true, Score: row.Score, Since: row.CreatedAt}
+	}
+	slog.Info("Loaded flapping devices", "component", "EntityService", "count", len(rows))
+	return nil
+}
+
+// handleFlapUpdate stores a device's flap state and records the start/stop in its history.
+func (writer *EntityService) handleFlapUpdate(ctx context.Context, flap *models.DeviceFlap) {
+	writer.flapState[flap.DeviceID] = *flap
+	writer.notifyFlap(flap)
+
+	_, err := writer.flapHistoryRepo.Create(ctx, &models.FlapHistory{
+		DeviceID: flap.DeviceID,
+		Flapping: flap.Flapping,
+		Score:    flap.Score,
+	})
+	if err != nil {
+		slog.Error("Failed to record flap history", "component", "EntityService", "device_id", flap.DeviceID, "error", err)
+	}
+}
+
+// handleGetFlapping returns the flap state of every flapping device.
+func (writer *EntityService) handleGetFlapping() models.Response {
+	flaps := make([]models.DeviceFlap, 0)
+	for _, flap := range writer.flapState {
+		if flap.Flapping {
+			flaps = append(flaps, flap)
+		}
+	}
+	return models.Response{Data: flaps}
+}
+
+// handleListFlapHistory returns the flap start/stop history of a device, newest first.
+func (writer *EntityService) handleListFlapHistory(ctx context.Context, deviceID int64) models.Response {
+	history, err := writer.flapHistoryRepo.ListByFields(ctx, map[string]any{"device_id": deviceID})
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failed to list flap history for device %d: %w", deviceID, err)}
+	}
+	return models.Response{Data: history}
+}
+
+// attachFlap populates the device's flap state from FailureService.
+func (writer *EntityService) attachFlap(device *models.Device) {
+	if device == nil {
+		return
+	}
+	if flap, exists := writer.flapState[device.ID]; exists {
+		device.Flap = &flap
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 
+	"nms/pkg/Services/stream"
 	"nms/pkg/database"
 	"nms/pkg/models"
 	"nms/pkg/plugin"
@@ -35,15 +36,37 @@ type EntityService struct {
 	credentialRepo       database.Repository[models.CredentialProfile]
 	deviceRepo           database.Repository[models.Device]
 	discoveryProfileRepo database.Repository[models.DiscoveryProfile]
+	transitionRepo       database.Repository[models.DeviceTransition]
+	failurePolicyRepo    database.Repository[models.FailurePolicy]
+	flapHistoryRepo      database.Repository[models.FlapHistory]
+	alertRuleRepo        database.Repository[models.AlertRule]
+	channelRepo          database.Repository[models.NotificationChannel]
+	routeRepo            database.Repository[models.NotificationRoute]
+	escalationRepo       database.Repository[models.EscalationPolicy]
+	scheduleRepo         database.Repository[models.OnCallSchedule]
+	overrideRepo         database.Repository[models.OnCallOverride]
+
+	// Queries the generic repositories can't express
+	db *sqlx.DB
 
 	// Event publishing channels
 	discoveryProfileEvents chan<- models.Event
 	deviceEvents           chan<- models.Event
+	alertRuleEvents        chan<- models.Event
+	notifications          chan<- models.Event
+
+	// Status changes and discovery findings sent to StreamService
+	streamChan chan<- models.StreamEvent
 
 	// In-memory caches for fast lookups (no DB round-trips)
 	deviceCache     map[int64]*models.Device
 	credentialCache map[int64]*models.CredentialProfile
 	cacheMu         sync.RWMutex
+
+	// Scheduler backoff/dependency and FailureService flap state, only touched from the Run goroutine
+	backoffState    map[int64]models.DeviceBackoff
+	dependencyState map[int64]models.DeviceDependency
+	flapState       map[int64]models.DeviceFlap
 }
 
 // NewEntityService creates a new entity writer service.
@@ -54,6 +77,9 @@ func NewEntityService(
 	db *sqlx.DB,
 	discoveryProfileEvents chan<- models.Event,
 	deviceEvents chan<- models.Event,
+	alertRuleEvents chan<- models.Event,
+	notifications chan<- models.Event,
+	streamChan chan<- models.StreamEvent,
 ) *EntityService {
 	return &EntityService{
 		discoveryResultsChan:   discoveryResults,
@@ -62,10 +88,26 @@ func NewEntityService(
 		credentialRepo:         database.NewSqlxRepository[models.CredentialProfile](db),
 		deviceRepo:             database.NewSqlxRepository[models.Device](db),
 		discoveryProfileRepo:   database.NewSqlxRepository[models.DiscoveryProfile](db),
+		transitionRepo:         database.NewSqlxRepository[models.DeviceTransition](db),
+		failurePolicyRepo:      database.NewSqlxRepository[models.FailurePolicy](db),
+		flapHistoryRepo:        database.NewSqlxRepository[models.FlapHistory](db),
+		alertRuleRepo:          database.NewSqlxRepository[models.AlertRule](db),
+		channelRepo:            database.NewSqlxRepository[models.NotificationChannel](db),
+		routeRepo:              database.NewSqlxRepository[models.NotificationRoute](db),
+		escalationRepo:         database.NewSqlxRepository[models.EscalationPolicy](db),
+		scheduleRepo:           database.NewSqlxRepository[models.OnCallSchedule](db),
+		overrideRepo:           database.NewSqlxRepository[models.OnCallOverride](db),
+		db:                     db,
 		discoveryProfileEvents: discoveryProfileEvents,
 		deviceEvents:           deviceEvents,
+		alertRuleEvents:        alertRuleEvents,
+		notifications:          notifications,
+		streamChan:             streamChan,
 		deviceCache:            make(map[int64]*models.Device),
 		credentialCache:        make(map[int64]*models.CredentialProfile),
+		backoffState:           make(map[int64]models.DeviceBackoff),
+		dependencyState:        make(map[int64]models.DeviceDependency),
+		flapState:              make(map[int64]models.DeviceFlap),
 	}
 }
 
@@ -100,6 +142,7 @@ func (writer *EntityService) provisionFromDiscovery(ctx context.Context, result
 
 	if err == nil && existingDevice != nil {
 		slog.Debug("Device already exists", "component", "EntityService", "target", result.Target, "port", result.Port, "device_id", existingDevice.ID)
+		writer.publishDiscoveryFinding(existingDevice.ID, result, false)
 		return
 	}
 
@@ -144,6 +187,7 @@ func (writer *EntityService) provisionFromDiscovery(ctx context.Context, result
 
 	// Update cache with newly created device
 	writer.updateDeviceCache(models.OpCreate, createdDevice)
+	writer.publishDiscoveryFinding(createdDevice.ID, result, true)
 
 	if initialStatus == "active" {
 		// Publish event so scheduler picks it up
@@ -164,6 +208,22 @@ func (writer *EntityService) handleEvent(ctx context.Context, event models.Event
 		writer.triggerDiscovery(ctx, event)
 	case models.EventProvisionDevice:
 		writer.provisionDevice(ctx, event)
+	case models.EventBackoffUpdate:
+		if backoff, ok := event.Payload.(*models.DeviceBackoff); ok {
+			writer.backoffState[backoff.DeviceID] = *backoff
+		}
+	case models.EventDependencyUpdate:
+		if dependency, ok := event.Payload.(*models.DeviceDependency); ok {
+			if dependency.BlockedBy == 0 {
+				delete(writer.dependencyState, dependency.DeviceID)
+			} else {
+				writer.dependencyState[dependency.DeviceID] = *dependency
+			}
+		}
+	case models.EventFlapUpdate:
+		if flap, ok := event.Payload.(*models.DeviceFlap); ok {
+			writer.handleFlapUpdate(ctx, flap)
+		}
 	default:
 		slog.Error("Ignoring unknown command type", "component", "EntityService", "type", event.Type)
 	}
@@ -211,6 +271,7 @@ func (writer *EntityService) provisionDevice(ctx context.Context, event models.E
 	}
 
 	// Update device status and polling interval
+	fromStatus := device.Status
 	device.Status = "active"
 	if cmd.PollingIntervalSeconds > 0 {
 		device.PollingIntervalSeconds = cmd.PollingIntervalSeconds
@@ -224,6 +285,7 @@ func (writer *EntityService) provisionDevice(ctx context.Context, event models.E
 
 	// Update cache with activated device
 	writer.updateDeviceCache(models.OpUpdate, updatedDevice)
+	writer.recordTransition(ctx, cmd.DeviceID, fromStatus, "active", "provisioned via API")
 
 	go sendEvent(writer.deviceEvents, models.Event{
 		Type:    models.EventUpdate,
@@ -330,6 +392,13 @@ func (writer *EntityService) handleDiscoveryProfileCRUD(ctx context.Context, req
 			resp.Error = fmt.Errorf("invalid payload type")
 			return resp
 		}
+		if entity.FailurePolicyID != nil {
+			resp.Error = fmt.Errorf("failure_policy_id is managed via /discovery_profiles/:id/failure_policy")
+			return resp
+		}
+		if existing, getErr := writer.discoveryProfileRepo.Get(ctx, req.ID); getErr == nil {
+			entity.FailurePolicyID = existing.FailurePolicyID
+		}
 		data, err := writer.discoveryProfileRepo.Update(ctx, req.ID, entity)
 		if err == nil {
 			// Enrich with credential profile before publishing event
@@ -362,10 +431,38 @@ func (writer *EntityService) handleCrudRequest(ctx context.Context, req models.R
 	switch req.Operation {
 	case models.OpGetBatch:
 		resp = writer.handleGetBatch(req)
+	case models.OpLookupDevices:
+		resp = writer.handleLookupDevices(req)
 	case models.OpGetCredential:
 		resp = writer.handleGetCredential(req)
 	case models.OpDeactivateDevice:
-		resp = writer.handleDeactivateDevice(ctx, req.ID)
+		resp = writer.handleDeactivateDevice(ctx, req)
+	case models.OpActivateDevice:
+		resp = writer.handleActivateDevice(ctx, req)
+	case models.OpGetInactiveDevices:
+		resp = writer.handleGetInactiveDevices(ctx)
+	case models.OpListTransitions:
+		resp = writer.handleListTransitions(ctx, req.ID)
+	case models.OpSearchTransitions:
+		resp = writer.handleSearchTransitions(ctx, req)
+	case models.OpSetParent:
+		resp = writer.handleSetParent(ctx, req)
+	case models.OpListChildren:
+		resp = writer.handleListChildren(req.ID)
+	case models.OpResolveFailurePolicy:
+		resp = writer.handleResolveFailurePolicy(ctx, req.ID)
+	case models.OpAssignFailurePolicy:
+		resp = writer.handleAssignFailurePolicy(ctx, req)
+	case models.OpDegradeDevice:
+		resp = writer.handleDegradeDevice(ctx, req)
+	case models.OpAlertDevice:
+		resp = writer.handleAlertDevice(req)
+	case models.OpGetDegradedDevices:
+		resp = writer.handleGetDegradedDevices()
+	case models.OpListFlapHistory:
+		resp = writer.handleListFlapHistory(ctx, req.ID)
+	case models.OpGetFlapping:
+		resp = writer.handleGetFlapping()
 	default:
 		// Standard CRUD operations
 		switch req.EntityType {
@@ -375,6 +472,20 @@ func (writer *EntityService) handleCrudRequest(ctx context.Context, req models.R
 			resp = writer.handleDeviceCRUD(ctx, req)
 		case "DiscoveryProfile":
 			resp = writer.handleDiscoveryProfileCRUD(ctx, req)
+		case "FailurePolicy":
+			resp = writer.handleFailurePolicyCRUD(ctx, req)
+		case "AlertRule":
+			resp = writer.handleAlertRuleCRUD(ctx, req)
+		case "NotificationChannel":
+			resp = writer.handleChannelCRUD(ctx, req)
+		case "NotificationRoute":
+			resp = writer.handleRouteCRUD(ctx, req)
+		case "EscalationPolicy":
+			resp = writer.handleEscalationPolicyCRUD(ctx, req)
+		case "OnCallSchedule":
+			resp = handleCRUD(ctx, req, writer.scheduleRepo, nil)
+		case "OnCallOverride":
+			resp = writer.handleOverrideCRUD(ctx, req)
 		default:
 			resp.Error = fmt.Errorf("unknown entity type: %s", req.EntityType)
 		}
@@ -419,21 +530,86 @@ func (writer *EntityService) handleDeviceCRUD(ctx context.Context, req models.Re
 			if strings.TrimSpace(device.PluginID) == "" {
 				return models.Response{Error: fmt.Errorf("plugin_id is required")}
 			}
+			if device.ParentDeviceID != nil {
+				if err := writer.validateParent(0, *device.ParentDeviceID); err != nil {
+					return models.Response{Error: err}
+				}
+			}
 		case models.OpUpdate:
 			// Fail-fast: credential_profile_id and discovery_profile_id are immutable
 			if device.CredentialProfileID != 0 || device.DiscoveryProfileID != 0 {
 				return models.Response{Error: fmt.Errorf("credential_profile_id and discovery_profile_id are immutable after creation")}
 			}
+			// Parent and failure policy have their own endpoints; keep the stored ones so updates don't clear them
+			if device.ParentDeviceID != nil {
+				return models.Response{Error: fmt.Errorf("parent_device_id is managed via /devices/:id/parent")}
+			}
+			if device.FailurePolicyID != nil {
+				return models.Response{Error: fmt.Errorf("failure_policy_id is managed via /devices/:id/failure_policy")}
+			}
+			writer.keepManagedRelations(req.ID, device)
 		}
 	}
 
+	// Capture previous status so manual status changes are recorded as transitions
+	var fromStatus string
+	if req.Operation == models.OpUpdate {
+		writer.cacheMu.RLock()
+		if cached, exists := writer.deviceCache[req.ID]; exists {
+			fromStatus = cached.Status
+		}
+		writer.cacheMu.RUnlock()
+	}
+
 	resp := handleCRUD(ctx, req, writer.deviceRepo, writer.deviceEvents)
 	if resp.Error == nil {
 		writer.updateDeviceCache(req.Operation, resp.Data)
+
+		if updated, ok := resp.Data.(*models.Device); ok && req.Operation == models.OpUpdate && fromStatus != "" {
+			writer.recordTransition(ctx, updated.ID, fromStatus, updated.Status, models.ManualStatusReason)
+		}
+
+		switch data := resp.Data.(type) {
+		case *models.Device:
+			// The created/updated device is shared with the cache and the Scheduler event, so decorate a copy
+			device := *data
+			writer.attachBackoff(&device)
+			writer.attachDependency(&device)
+			writer.attachFlap(&device)
+			resp.Data = &device
+		case []*models.Device:
+			for _, dev := range data {
+				writer.attachBackoff(dev)
+				writer.attachDependency(dev)
+				writer.attachFlap(dev)
+			}
+		}
+		if req.Operation == models.OpDelete {
+			delete(writer.backoffState, req.ID)
+			delete(writer.dependencyState, req.ID)
+			delete(writer.flapState, req.ID)
+			writer.detachChildren(req.ID)
+		}
 	}
 	return resp
 }
 
+// attachBackoff populates the device's backoff from Scheduler state.
+// Monitored devices without reported failures poll at their configured interval.
+func (writer *EntityService) attachBackoff(device *models.Device) {
+	if device == nil || !device.Monitored() {
+		return
+	}
+	if backoff, exists := writer.backoffState[device.ID]; exists {
+		device.Backoff = &backoff
+		return
+	}
+	device.Backoff = &models.DeviceBackoff{
+		DeviceID:                 device.ID,
+		EffectiveIntervalSeconds: device.PollingIntervalSeconds,
+	}
+}
+
 // updateDeviceCache updates the in-memory device cache based on CRUD operation
 func (writer *EntityService) updateDeviceCache(op string, data interface{}) {
 	device, ok := data.(*models.Device)
@@ -500,10 +676,14 @@ func (writer *EntityService) LoadCaches(ctx context.Context) error {
 	}
 	slog.Info("Loaded devices to cache", "component", "EntityService", "count", len(devices))
 
+	if err := writer.loadFlapState(ctx); err != nil {
+		return fmt.Errorf("failed to load flap state: %w", err)
+	}
+
 	return nil
 }
 
-// GetActiveDeviceIDs returns IDs of all active devices in cache.
+// GetActiveDeviceIDs returns IDs of all monitored (active or degraded) devices in cache.
 // Used by Scheduler to initialize its priority queue.
 func (writer *EntityService) GetActiveDeviceIDs() []int64 {
 	writer.cacheMu.RLock()
@@ -511,13 +691,28 @@ func (writer *EntityService) GetActiveDeviceIDs() []int64 {
 
 	ids := make([]int64, 0, len(writer.deviceCache))
 	for id, dev := range writer.deviceCache {
-		if dev.Status == "active" {
+		if dev.Monitored() {
 			ids = append(ids, id)
 		}
 	}
 	return ids
 }
 
+// handleLookupDevices returns copies of the cached devices with the given IDs.
+// Unknown IDs are left out of the map.
+func (writer *EntityService) handleLookupDevices(req models.Request) models.Response {
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	devices := make(map[int64]models.Device, len(req.IDs))
+	for _, id := range req.IDs {
+		if dev, exists := writer.deviceCache[id]; exists {
+			devices[id] = *dev
+		}
+	}
+	return models.Response{Data: devices}
+}
+
 // handleGetBatch handles batch device lookup by IDs.
 // Returns devices split by should_ping flag.
 func (writer *EntityService) handleGetBatch(req models.Request) models.Response {
@@ -534,8 +729,8 @@ func (writer *EntityService) handleGetBatch(req models.Request) models.Response
 			slog.Debug("Device not found in cache (deleted?)", "component", "EntityService", "device_id", id)
 			continue
 		}
-		// Only return active devices
-		if dev.Status != "active" {
+		// Only return monitored devices
+		if !dev.Monitored() {
 			continue
 		}
 		if dev.ShouldPing {
@@ -569,28 +764,166 @@ func (writer *EntityService) handleGetCredential(req models.Request) models.Resp
 
 // handleDeactivateDevice deactivates a device by setting its status to inactive.
 // Called by HealthMonitor when failure threshold is exceeded.
-func (writer *EntityService) handleDeactivateDevice(ctx context.Context, deviceID int64) models.Response {
+func (writer *EntityService) handleDeactivateDevice(ctx context.Context, req models.Request) models.Response {
+	reason, _ := req.Payload.(string)
+	if reason == "" {
+		reason = "deactivated by health monitor"
+	}
+
+	resp := writer.setDeviceStatus(ctx, req.ID, "inactive", reason)
+	if resp.Error == nil {
+		slog.Info("Device deactivated", "component", "EntityService", "device_id", req.ID, "reason", reason)
+	}
+	return resp
+}
+
+// handleActivateDevice reactivates an inactive device.
+// Called by RecoveryService after enough consecutive successful probes; the
+// resulting update event re-enqueues the device in the Scheduler.
+func (writer *EntityService) handleActivateDevice(ctx context.Context, req models.Request) models.Response {
+	reason, _ := req.Payload.(string)
+	if reason == "" {
+		reason = "reactivated"
+	}
+
+	resp := writer.setDeviceStatus(ctx, req.ID, "active", reason)
+	if resp.Error == nil {
+		slog.Info("Device reactivated", "component", "EntityService", "device_id", req.ID, "reason", reason)
+	}
+	return resp
+}
+
+// setDeviceStatus changes a device's status, records the transition and notifies the Scheduler.
+func (writer *EntityService) setDeviceStatus(ctx context.Context, deviceID int64, status string, reason string) models.Response {
 	device, err := writer.deviceRepo.Get(ctx, deviceID)
 	if err != nil {
 		return models.Response{Error: fmt.Errorf("device %d not found: %w", deviceID, err)}
 	}
 
-	// Update device status to inactive
-	device.Status = "inactive"
+	fromStatus := device.Status
+	device.Status = status
 	updatedDevice, err := writer.deviceRepo.Update(ctx, deviceID, device)
 	if err != nil {
-		return models.Response{Error: fmt.Errorf("failed to deactivate device %d: %w", deviceID, err)}
+		return models.Response{Error: fmt.Errorf("failed to set device %d to %s: %w", deviceID, status, err)}
 	}
 
-	// Update cache with deactivated device
+	// Update cache with new status; the Scheduler re-derives dependency state once the device is polled again
 	writer.updateDeviceCache(models.OpUpdate, updatedDevice)
+	writer.recordTransition(ctx, deviceID, fromStatus, status, reason)
+	delete(writer.dependencyState, deviceID)
 
 	// Publish event for cache invalidation in Scheduler
 	go sendEvent(writer.deviceEvents, models.Event{
 		Type:    models.EventUpdate,
 		Payload: updatedDevice,
 	})
+	if fromStatus != status {
+		writer.notifyStatus(updatedDevice, reason)
+	}
 
-	slog.Info("Device deactivated", "component", "EntityService", "device_id", deviceID)
 	return models.Response{Data: updatedDevice}
 }
+
+// recordTransition persists a status change. Failures are logged, never propagated.
+func (writer *EntityService) recordTransition(ctx context.Context, deviceID int64, from, to, reason string) {
+	if from == to {
+		return
+	}
+
+	_, err := writer.transitionRepo.Create(ctx, &models.DeviceTransition{
+		DeviceID:   deviceID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+	})
+	if err != nil {
+		slog.Error("Failed to record device transition", "component", "EntityService", "device_id", deviceID, "from", from, "to", to, "error", err)
+	}
+
+	stream.Publish(writer.streamChan, models.StreamEvent{
+		Type:     models.StreamDeviceStatus,
+		DeviceID: deviceID,
+		Data:     &models.StreamStatusChange{From: from, To: to, Reason: reason},
+	})
+}
+
+// publishDiscoveryFinding streams a device that answered a discovery run.
+func (writer *EntityService) publishDiscoveryFinding(deviceID int64, result plugin.Result, created bool) {
+	stream.Publish(writer.streamChan, models.StreamEvent{
+		Type:     models.StreamDiscovery,
+		DeviceID: deviceID,
+		Data: &models.StreamDiscoveryFinding{
+			DiscoveryProfileID: result.DiscoveryProfileID,
+			Target:             result.Target,
+			Port:               result.Port,
+			Hostname:           result.Hostname,
+			New:                created,
+		},
+	})
+}
+
+// handleGetInactiveDevices returns inactive devices from cache for recovery probing.
+// Devices an operator deactivated through the API stay inactive until reactivated by hand,
+// so they are left out. If the transition history can't be read, recovery skips the round.
+func (writer *EntityService) handleGetInactiveDevices(ctx context.Context) models.Response {
+	var manual []int64
+	err := writer.db.SelectContext(ctx, &manual, `
+		SELECT d.id
+		FROM devices d
+		CROSS JOIN LATERAL (
+			SELECT reason
+			FROM device_transitions
+			WHERE device_id = d.id AND to_status = 'inactive'
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) t
+		WHERE d.status = 'inactive' AND t.reason = $1`, models.ManualStatusReason)
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failed to find manually deactivated devices: %w", err)}
+	}
+	skip := make(map[int64]bool, len(manual))
+	for _, id := range manual {
+		skip[id] = true
+	}
+
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	devices := make([]*models.Device, 0)
+	for _, dev := range writer.deviceCache {
+		if dev.Status == "inactive" && !skip[dev.ID] {
+			devices = append(devices, dev)
+		}
+	}
+	return models.Response{Data: devices}
+}
+
+// handleListTransitions returns the status history of a device, newest first.
+func (writer *EntityService) handleListTransitions(ctx context.Context, deviceID int64) models.Response {
+	transitions, err := writer.transitionRepo.ListByFields(ctx, map[string]any{"device_id": deviceID})
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failed to list transitions for device %d: %w", deviceID, err)}
+	}
+	return models.Response{Data: transitions}
+}
+
+// handleSearchTransitions returns the status transitions of the given devices (every device
+// when none are given) within a time range, newest first.
+func (writer *EntityService) handleSearchTransitions(ctx context.Context, req models.Request) models.Response {
+	query, ok := req.Payload.(*models.TransitionQuery)
+	if !ok {
+		return models.Response{Error: fmt.Errorf("invalid payload for transition search")}
+	}
+
+	transitions := make([]*models.DeviceTransition, 0)
+	err := writer.db.SelectContext(ctx, &transitions, `
+		SELECT * FROM device_transitions
+		WHERE created_at >= $1 AND created_at <= $2
+		  AND (cardinality($3::bigint[]) = 0 OR device_id = ANY($3))
+		ORDER BY created_at DESC
+		LIMIT $4`, query.Start, query.End, query.DeviceIDs, query.Limit)
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failed to search transitions: %w", err)}
+	}
+	return models.Response{Data: transitions}
+}
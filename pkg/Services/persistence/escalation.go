@@ -0,0 +1,43 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"nms/pkg/models"
+)
+
+// handleEscalationPolicyCRUD checks steps are ordered and reference existing channels and schedules.
+func (writer *EntityService) handleEscalationPolicyCRUD(ctx context.Context, req models.Request) models.Response {
+	if policy, ok := req.Payload.(*models.EscalationPolicy); ok {
+		previousDelay := 0
+		for i, step := range policy.Steps {
+			if step.DelayMinutes < previousDelay {
+				return models.Response{Error: fmt.Errorf("step %d: delay_minutes must not be shorter than the previous step", i+1)}
+			}
+			previousDelay = step.DelayMinutes
+			if _, err := writer.channelRepo.Get(ctx, step.ChannelID); err != nil {
+				return models.Response{Error: fmt.Errorf("step %d: notification channel %d not found", i+1, step.ChannelID)}
+			}
+			if step.ScheduleID != nil {
+				if _, err := writer.scheduleRepo.Get(ctx, *step.ScheduleID); err != nil {
+					return models.Response{Error: fmt.Errorf("step %d: on-call schedule %d not found", i+1, *step.ScheduleID)}
+				}
+			}
+		}
+	}
+	return handleCRUD(ctx, req, writer.escalationRepo, nil)
+}
+
+// handleOverrideCRUD checks the override window and schedule before delegating to the generic handler.
+func (writer *EntityService) handleOverrideCRUD(ctx context.Context, req models.Request) models.Response {
+	if override, ok := req.Payload.(*models.OnCallOverride); ok {
+		if !override.EndsAt.After(override.StartsAt) {
+			return models.Response{Error: fmt.Errorf("ends_at must be after starts_at")}
+		}
+		if _, err := writer.scheduleRepo.Get(ctx, override.ScheduleID); err != nil {
+			return models.Response{Error: fmt.Errorf("on-call schedule %d not found", override.ScheduleID)}
+		}
+	}
+	return handleCRUD(ctx, req, writer.overrideRepo, nil)
+}
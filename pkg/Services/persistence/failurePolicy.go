@@ -0,0 +1,150 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"nms/pkg/models"
+)
+
+// handleFailurePolicyCRUD validates mode-specific fields before delegating to the generic handler.
+// Updates are checked against the stored policy, since window_seconds may be omitted to keep it.
+func (writer *EntityService) handleFailurePolicyCRUD(ctx context.Context, req models.Request) models.Response {
+	if policy, ok := req.Payload.(*models.FailurePolicy); ok {
+		windowSeconds := policy.WindowSeconds
+		if req.Operation == models.OpUpdate && windowSeconds == 0 {
+			existing, err := writer.failurePolicyRepo.Get(ctx, req.ID)
+			if err != nil {
+				return models.Response{Error: fmt.Errorf("failure policy %d not found: %w", req.ID, err)}
+			}
+			windowSeconds = existing.WindowSeconds
+		}
+		if policy.Mode == models.FailureModeSliding && windowSeconds == 0 {
+			return models.Response{Error: fmt.Errorf("window_seconds is required for sliding mode")}
+		}
+		if policy.PingThreshold == 0 && policy.PollThreshold == 0 {
+			return models.Response{Error: fmt.Errorf("at least one of ping_threshold or poll_threshold must be set")}
+		}
+	}
+	return handleCRUD(ctx, req, writer.failurePolicyRepo, nil)
+}
+
+// handleResolveFailurePolicy returns the policy that applies to a device:
+// its own assignment first, then its discovery profile's. Data is nil when neither is set.
+func (writer *EntityService) handleResolveFailurePolicy(ctx context.Context, deviceID int64) models.Response {
+	writer.cacheMu.RLock()
+	device, exists := writer.deviceCache[deviceID]
+	var policyID *int64
+	var profileID int64
+	if exists {
+		policyID, profileID = device.FailurePolicyID, device.DiscoveryProfileID
+	}
+	writer.cacheMu.RUnlock()
+
+	if !exists {
+		return models.Response{Error: fmt.Errorf("device %d not found", deviceID)}
+	}
+
+	if policyID == nil {
+		profile, err := writer.discoveryProfileRepo.Get(ctx, profileID)
+		if err != nil || profile.FailurePolicyID == nil {
+			return models.Response{}
+		}
+		policyID = profile.FailurePolicyID
+	}
+
+	policy, err := writer.failurePolicyRepo.Get(ctx, *policyID)
+	if err != nil {
+		return models.Response{Error: fmt.Errorf("failure policy %d not found: %w", *policyID, err)}
+	}
+	return models.Response{Data: policy}
+}
+
+// handleAssignFailurePolicy assigns (or clears, with a nil payload) the policy of a device or discovery profile.
+func (writer *EntityService) handleAssignFailurePolicy(ctx context.Context, req models.Request) models.Response {
+	policyID, _ := req.Payload.(*int64)
+	if policyID != nil {
+		if _, err := writer.failurePolicyRepo.Get(ctx, *policyID); err != nil {
+			return models.Response{Error: fmt.Errorf("failure policy %d not found", *policyID)}
+		}
+	}
+
+	switch req.EntityType {
+	case "Device":
+		device, err := writer.deviceRepo.Get(ctx, req.ID)
+		if err != nil {
+			return models.Response{Error: fmt.Errorf("device %d not found: %w", req.ID, err)}
+		}
+		device.FailurePolicyID = policyID
+		updated, err := writer.deviceRepo.Update(ctx, req.ID, device)
+		if err != nil {
+			return models.Response{Error: fmt.Errorf("failed to assign failure policy to device %d: %w", req.ID, err)}
+		}
+		writer.updateDeviceCache(models.OpUpdate, updated)
+		slog.Info("Failure policy assigned", "component", "EntityService", "device_id", req.ID, "policy_id", policyID)
+		return models.Response{Data: updated}
+
+	case "DiscoveryProfile":
+		profile, err := writer.discoveryProfileRepo.Get(ctx, req.ID)
+		if err != nil {
+			return models.Response{Error: fmt.Errorf("discovery profile %d not found: %w", req.ID, err)}
+		}
+		profile.FailurePolicyID = policyID
+		updated, err := writer.discoveryProfileRepo.Update(ctx, req.ID, profile)
+		if err != nil {
+			return models.Response{Error: fmt.Errorf("failed to assign failure policy to discovery profile %d: %w", req.ID, err)}
+		}
+		slog.Info("Failure policy assigned", "component", "EntityService", "profile_id", req.ID, "policy_id", policyID)
+		return models.Response{Data: updated}
+	}
+
+	return models.Response{Error: fmt.Errorf("failure policies cannot be assigned to %s", req.EntityType)}
+}
+
+// handleDegradeDevice marks a device degraded. It stays scheduled, so the next successful poll can restore it.
+func (writer *EntityService) handleDegradeDevice(ctx context.Context, req models.Request) models.Response {
+	reason, _ := req.Payload.(string)
+	if reason == "" {
+		reason = "degraded by failure policy"
+	}
+
+	resp := writer.setDeviceStatus(ctx, req.ID, "degraded", reason)
+	if resp.Error == nil {
+		slog.Info("Device degraded", "component", "EntityService", "device_id", req.ID, "reason", reason)
+	}
+	return resp
+}
+
+// handleAlertDevice notifies that an alert-only failure policy triggered; the device keeps its status.
+func (writer *EntityService) handleAlertDevice(req models.Request) models.Response {
+	reason, _ := req.Payload.(string)
+	if reason == "" {
+		reason = "alert by failure policy"
+	}
+
+	writer.cacheMu.RLock()
+	device, exists := writer.deviceCache[req.ID]
+	writer.cacheMu.RUnlock()
+	if !exists {
+		return models.Response{Error: fmt.Errorf("device %d not found", req.ID)}
+	}
+
+	writer.notifyDevice(device, "warning", fmt.Sprintf("Failure policy triggered on device %s", deviceLabel(device)), reason)
+	slog.Info("Failure policy alert sent", "component", "EntityService", "device_id", req.ID, "reason", reason)
+	return models.Response{Data: device}
+}
+
+// handleGetDegradedDevices returns degraded devices from cache.
+func (writer *EntityService) handleGetDegradedDevices() models.Response {
+	writer.cacheMu.RLock()
+	defer writer.cacheMu.RUnlock()
+
+	devices := make([]*models.Device, 0)
+	for _, dev := range writer.deviceCache {
+		if dev.Status == "degraded" {
+			devices = append(devices, dev)
+		}
+	}
+	return models.Response{Data: devices}
+}
@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"nms/pkg/models"
+)
+
+// policyRepo is an in-memory failure policy repository; only Get, Create and Update are used.
+type policyRepo struct {
+	rows map[int64]models.FailurePolicy
+}
+
+func (r *policyRepo) Get(ctx context.Context, id int64) (*models.FailurePolicy, error) {
+	row, ok := r.rows[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &row, nil
+}
+
+func (r *policyRepo) Create(ctx context.Context, entity *models.FailurePolicy) (*models.FailurePolicy, error) {
+	entity.ID = int64(len(r.rows) + 1)
+	r.rows[entity.ID] = *entity
+	return entity, nil
+}
+
+// Update keeps the stored window when it is omitted, like the update:"omitempty" tag.
+func (r *policyRepo) Update(ctx context.Context, id int64, entity *models.FailurePolicy) (*models.FailurePolicy, error) {
+	if entity.WindowSeconds == 0 {
+		entity.WindowSeconds = r.rows[id].WindowSeconds
+	}
+	entity.ID = id
+	r.rows[id] = *entity
+	return entity, nil
+}
+
+func (r *policyRepo) List(ctx context.Context) ([]*models.FailurePolicy, error) { return nil, nil }
+func (r *policyRepo) GetByFields(ctx context.Context, filters map[string]any) (*models.FailurePolicy, error) {
+	return nil, sql.ErrNoRows
+}
+func (r *policyRepo) ListByFields(ctx context.Context, filters map[string]any) ([]*models.FailurePolicy, error) {
+	return nil, nil
+}
+func (r *policyRepo) Delete(ctx context.Context, id int64) error { return nil }
+
+func TestFailurePolicyWindowValidatedAgainstStoredPolicy(t *testing.T) {
+	repo := &policyRepo{rows: map[int64]models.FailurePolicy{
+		1: {ID: 1, Name: "sliding", Mode: models.FailureModeSliding, WindowSeconds: 300, PingThreshold: 3, Action: "alert"},
+		2: {ID: 2, Name: "consecutive", Mode: models.FailureModeConsecutive, PingThreshold: 3, Action: "alert"},
+	}}
+	writer := &EntityService{failurePolicyRepo: repo}
+	ctx := context.Background()
+	request := func(op string, id int64, policy models.FailurePolicy) models.Response {
+		return writer.handleFailurePolicyCRUD(ctx, models.Request{Operation: op, EntityType: "FailurePolicy", ID: id, Payload: &policy})
+	}
+
+	// Renaming a sliding policy without resending window_seconds keeps the stored window
+	resp := request(models.OpUpdate, 1, models.FailurePolicy{Name: "renamed", Mode: models.FailureModeSliding, PingThreshold: 5, Action: "alert"})
+	if resp.Error != nil {
+		t.Fatalf("update without window_seconds rejected: %v", resp.Error)
+	}
+	if stored := repo.rows[1]; stored.Name != "renamed" || stored.WindowSeconds != 300 {
+		t.Errorf("stored policy = %+v", stored)
+	}
+
+	// Switching a consecutive policy to sliding still needs a window
+	if resp := request(models.OpUpdate, 2, models.FailurePolicy{Name: "consecutive", Mode: models.FailureModeSliding, PingThreshold: 3, Action: "alert"}); resp.Error == nil {
+		t.Error("switch to sliding mode without window_seconds accepted")
+	}
+	if resp := request(models.OpUpdate, 2, models.FailurePolicy{Name: "consecutive", Mode: models.FailureModeSliding, WindowSeconds: 60, PingThreshold: 3, Action: "alert"}); resp.Error != nil {
+		t.Errorf("switch to sliding mode with window_seconds rejected: %v", resp.Error)
+	}
+
+	if resp := request(models.OpCreate, 0, models.FailurePolicy{Name: "new", Mode: models.FailureModeSliding, PingThreshold: 3, Action: "alert"}); resp.Error == nil {
+		t.Error("sliding policy created without window_seconds")
+	}
+	if resp := request(models.OpUpdate, 99, models.FailurePolicy{Name: "missing", Mode: models.FailureModeSliding, PingThreshold: 3, Action: "alert"}); resp.Error == nil {
+		t.Error("update of a missing policy accepted")
+	}
+}
+
+func TestAlertDeviceNotifiesWithoutStatusChange(t *testing.T) {
+	notifications := make(chan models.Event, 1)
+	device := &models.Device{ID: 7, Hostname: "router-1", Status: "active"}
+	writer := &EntityService{notifications: notifications, deviceCache: map[int64]*models.Device{7: device}}
+
+	resp := writer.handleAlertDevice(models.Request{Operation: models.OpAlertDevice, ID: 7, Payload: "3 ping failures within 5m0s (policy edge)"})
+	if resp.Error != nil {
+		t.Fatalf("alert failed: %v", resp.Error)
+	}
+	if device.Status != "active" {
+		t.Errorf("status changed to %s", device.Status)
+	}
+
+	event := <-notifications
+	notification, ok := event.Payload.(*models.Notification)
+	if event.Type != models.EventNotification || !ok {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if notification.Kind != models.NotificationDeviceStatus || notification.Severity != "warning" || notification.DeviceID != 7 ||
+		notification.Hostname != "router-1" || notification.Message != "3 ping failures within 5m0s (policy edge)" {
+		t.Errorf("notification = %+v", notification)
+	}
+
+	if resp := writer.handleAlertDevice(models.Request{Operation: models.OpAlertDevice, ID: 8}); resp.Error == nil {
+		t.Error("alert for an unknown device succeeded")
+	}
+}
@@ -0,0 +1,215 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// maxBuckets caps the buckets a single series may span (range / step).
+const maxBuckets = 10000
+
+// ErrInvalidMetricQuery marks query errors caused by the request rather than the database.
+var ErrInvalidMetricQuery = errors.New("invalid metric query")
+
+// MetricSeries is one bucketed series of a step query.
+type MetricSeries struct {
+	Group      string         `json:"group"`               // Device ID, discovery profile ID, plugin ID or "all"
+	DeviceID   int64          `json:"device_id,omitempty"` // Set when grouped by device
+	Resolution string         `json:"resolution"`          // Tier the buckets were computed from
+	Points     []*SeriesPoint `json:"points"`              // Buckets without samples are omitted
+}
+
+// SeriesPoint is the aggregate of one bucket.
+type SeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"` // Bucket start
+	Value     *float64  `json:"value"`
+}
+
+// groupExprs maps MetricQuery.GroupBy to the SQL expression naming each series.
+var groupExprs = map[string]string{
+	"device":            "m.device_id::text",
+	"all":               "'all'",
+	"discovery_profile": "d.discovery_profile_id::text",
+	"plugin":            "d.plugin_id",
+}
+
+// rawAggregates computes each aggregate over the extracted sample values v.
+var rawAggregates = map[string]string{
+	"avg":   "AVG(v)",
+	"min":   "MIN(v)",
+	"max":   "MAX(v)",
+	"sum":   "SUM(v)",
+	"count": "COUNT(v)::double precision",
+	"last":  "(ARRAY_AGG(v ORDER BY ts DESC))[1]",
+	"p95":   "percentile_cont(0.95) WITHIN GROUP (ORDER BY v)",
+	"p99":   "percentile_cont(0.99) WITHIN GROUP (ORDER BY v)",
+}
+
+// tierAggregates recombines rollup buckets. Percentiles can't be rebuilt from rollups.
+var tierAggregates = map[string]string{
+	"avg":   "SUM(m.avg * m.count) / SUM(m.count)",
+	"min":   "MIN(m.min)",
+	"max":   "MAX(m.max)",
+	"sum":   "SUM(m.avg * m.count)",
+	"count": "SUM(m.count)::double precision",
+	"last":  "(ARRAY_AGG(m.last ORDER BY m.last_at DESC))[1]",
+}
+
+// getMetricSeries answers a step query: one aggregated value per bucket and group, computed
+// in SQL. Whole rollup buckets are used when the step is a multiple of a tier's width.
+func (s *MetricsService) getMetricSeries(ctx context.Context, deviceIDs []int64, query models.MetricQuery) ([]*MetricSeries, error) {
+	step, err := time.ParseDuration(query.Step)
+	if err != nil || step < time.Second {
+		return nil, fmt.Errorf("%w: step must be a duration of at least 1s, e.g. \"5m\"", ErrInvalidMetricQuery)
+	}
+	if query.Path == "" {
+		return nil, fmt.Errorf("%w: path is required for step queries", ErrInvalidMetricQuery)
+	}
+	if err := validatePath(query.Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+	}
+	if query.Aggregate == "" {
+		query.Aggregate = "avg"
+	}
+	if query.GroupBy == "" {
+		query.GroupBy = "device"
+	}
+	if _, ok := rawAggregates[query.Aggregate]; !ok {
+		return nil, fmt.Errorf("%w: unknown aggregate %q", ErrInvalidMetricQuery, query.Aggregate)
+	}
+	if _, ok := groupExprs[query.GroupBy]; !ok {
+		return nil, fmt.Errorf("%w: unknown group_by %q", ErrInvalidMetricQuery, query.GroupBy)
+	}
+	if buckets := query.End.Sub(query.Start) / step; buckets > maxBuckets {
+		return nil, fmt.Errorf("%w: range spans %d buckets of %s, at most %d allowed", ErrInvalidMetricQuery, buckets, step, maxBuckets)
+	}
+
+	resolution, err := s.selectStepResolution(query, step, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if tier, ok := findTier(resolution); ok {
+		series, err := s.querySeries(ctx, tierSeriesQuery(tier, query), resolution, query.GroupBy,
+			deviceIDs, query.Start.Add(-tier.width), query.End, step.Seconds(), query.Path)
+		if err != nil || len(series) > 0 {
+			return series, err
+		}
+		// Nothing rolled up (e.g. a non-numeric path): compute from raw rows
+	}
+	return s.querySeries(ctx, rawSeriesQuery(query), ResolutionRaw, query.GroupBy,
+		deviceIDs, query.Start, query.End, step.Seconds())
+}
+
+// selectStepResolution picks the coarsest rollup tier whose width divides the step and whose
+// retention reaches the start, or raw rows for percentiles and finer steps.
+func (s *MetricsService) selectStepResolution(query models.MetricQuery, step time.Duration, now time.Time) (string, error) {
+	fits := func(tier rollupTier) bool {
+		_, rebuildable := tierAggregates[query.Aggregate]
+		return rebuildable && step >= tier.width && step%tier.width == 0
+	}
+
+	switch query.Resolution {
+	case ResolutionRaw:
+		return ResolutionRaw, nil
+	case "", ResolutionAuto:
+		for i := len(rollupTiers) - 1; i >= 0; i-- {
+			if tier := rollupTiers[i]; fits(tier) && s.retention.covers(tier.name, query.Start, now) {
+				return tier.name, nil
+			}
+		}
+		return ResolutionRaw, nil
+	}
+
+	tier, ok := findTier(query.Resolution)
+	if !ok || !fits(tier) {
+		return "", fmt.Errorf("%w: resolution %s can't serve step %s with aggregate %s", ErrInvalidMetricQuery, query.Resolution, query.Step, query.Aggregate)
+	}
+	return tier.name, nil
+}
+
+// rawSeriesQuery aggregates the extracted path of raw rows per group and bucket.
+// Parameters: $1 device IDs, $2 start, $3 end, $4 step seconds.
+// Samples where the path holds something other than a number are counted so the caller
+// can reject the path; samples missing the path are skipped.
+func rawSeriesQuery(query models.MetricQuery) string {
+	pgPath := strings.Replace(query.Path, ".", ",", -1)
+	return fmt.Sprintf(`
+		SELECT grp, date_bin($4::double precision * INTERVAL '1 second', ts, %[1]s) AS bucket,
+			%[2]s AS value,
+			COUNT(*) FILTER (WHERE kind <> 'number') AS non_numeric,
+			MIN(kind) FILTER (WHERE kind <> 'number') AS found
+		FROM (
+			SELECT %[3]s AS grp, m.timestamp AS ts,
+				jsonb_typeof(m.data #> '{%[4]s}') AS kind,
+				CASE WHEN jsonb_typeof(m.data #> '{%[4]s}') = 'number' THEN (m.data #>> '{%[4]s}')::double precision END AS v
+			FROM metrics m
+			JOIN devices d ON d.id = m.device_id
+			WHERE m.device_id = ANY($1) AND m.timestamp >= $2 AND m.timestamp <= $3
+		) samples
+		WHERE kind IS NOT NULL AND kind <> 'null'
+		GROUP BY grp, bucket
+		ORDER BY grp, bucket`,
+		bucketOrigin, rawAggregates[query.Aggregate], groupExprs[query.GroupBy], pgPath)
+}
+
+// tierSeriesQuery recombines rollup buckets per group and step bucket.
+// Parameters: $1 device IDs, $2 start, $3 end, $4 step seconds, $5 path.
+func tierSeriesQuery(tier rollupTier, query models.MetricQuery) string {
+	return fmt.Sprintf(`
+		SELECT %[3]s AS grp, date_bin($4::double precision * INTERVAL '1 second', m.bucket, %[1]s) AS step_bucket,
+			%[2]s AS value, 0 AS non_numeric, NULL::text AS found
+		FROM %[4]s m
+		JOIN devices d ON d.id = m.device_id
+		WHERE m.device_id = ANY($1) AND m.path = $5 AND m.bucket > $2 AND m.bucket <= $3
+		GROUP BY grp, step_bucket
+		ORDER BY grp, step_bucket`,
+		bucketOrigin, tierAggregates[query.Aggregate], groupExprs[query.GroupBy], tier.table)
+}
+
+// querySeries runs a series query and assembles one MetricSeries per group.
+func (s *MetricsService) querySeries(ctx context.Context, sqlQuery, resolution, groupBy string, args ...any) ([]*MetricSeries, error) {
+	rows, err := s.readDB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("series query failed: %w", err)
+	}
+	defer rows.Close()
+
+	series := make([]*MetricSeries, 0)
+	var current *MetricSeries
+	for rows.Next() {
+		var group string
+		var point SeriesPoint
+		var value sql.NullFloat64
+		var nonNumeric int64
+		var found sql.NullString
+		if err := rows.Scan(&group, &point.Timestamp, &value, &nonNumeric, &found); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if nonNumeric > 0 {
+			return nil, fmt.Errorf("%w: path holds %s values, only numeric leaves can be aggregated", ErrInvalidMetricQuery, found.String)
+		}
+		if value.Valid {
+			point.Value = &value.Float64
+		}
+
+		if current == nil || current.Group != group {
+			current = &MetricSeries{Group: group, Resolution: resolution, Points: make([]*SeriesPoint, 0)}
+			if groupBy == "device" {
+				current.DeviceID, _ = strconv.ParseInt(group, 10, 64)
+			}
+			series = append(series, current)
+		}
+		current.Points = append(current.Points, &point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return series, nil
+}
@@ -0,0 +1,204 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// maxLatestDevices caps how many devices one latest-value request may address.
+const maxLatestDevices = 1000
+
+// LatestMetric is the newest poll result of a device.
+type LatestMetric struct {
+	DeviceID  int64           `json:"device_id"`
+	Hostname  string          `json:"hostname"`
+	IPAddress string          `json:"ip_address"`
+	PluginID  string          `json:"plugin_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// LatestValue is a device's newest poll result, or the value at a path of it.
+type LatestValue struct {
+	DeviceID  int64           `json:"device_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Path      string          `json:"path,omitempty"`
+	Value     json.RawMessage `json:"value"` // Whole result without a path; null when the result lacks the path
+}
+
+// latestEntry is the newest stored poll result of a device.
+type latestEntry struct {
+	timestamp time.Time
+	data      json.RawMessage
+}
+
+// latestCache keeps the newest stored poll result per device, so current values are read
+// from memory instead of a per-device ORDER BY timestamp DESC LIMIT 1 over metrics.
+// Write workers store concurrently, hence the lock.
+type latestCache struct {
+	mu      sync.RWMutex
+	entries map[int64]latestEntry
+}
+
+func newLatestCache() *latestCache {
+	return &latestCache{entries: make(map[int64]latestEntry)}
+}
+
+// store keeps a result unless the device already has a newer one.
+func (c *latestCache) store(deviceID int64, timestamp time.Time, data json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, exists := c.entries[deviceID]; exists && current.timestamp.After(timestamp) {
+		return
+	}
+	c.entries[deviceID] = latestEntry{timestamp: timestamp, data: data}
+}
+
+// get returns the newest result of a device.
+func (c *latestCache) get(deviceID int64) (latestEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.entries[deviceID]
+	return entry, exists
+}
+
+// LoadLatestValues fills the latest-value cache with each device's newest stored result,
+// read through idx_metrics_device_time. Call before Run.
+func (s *MetricsService) LoadLatestValues(ctx context.Context) error {
+	rows, err := s.readDB.QueryContext(ctx, `
+		SELECT d.id, m.timestamp, m.data
+		FROM devices d
+		CROSS JOIN LATERAL (
+			SELECT timestamp, data
+			FROM metrics
+			WHERE device_id = d.id
+			ORDER BY timestamp DESC
+			LIMIT 1
+		) m`)
+	if err != nil {
+		return fmt.Errorf("latest values query failed: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var deviceID int64
+		var timestamp time.Time
+		var data json.RawMessage
+		if err := rows.Scan(&deviceID, &timestamp, &data); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		s.latest.store(deviceID, timestamp, data)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	slog.Info("Latest values loaded", "component", "MetricsService", "devices", count)
+	return nil
+}
+
+// handleGetLatest answers OpGetLatest from the cache: the newest result of each requested
+// device, or the value at Path of it. Devices without a stored result are left out.
+func (s *MetricsService) handleGetLatest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	query, ok := req.Payload.(*models.LatestQuery)
+	if !ok {
+		resp.Error = fmt.Errorf("invalid payload for latest values")
+		req.ReplyCh <- resp
+		return
+	}
+
+	resp.Data, resp.Error = s.latestValues(ctx, query)
+	req.ReplyCh <- resp
+}
+
+// latestValues resolves the query's devices and reads their cached results.
+func (s *MetricsService) latestValues(ctx context.Context, query *models.LatestQuery) ([]*LatestValue, error) {
+	if err := validatePath(query.Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+	}
+	if hasWildcard(query.Path) {
+		return nil, fmt.Errorf("%w: latest values take a concrete path", ErrInvalidMetricQuery)
+	}
+
+	deviceIDs, err := s.resolveDevices(ctx, &MetricQueryRequest{DeviceIDs: query.DeviceIDs, DiscoveryProfileID: query.DiscoveryProfileID})
+	if err != nil {
+		return nil, err
+	}
+	if len(deviceIDs) > maxLatestDevices {
+		return nil, fmt.Errorf("%w: %d devices requested, at most %d allowed", ErrInvalidMetricQuery, len(deviceIDs), maxLatestDevices)
+	}
+
+	values := make([]*LatestValue, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		entry, ok := s.latest.get(deviceID)
+		if !ok {
+			continue
+		}
+		value := &LatestValue{DeviceID: deviceID, Timestamp: entry.timestamp, Path: query.Path, Value: entry.data}
+		if query.Path != "" {
+			value.Value = models.ExtractPath(entry.data, query.Path)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// handleLatest returns the newest poll result of every monitored device.
+// Payload is the oldest result age still returned.
+func (s *MetricsService) handleLatest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	maxAge, ok := req.Payload.(time.Duration)
+	if !ok || maxAge <= 0 {
+		resp.Error = fmt.Errorf("invalid payload for latest metrics")
+		req.ReplyCh <- resp
+		return
+	}
+
+	resp.Data, resp.Error = s.latestMetrics(ctx, time.Now().Add(-maxAge))
+	req.ReplyCh <- resp
+}
+
+// latestMetrics lists the monitored devices and attaches their cached results. Devices
+// without a result since the given time are left out.
+func (s *MetricsService) latestMetrics(ctx context.Context, since time.Time) ([]*LatestMetric, error) {
+	rows, err := s.readDB.QueryContext(ctx, `
+		SELECT id, COALESCE(hostname, ''), host(ip_address), plugin_id
+		FROM devices
+		WHERE status IN ('active', 'degraded')
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("latest metrics query failed: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make([]*LatestMetric, 0)
+	for rows.Next() {
+		lm := &LatestMetric{}
+		if err := rows.Scan(&lm.DeviceID, &lm.Hostname, &lm.IPAddress, &lm.PluginID); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		entry, ok := s.latest.get(lm.DeviceID)
+		if !ok || entry.timestamp.Before(since) {
+			continue
+		}
+		lm.Timestamp, lm.Data = entry.timestamp, entry.data
+		latest = append(latest, lm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return latest, nil
+}
@@ -0,0 +1,201 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// wildcard is the path segment that matches every key at its level.
+const wildcard = "*"
+
+// maxExpandedPaths caps how many concrete paths one query may address after expansion.
+const maxExpandedPaths = 100
+
+// reportedPathsWindow is how far back OpListMetricPaths looks for paths a device reported.
+const reportedPathsWindow = 24 * time.Hour
+
+// hasWildcard reports whether a path contains a "*" segment.
+func hasWildcard(path string) bool {
+	for _, segment := range strings.Split(path, ".") {
+		if segment == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// requestedPaths returns Path followed by Paths, without duplicates.
+func requestedPaths(query models.MetricQuery) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, path := range append([]string{query.Path}, query.Paths...) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// isMultiPath reports whether a query needs results keyed by path: several paths or a wildcard.
+// A single concrete Path keeps the plain response shape.
+func isMultiPath(query models.MetricQuery) bool {
+	return len(query.Paths) > 0 || hasWildcard(query.Path)
+}
+
+// expandPaths validates the requested paths and replaces wildcard segments with the keys
+// present in the devices' data over the query range. Found keys must pass keyValidator,
+// so expanded paths are as safe to embed in SQL as validated ones.
+func (s *MetricsService) expandPaths(ctx context.Context, deviceIDs []int64, query models.MetricQuery) ([]string, error) {
+	seen := make(map[string]bool)
+	var expanded []string
+	for _, pattern := range requestedPaths(query) {
+		if err := validatePath(pattern); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMetricQuery, pattern, err)
+		}
+		paths, err := s.expandPattern(ctx, deviceIDs, strings.Split(pattern, "."), query.Start, query.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				expanded = append(expanded, path)
+			}
+		}
+		if len(expanded) > maxExpandedPaths {
+			return nil, fmt.Errorf("%w: paths expand to more than %d concrete paths", ErrInvalidMetricQuery, maxExpandedPaths)
+		}
+	}
+	sort.Strings(expanded)
+	return expanded, nil
+}
+
+// expandPattern expands the first wildcard segment, then the rest recursively.
+func (s *MetricsService) expandPattern(ctx context.Context, deviceIDs []int64, segments []string, start, end time.Time) ([]string, error) {
+	at := -1
+	for i, segment := range segments {
+		if segment == wildcard {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return []string{strings.Join(segments, ".")}, nil
+	}
+
+	keys, err := s.keysAt(ctx, deviceIDs, segments[:at], start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, key := range keys {
+		concrete := append(append(append([]string{}, segments[:at]...), key), segments[at+1:]...)
+		more, err := s.expandPattern(ctx, deviceIDs, concrete, start, end)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, more...)
+		if len(paths) > maxExpandedPaths {
+			return nil, fmt.Errorf("%w: paths expand to more than %d concrete paths", ErrInvalidMetricQuery, maxExpandedPaths)
+		}
+	}
+	return paths, nil
+}
+
+// keysAt lists the object keys found under a concrete prefix in the range.
+// Keys that don't form a valid path segment are skipped.
+func (s *MetricsService) keysAt(ctx context.Context, deviceIDs []int64, prefix []string, start, end time.Time) ([]string, error) {
+	object := "m.data"
+	if len(prefix) > 0 {
+		object = fmt.Sprintf("(m.data #> '{%s}')", strings.Join(prefix, ","))
+	}
+	rows, err := s.readDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT k
+		FROM metrics m
+		CROSS JOIN LATERAL jsonb_object_keys(CASE WHEN jsonb_typeof(%[1]s) = 'object' THEN %[1]s ELSE '{}'::jsonb END) k
+		WHERE m.device_id = ANY($1) AND m.timestamp >= $2 AND m.timestamp <= $3
+		ORDER BY k`, object), deviceIDs, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand wildcard under %q: %w", strings.Join(prefix, "."), err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if keyValidator.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// queryPaths runs a query once per expanded path and keys the results by concrete path.
+// Step queries yield []*MetricSeries per path, raw queries []*BatchMetricResult.
+func (s *MetricsService) queryPaths(ctx context.Context, deviceIDs []int64, query models.MetricQuery) (map[string]any, error) {
+	query = s.withDefaultRange(query)
+	paths, err := s.expandPaths(ctx, deviceIDs, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]any, len(paths))
+	for _, path := range paths {
+		single := query
+		single.Path, single.Paths = path, nil
+
+		var data any
+		if query.Step != "" {
+			data, err = s.getMetricSeries(ctx, deviceIDs, single)
+		} else {
+			data, err = s.getMetricsBatch(ctx, deviceIDs, single)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		results[path] = data
+	}
+	return results, nil
+}
+
+// handleListPaths lists the numeric leaf paths a device reported within reportedPathsWindow.
+// The 1m rollup tier holds exactly those paths, one row per path and minute, so the lookup
+// stays on its primary key instead of unpacking raw JSONB.
+func (s *MetricsService) handleListPaths(ctx context.Context, req models.Request) {
+	var resp models.Response
+	resp.Data, resp.Error = s.reportedPaths(ctx, req.ID, time.Now().Add(-reportedPathsWindow))
+	req.ReplyCh <- resp
+}
+
+// reportedPaths returns the distinct paths of a device's 1m buckets since a time, sorted.
+func (s *MetricsService) reportedPaths(ctx context.Context, deviceID int64, since time.Time) ([]string, error) {
+	rows, err := s.readDB.QueryContext(ctx, `
+		SELECT DISTINCT path
+		FROM metrics_1m
+		WHERE device_id = $1 AND bucket >= $2
+		ORDER BY path`, deviceID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paths of device %d: %w", deviceID, err)
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,171 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// maxSearchMatches caps the matches one search may return.
+const maxSearchMatches = 10000
+
+// searchComparisons maps ordered MetricSearch operators to SQL.
+var searchComparisons = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// MetricMatch is one sample satisfying a search predicate.
+type MetricMatch struct {
+	DeviceID  int64           `json:"device_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// MetricSearchResult lists matching samples, newest first.
+type MetricSearchResult struct {
+	Devices   []int64        `json:"devices"`   // Distinct devices among Matches
+	Matches   []*MetricMatch `json:"matches"`   // At most Limit samples
+	Truncated bool           `json:"truncated"` // More samples matched than Limit
+}
+
+// handleSearch answers a value predicate search over raw metrics.
+func (s *MetricsService) handleSearch(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	search, ok := req.Payload.(*models.MetricSearch)
+	if !ok {
+		resp.Error = fmt.Errorf("invalid payload for metric search")
+		req.ReplyCh <- resp
+		return
+	}
+
+	resp.Data, resp.Error = s.searchMetrics(ctx, *search)
+	req.ReplyCh <- resp
+}
+
+// searchMetrics finds samples whose value at the path satisfies the predicate.
+// Equality is a containment test served by the GIN index on metrics.data; ordered
+// comparisons only match numbers and rely on the time range and device scope.
+func (s *MetricsService) searchMetrics(ctx context.Context, search models.MetricSearch) (*MetricSearchResult, error) {
+	if search.Path == "" || hasWildcard(search.Path) {
+		return nil, fmt.Errorf("%w: search needs one concrete path", ErrInvalidMetricQuery)
+	}
+	if err := validatePath(search.Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+	}
+	limit := search.Limit
+	if limit <= 0 {
+		limit = s.defaultLimit
+	}
+	if limit > maxSearchMatches {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrInvalidMetricQuery, maxSearchMatches)
+	}
+
+	ranged := s.withDefaultRange(models.MetricQuery{Start: search.Start, End: search.End})
+	args := []any{ranged.Start, ranged.End, limit + 1}
+	conditions := []string{"m.timestamp >= $1", "m.timestamp <= $2"}
+
+	if len(search.DeviceIDs) > 0 || search.DiscoveryProfileID != 0 {
+		deviceIDs, err := s.resolveDevices(ctx, &MetricQueryRequest{DeviceIDs: search.DeviceIDs, DiscoveryProfileID: search.DiscoveryProfileID})
+		if err != nil {
+			return nil, err
+		}
+		if len(deviceIDs) == 0 {
+			return &MetricSearchResult{Devices: []int64{}, Matches: []*MetricMatch{}}, nil
+		}
+		args = append(args, deviceIDs)
+		conditions = append(conditions, fmt.Sprintf("m.device_id = ANY($%d)", len(args)))
+	}
+
+	predicate, value, err := searchPredicate(search)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, value)
+	conditions = append(conditions, fmt.Sprintf(predicate, len(args)))
+
+	pgPath := strings.Replace(search.Path, ".", ",", -1)
+	rows, err := s.readDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT m.device_id, m.timestamp, m.data #> '{%s}'
+		FROM metrics m
+		WHERE %s
+		ORDER BY m.timestamp DESC, m.device_id
+		LIMIT $3`, pgPath, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("metric search failed: %w", err)
+	}
+	defer rows.Close()
+
+	result := &MetricSearchResult{Devices: []int64{}, Matches: make([]*MetricMatch, 0)}
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		if len(result.Matches) == limit {
+			result.Truncated = true
+			break
+		}
+		match := &MetricMatch{}
+		if err := rows.Scan(&match.DeviceID, &match.Timestamp, &match.Value); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		result.Matches = append(result.Matches, match)
+		if !seen[match.DeviceID] {
+			seen[match.DeviceID] = true
+			result.Devices = append(result.Devices, match.DeviceID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	sort.Slice(result.Devices, func(i, j int) bool { return result.Devices[i] < result.Devices[j] })
+	return result, nil
+}
+
+// searchPredicate returns the WHERE clause of a search, with %d standing for the value's
+// placeholder, and the value to bind. The path is embedded, so it must be validated first.
+func searchPredicate(search models.MetricSearch) (string, any, error) {
+	pgPath := strings.Replace(search.Path, ".", ",", -1)
+
+	if op, ok := searchComparisons[search.Operator]; ok {
+		number, ok := search.Value.(float64)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s needs a numeric value", ErrInvalidMetricQuery, search.Operator)
+		}
+		return fmt.Sprintf("jsonb_typeof(m.data #> '{%[1]s}') = 'number' AND (m.data #>> '{%[1]s}')::double precision %[2]s $%%d", pgPath, op), number, nil
+	}
+
+	switch search.Value.(type) {
+	case float64, string, bool:
+	default:
+		return "", nil, fmt.Errorf("%w: %s needs a number, string or boolean value", ErrInvalidMetricQuery, search.Operator)
+	}
+
+	switch search.Operator {
+	case "eq":
+		// {"memory":{"free":v}}: containment lets the GIN index find the rows
+		var doc any = search.Value
+		segments := strings.Split(search.Path, ".")
+		for i := len(segments) - 1; i >= 0; i-- {
+			doc = map[string]any{segments[i]: doc}
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+		}
+		return "m.data @> $%d::jsonb", string(encoded), nil
+	case "ne":
+		encoded, err := json.Marshal(search.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+		}
+		return fmt.Sprintf("m.data #> '{%s}' <> $%%d::jsonb", pgPath), string(encoded), nil
+	}
+	return "", nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidMetricQuery, search.Operator)
+}
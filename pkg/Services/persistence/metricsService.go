@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"nms/pkg/Services/exporter"
+	"nms/pkg/Services/stream"
 	"nms/pkg/models"
 	"nms/pkg/plugin"
 
@@ -45,24 +47,37 @@ type metricsJob struct {
 // ═══════════════════════════════════════════════════════════════════════════
 
 // pathValidator ensures path segments start with a letter and contain safe chars only.
-var pathValidator = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,31}(\.[a-zA-Z][a-zA-Z0-9_]{0,31})*$`)
+// A segment may also be the "*" wildcard, which is expanded to concrete keys before any SQL is built.
+var pathValidator = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_]{0,31}|\*)(\.([a-zA-Z][a-zA-Z0-9_]{0,31}|\*))*$`)
+
+// keyValidator checks JSON keys found while expanding a wildcard, so only keys a
+// concrete path could name end up in queries.
+var keyValidator = regexp.MustCompile(keyPattern)
 
 // MetricResult represents a single point in the time series.
+// Points read from a rollup tier carry the bucket average in Value and the rest of the rollup alongside.
 type MetricResult struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Value     json.RawMessage `json:"value"`
+	Min       *float64        `json:"min,omitempty"`
+	Max       *float64        `json:"max,omitempty"`
+	Count     *int64          `json:"count,omitempty"`
+	Last      *float64        `json:"last,omitempty"`
 }
 
 // BatchMetricResult groups results by device ID.
 type BatchMetricResult struct {
-	DeviceID int64           `json:"device_id"`
-	Results  []*MetricResult `json:"results"`
+	DeviceID   int64           `json:"device_id"`
+	Resolution string          `json:"resolution"` // Tier the points came from: raw, 1m, 1h or 1d
+	Results    []*MetricResult `json:"results"`
 }
 
 // MetricQueryRequest holds parameters for a metrics query.
+// Devices are DeviceIDs plus every device of DiscoveryProfileID, if set.
 type MetricQueryRequest struct {
-	DeviceIDs []int64
-	Query     models.MetricQuery
+	DeviceIDs          []int64
+	DiscoveryProfileID int64
+	Query              models.MetricQuery
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -84,12 +99,30 @@ type MetricsService struct {
 	workerCount int
 	jobChan     chan metricsJob
 
-	// Failure events sent to HealthMonitor
+	// Failure and success events sent to HealthMonitor
 	failureChan chan<- models.Event
 
+	// Poll outcomes sent to Scheduler (adaptive backoff)
+	pollOutcomeChan chan<- models.Event
+
+	// Poll results sent to AlertService for threshold evaluation
+	alertChan chan<- []plugin.Result
+
+	// Stored poll batches sent to ExportService; nil when no export sink is configured
+	exportChan chan<- exporter.PollBatch
+
+	// Stored poll results sent to StreamService for live subscribers
+	streamChan chan<- models.StreamEvent
+
 	// Query defaults
 	defaultLimit      int
 	defaultRangeHours int
+
+	// Retention per storage tier, for choosing the tier of a query
+	retention RollupRetention
+
+	// Newest stored poll result per device
+	latest *latestCache
 }
 
 // NewMetricsService creates a new unified metrics service.
@@ -100,8 +133,13 @@ func NewMetricsService(
 	readDB *sql.DB,
 	workerCount int,
 	failureChan chan<- models.Event,
+	pollOutcomeChan chan<- models.Event,
+	alertChan chan<- []plugin.Result,
+	exportChan chan<- exporter.PollBatch,
+	streamChan chan<- models.StreamEvent,
 	defaultLimit int,
 	defaultRangeHours int,
+	retention RollupRetention,
 ) *MetricsService {
 	return &MetricsService{
 		pollResults:       pollResults,
@@ -111,8 +149,14 @@ func NewMetricsService(
 		workerCount:       workerCount,
 		jobChan:           make(chan metricsJob, workerCount*2), // buffer = 2x workers
 		failureChan:       failureChan,
+		pollOutcomeChan:   pollOutcomeChan,
+		alertChan:         alertChan,
+		exportChan:        exportChan,
+		streamChan:        streamChan,
 		defaultLimit:      defaultLimit,
 		defaultRangeHours: defaultRangeHours,
+		retention:         retention,
+		latest:            newLatestCache(),
 	}
 }
 
@@ -163,7 +207,20 @@ func (s *MetricsService) worker(ctx context.Context, id int, wg *sync.WaitGroup)
 		case jobTypeWrite:
 			s.handleWrite(ctx, job.writeResults)
 		case jobTypeRead:
-			s.handleQuery(ctx, job.readRequest)
+			switch job.readRequest.Operation {
+			case models.OpSearchMetrics:
+				s.handleSearch(ctx, job.readRequest)
+			case models.OpTopMetrics:
+				s.handleTop(ctx, job.readRequest)
+			case models.OpLatestMetrics:
+				s.handleLatest(ctx, job.readRequest)
+			case models.OpListMetricPaths:
+				s.handleListPaths(ctx, job.readRequest)
+			case models.OpGetLatest:
+				s.handleGetLatest(ctx, job.readRequest)
+			default:
+				s.handleQuery(ctx, job.readRequest)
+			}
 		}
 	}
 
@@ -183,8 +240,11 @@ func (s *MetricsService) handleWrite(ctx context.Context, results []plugin.Resul
 	now := time.Now()
 
 	for _, result := range results {
+		s.publishPollOutcome(result, now)
+
 		if result.Success {
 			rows = append(rows, []any{result.DeviceID, result.Data, now})
+			s.publishPollSuccess(result.DeviceID, now)
 		} else {
 			slog.Error("Poll result error", "component", "MetricsService",
 				"device_id", result.DeviceID, "target", result.Target,
@@ -201,6 +261,8 @@ func (s *MetricsService) handleWrite(ctx context.Context, results []plugin.Resul
 		}
 	}
 
+	s.publishAlertResults(results)
+
 	if len(rows) == 0 {
 		slog.Debug("No successful results to insert", "component", "MetricsService")
 		return
@@ -232,6 +294,81 @@ func (s *MetricsService) handleWrite(ctx context.Context, results []plugin.Resul
 	}
 
 	slog.Debug("Batch inserted metrics", "component", "MetricsService", "count", len(rows))
+
+	for _, result := range results {
+		if result.Success {
+			s.latest.store(result.DeviceID, now, result.Data)
+			stream.Publish(s.streamChan, models.StreamEvent{
+				Type:      models.StreamPollResult,
+				DeviceID:  result.DeviceID,
+				Timestamp: now,
+				Data:      result.Data,
+			})
+		}
+	}
+
+	s.publishExportBatch(results, now)
+}
+
+// publishPollOutcome reports a poll result to the Scheduler without blocking.
+func (s *MetricsService) publishPollOutcome(result plugin.Result, at time.Time) {
+	event := models.Event{
+		Type: models.EventPollOutcome,
+		Payload: &models.PollOutcomeEvent{
+			DeviceID:  result.DeviceID,
+			Timestamp: at,
+			Success:   result.Success,
+		},
+	}
+
+	select {
+	case s.pollOutcomeChan <- event:
+	default:
+		slog.Warn("Poll outcome channel full, dropping event", "component", "MetricsService", "device_id", result.DeviceID)
+	}
+}
+
+// publishPollSuccess reports a successful poll to HealthMonitor for availability history.
+// Unlike failures it never blocks: a dropped success is corrected by the next one.
+func (s *MetricsService) publishPollSuccess(deviceID int64, at time.Time) {
+	event := models.Event{
+		Type: models.EventDeviceSuccess,
+		Payload: &models.DeviceSuccessEvent{
+			DeviceID:  deviceID,
+			Timestamp: at,
+			Reason:    "poll",
+		},
+	}
+
+	select {
+	case s.failureChan <- event:
+	default:
+		slog.Warn("Failure channel full, dropping success event", "component", "MetricsService", "device_id", deviceID)
+	}
+}
+
+// publishAlertResults forwards a batch to AlertService without blocking.
+// A dropped batch only delays alerts until the next poll.
+func (s *MetricsService) publishAlertResults(results []plugin.Result) {
+	select {
+	case s.alertChan <- results:
+	default:
+		slog.Warn("Alert channel full, dropping poll results", "component", "MetricsService", "count", len(results))
+	}
+}
+
+// publishExportBatch forwards a stored batch to ExportService without blocking.
+// A dropped batch is a gap in the external backend; the metrics table still has it.
+func (s *MetricsService) publishExportBatch(results []plugin.Result, polledAt time.Time) {
+	if s.exportChan == nil {
+		return
+	}
+
+	select {
+	case s.exportChan <- exporter.PollBatch{Results: results, PolledAt: polledAt}:
+	default:
+		slog.Warn("Export channel full, dropping poll results", "component", "MetricsService", "count", len(results))
+	}
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -249,7 +386,26 @@ func (s *MetricsService) handleQuery(ctx context.Context, req models.Request) {
 		return
 	}
 
-	results, err := s.getMetricsBatch(ctx, query.DeviceIDs, query.Query)
+	deviceIDs, err := s.resolveDevices(ctx, query)
+	if err != nil {
+		resp.Error = err
+		req.ReplyCh <- resp
+		return
+	}
+
+	if isMultiPath(query.Query) {
+		resp.Data, resp.Error = s.queryPaths(ctx, deviceIDs, query.Query)
+		req.ReplyCh <- resp
+		return
+	}
+
+	if query.Query.Step != "" {
+		resp.Data, resp.Error = s.getMetricSeries(ctx, deviceIDs, s.withDefaultRange(query.Query))
+		req.ReplyCh <- resp
+		return
+	}
+
+	results, err := s.getMetricsBatch(ctx, deviceIDs, query.Query)
 	if err != nil {
 		resp.Error = err
 	} else {
@@ -259,24 +415,76 @@ func (s *MetricsService) handleQuery(ctx context.Context, req models.Request) {
 	req.ReplyCh <- resp
 }
 
-// getMetricsBatch fetches metrics for multiple devices.
-func (s *MetricsService) getMetricsBatch(ctx context.Context, deviceIDs []int64, query models.MetricQuery) ([]*BatchMetricResult, error) {
-	limit := query.Limit
-	if limit <= 0 {
-		limit = s.defaultLimit
+// resolveDevices merges the requested device IDs with the devices of the discovery profile.
+func (s *MetricsService) resolveDevices(ctx context.Context, query *MetricQueryRequest) ([]int64, error) {
+	if query.DiscoveryProfileID == 0 {
+		return query.DeviceIDs, nil
 	}
 
-	// Default time range if not provided
+	rows, err := s.readDB.QueryContext(ctx, "SELECT id FROM devices WHERE discovery_profile_id = $1 ORDER BY id", query.DiscoveryProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices of discovery profile %d: %w", query.DiscoveryProfileID, err)
+	}
+	defer rows.Close()
+
+	seen := make(map[int64]bool, len(query.DeviceIDs))
+	deviceIDs := make([]int64, 0, len(query.DeviceIDs))
+	for _, id := range query.DeviceIDs {
+		if !seen[id] {
+			seen[id] = true
+			deviceIDs = append(deviceIDs, id)
+		}
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			deviceIDs = append(deviceIDs, id)
+		}
+	}
+	return deviceIDs, rows.Err()
+}
+
+// withDefaultRange fills in a missing end (now) and start (default lookback before end).
+func (s *MetricsService) withDefaultRange(query models.MetricQuery) models.MetricQuery {
 	if query.End.IsZero() {
 		query.End = time.Now()
 	}
 	if query.Start.IsZero() {
 		query.Start = query.End.Add(-time.Duration(s.defaultRangeHours) * time.Hour)
 	}
+	return query
+}
+
+// getMetricsBatch fetches metrics for multiple devices from the raw table or a rollup tier.
+// A device with no rollup rows falls back to raw rows, e.g. for non-numeric paths.
+func (s *MetricsService) getMetricsBatch(ctx context.Context, deviceIDs []int64, query models.MetricQuery) ([]*BatchMetricResult, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = s.defaultLimit
+	}
+
+	// Default time range if not provided
+	query = s.withDefaultRange(query)
 
 	// Validate path to prevent SQL injection
 	if err := validatePath(query.Path); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+	}
+
+	resolution := s.selectResolution(query, limit, time.Now())
+	var tierStmt *sql.Stmt
+	tier, rolledUp := findTier(resolution)
+	if rolledUp {
+		stmt, err := s.prepareTierQuery(ctx, tier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		tierStmt = stmt
 	}
 
 	// Convert dot notation to PG JSONB path array format: cpu.total -> {cpu,total}
@@ -303,6 +511,17 @@ func (s *MetricsService) getMetricsBatch(ctx context.Context, deviceIDs []int64,
 	results := make([]*BatchMetricResult, 0, len(deviceIDs))
 
 	for _, deviceID := range deviceIDs {
+		if rolledUp {
+			tierResults, err := queryTier(ctx, tierStmt, tier, deviceID, query, limit)
+			if err != nil {
+				return nil, err
+			}
+			if len(tierResults) > 0 {
+				results = append(results, &BatchMetricResult{DeviceID: deviceID, Resolution: resolution, Results: tierResults})
+				continue
+			}
+		}
+
 		rows, err := stmt.QueryContext(ctx, deviceID, query.Start, query.End, limit)
 		if err != nil {
 			return nil, fmt.Errorf("query failed for device %d: %w", deviceID, err)
@@ -324,8 +543,9 @@ func (s *MetricsService) getMetricsBatch(ctx context.Context, deviceIDs []int64,
 		}
 
 		results = append(results, &BatchMetricResult{
-			DeviceID: deviceID,
-			Results:  metricResults,
+			DeviceID:   deviceID,
+			Resolution: ResolutionRaw,
+			Results:    metricResults,
 		})
 	}
 
@@ -341,7 +561,7 @@ func validatePath(path string) error {
 		return fmt.Errorf("invalid path: exceeds maximum length of 128 characters")
 	}
 	if !pathValidator.MatchString(path) {
-		return fmt.Errorf("invalid path: must start with a letter and contain only alphanumeric characters, dots, and underscores")
+		return fmt.Errorf("invalid path: segments must start with a letter and contain only alphanumeric characters and underscores, or be *")
 	}
 	return nil
 }
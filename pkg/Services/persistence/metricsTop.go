@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// topMinBuckets is how many rollup buckets a window must span before a tier is used for
+// ranking, so the partial buckets at its edges stay a small share of the value.
+const topMinBuckets = 60
+
+// topAggregates are the aggregates a ranking may use; each has raw and rollup forms.
+var topAggregates = map[string]bool{"avg": true, "min": true, "max": true, "last": true}
+
+// TopMetricEntry is one ranked device. Hostname and IPAddress are filled in from the
+// device cache by the API layer.
+type TopMetricEntry struct {
+	Rank      int     `json:"rank"`
+	DeviceID  int64   `json:"device_id"`
+	Hostname  string  `json:"hostname"`
+	IPAddress string  `json:"ip_address"`
+	Value     float64 `json:"value"`
+}
+
+// TopMetricsResult is a ranking of devices by an aggregated path.
+type TopMetricsResult struct {
+	Path       string            `json:"path"`
+	Aggregate  string            `json:"aggregate"`
+	Window     string            `json:"window"`
+	Order      string            `json:"order"`
+	Resolution string            `json:"resolution"` // Tier the values were computed from
+	Entries    []*TopMetricEntry `json:"entries"`
+}
+
+// handleTop answers a top-N ranking request.
+func (s *MetricsService) handleTop(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	top, ok := req.Payload.(*models.TopMetricsRequest)
+	if !ok {
+		resp.Error = fmt.Errorf("invalid payload for top metrics")
+		req.ReplyCh <- resp
+		return
+	}
+
+	resp.Data, resp.Error = s.topMetrics(ctx, *top, time.Now())
+	req.ReplyCh <- resp
+}
+
+// topMetrics ranks devices by the path aggregated over the window, with one GROUP BY in
+// SQL. Long windows read a rollup tier instead of raw rows; a tier with no rows for the
+// path falls back to raw rows.
+func (s *MetricsService) topMetrics(ctx context.Context, top models.TopMetricsRequest, now time.Time) (*TopMetricsResult, error) {
+	if top.Path == "" || hasWildcard(top.Path) {
+		return nil, fmt.Errorf("%w: ranking needs one concrete path", ErrInvalidMetricQuery)
+	}
+	if err := validatePath(top.Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricQuery, err)
+	}
+	if !topAggregates[top.Aggregate] {
+		return nil, fmt.Errorf("%w: aggregate must be avg, min, max or last", ErrInvalidMetricQuery)
+	}
+	if top.Order != "asc" && top.Order != "desc" {
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrInvalidMetricQuery)
+	}
+
+	result := &TopMetricsResult{
+		Path:       top.Path,
+		Aggregate:  top.Aggregate,
+		Window:     top.Window.String(),
+		Order:      top.Order,
+		Resolution: s.selectTopResolution(top, now),
+	}
+	start := now.Add(-top.Window)
+
+	var err error
+	if tier, ok := findTier(result.Resolution); ok {
+		result.Entries, err = s.queryTop(ctx, tierTopQuery(tier, top), top.N, start, top.N, top.DiscoveryProfileID, top.Path)
+		if err != nil || len(result.Entries) > 0 {
+			return result, err
+		}
+		result.Resolution = ResolutionRaw
+	}
+	result.Entries, err = s.queryTop(ctx, rawTopQuery(top), top.N, start, top.N, top.DiscoveryProfileID)
+	return result, err
+}
+
+// selectTopResolution picks the coarsest tier that still spans topMinBuckets buckets over
+// the window and reaches back to its start. "last" only uses the 1m tier, as coarser tiers
+// lag by up to a bucket, and only needs its newest buckets, so retention doesn't matter;
+// devices silent for longer than the 1m retention drop out of such a ranking.
+func (s *MetricsService) selectTopResolution(top models.TopMetricsRequest, now time.Time) string {
+	start := now.Add(-top.Window)
+	if top.Aggregate == "last" {
+		if top.Window/rollupTiers[0].width >= topMinBuckets {
+			return rollupTiers[0].name
+		}
+		return ResolutionRaw
+	}
+	for i := len(rollupTiers) - 1; i >= 0; i-- {
+		tier := rollupTiers[i]
+		if top.Window/tier.width >= topMinBuckets && s.retention.covers(tier.name, start, now) {
+			return tier.name
+		}
+	}
+	return ResolutionRaw
+}
+
+// rawTopQuery aggregates the numeric samples of the path per device.
+// Parameters: $1 start, $2 N, $3 discovery profile ID (0 for all).
+func rawTopQuery(top models.TopMetricsRequest) string {
+	pgPath := strings.Replace(top.Path, ".", ",", -1)
+	return fmt.Sprintf(`
+		SELECT device_id, %[1]s AS value
+		FROM (
+			SELECT m.device_id, m.timestamp AS ts, (m.data #>> '{%[2]s}')::double precision AS v
+			FROM metrics m
+			JOIN devices d ON d.id = m.device_id
+			WHERE m.timestamp >= $1
+			  AND ($3::bigint = 0 OR d.discovery_profile_id = $3)
+			  AND jsonb_typeof(m.data #> '{%[2]s}') = 'number'
+		) samples
+		GROUP BY device_id
+		ORDER BY value %[3]s, device_id
+		LIMIT $2`,
+		rawAggregates[top.Aggregate], pgPath, strings.ToUpper(top.Order))
+}
+
+// tierTopQuery recombines the rollup buckets of the path per device. The bucket holding
+// the window start is left out, so no sample older than the window counts.
+// Parameters: $1 start, $2 N, $3 discovery profile ID (0 for all), $4 path.
+func tierTopQuery(tier rollupTier, top models.TopMetricsRequest) string {
+	return fmt.Sprintf(`
+		SELECT m.device_id, %[1]s AS value
+		FROM %[2]s m
+		JOIN devices d ON d.id = m.device_id
+		WHERE m.path = $4 AND m.bucket >= $1
+		  AND ($3::bigint = 0 OR d.discovery_profile_id = $3)
+		GROUP BY m.device_id
+		ORDER BY value %[3]s, m.device_id
+		LIMIT $2`,
+		tierAggregates[top.Aggregate], tier.table, strings.ToUpper(top.Order))
+}
+
+// queryTop runs a ranking query and numbers the devices.
+func (s *MetricsService) queryTop(ctx context.Context, sqlQuery string, n int, args ...any) ([]*TopMetricEntry, error) {
+	rows, err := s.readDB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("top query failed: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*TopMetricEntry, 0, n)
+	for rows.Next() {
+		entry := &TopMetricEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(&entry.DeviceID, &entry.Value); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, nil
+}
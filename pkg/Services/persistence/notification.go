@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"text/template"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// statusSeverity maps device statuses to notification severities.
+var statusSeverity = map[string]string{
+	"inactive": "critical",
+	"degraded": "warning",
+	"active":   "info",
+
+	models.FlapStarted: "warning",
+	models.FlapStopped: "info",
+}
+
+// handleChannelCRUD validates channel settings and templates before delegating to the generic handler.
+func (writer *EntityService) handleChannelCRUD(ctx context.Context, req models.Request) models.Response {
+	if channel, ok := req.Payload.(*models.NotificationChannel); ok {
+		if err := validateChannel(channel); err != nil {
+			return models.Response{Error: err}
+		}
+	}
+	return handleCRUD(ctx, req, writer.channelRepo, nil)
+}
+
+// handleRouteCRUD checks the target channel exists before delegating to the generic handler.
+func (writer *EntityService) handleRouteCRUD(ctx context.Context, req models.Request) models.Response {
+	if route, ok := req.Payload.(*models.NotificationRoute); ok {
+		if _, err := writer.channelRepo.Get(ctx, route.ChannelID); err != nil {
+			return models.Response{Error: fmt.Errorf("notification channel %d not found", route.ChannelID)}
+		}
+		for _, kind := range route.Kinds {
+			switch kind {
+			case models.NotificationAlertFiring, models.NotificationAlertResolved, models.NotificationDeviceStatus:
+			default:
+				return models.Response{Error: fmt.Errorf("unknown notification kind %q", kind)}
+			}
+		}
+	}
+	return handleCRUD(ctx, req, writer.routeRepo, nil)
+}
+
+// validateChannel checks the fields each channel type needs and that templates parse.
+func validateChannel(channel *models.NotificationChannel) error {
+	switch channel.Type {
+	case models.ChannelEmail:
+		if len(channel.Recipients) == 0 {
+			return fmt.Errorf("recipients are required for email channels")
+		}
+		for _, recipient := range channel.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid recipient %q", recipient)
+			}
+		}
+	default:
+		parsed, err := url.Parse(channel.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("url must be an http(s) URL for %s channels", channel.Type)
+		}
+	}
+
+	if _, err := template.New("subject").Parse(channel.SubjectTemplate); err != nil {
+		return fmt.Errorf("invalid subject_template: %w", err)
+	}
+	if _, err := template.New("body").Parse(channel.BodyTemplate); err != nil {
+		return fmt.Errorf("invalid body_template: %w", err)
+	}
+	return nil
+}
+
+// notifyStatus tells NotificationService about a device status change.
+func (writer *EntityService) notifyStatus(device *models.Device, reason string) {
+	severity, exists := statusSeverity[device.Status]
+	if !exists {
+		severity = "info"
+	}
+	writer.notifyDevice(device, severity, fmt.Sprintf("Device %s is %s", deviceLabel(device), device.Status), reason)
+}
+
+// notifyDevice sends a device_status notification about a device in its current status.
+func (writer *EntityService) notifyDevice(device *models.Device, severity, title, message string) {
+	go sendEvent(writer.notifications, models.Event{
+		Type: models.EventNotification,
+		Payload: &models.Notification{
+			Kind:      models.NotificationDeviceStatus,
+			Severity:  severity,
+			Title:     title,
+			Message:   message,
+			DeviceID:  device.ID,
+			Hostname:  device.Hostname,
+			IPAddress: device.IPAddress,
+			PluginID:  device.PluginID,
+			Tags:      device.Tags,
+			State:     device.Status,
+			Timestamp: time.Now(),
+		},
+	})
+}
+
+// notifyFlap tells NotificationService that a device started or stopped flapping. The same
+// event switches suppression of the device's notifications, so it is sent as EventFlapUpdate.
+// The send blocks instead of dropping: a lost or reordered stop would mute the device indefinitely.
+// NotificationService never waits on EntityService, so this can't deadlock.
+func (writer *EntityService) notifyFlap(flap *models.DeviceFlap) {
+	writer.cacheMu.RLock()
+	cached, exists := writer.deviceCache[flap.DeviceID]
+	writer.cacheMu.RUnlock()
+	if !exists {
+		return
+	}
+
+	state, message := models.FlapStopped, "stopped flapping, notifications resumed"
+	if flap.Flapping {
+		state, message = models.FlapStarted, "flapping, notifications suppressed until it stabilises"
+	}
+	writer.notifications <- models.Event{
+		Type: models.EventFlapUpdate,
+		Payload: &models.Notification{
+			Kind:      models.NotificationDeviceStatus,
+			Severity:  statusSeverity[state],
+			Title:     fmt.Sprintf("Device %s is %s", deviceLabel(cached), state),
+			Message:   fmt.Sprintf("%s (flap score %.1f)", message, flap.Score),
+			DeviceID:  cached.ID,
+			Hostname:  cached.Hostname,
+			IPAddress: cached.IPAddress,
+			PluginID:  cached.PluginID,
+			Tags:      cached.Tags,
+			State:     state,
+			Timestamp: flap.Since,
+		},
+	}
+}
+
+// deviceLabel names a device by hostname, falling back to its address.
+func deviceLabel(device *models.Device) string {
+	if device.Hostname != "" {
+		return device.Hostname
+	}
+	return device.IPAddress
+}
@@ -0,0 +1,131 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// Resolutions accepted in MetricQuery.Resolution
+const (
+	ResolutionAuto = "auto"
+	ResolutionRaw  = "raw"
+)
+
+// rollupTier is one level of pre-aggregated metrics, built from the tier below it.
+type rollupTier struct {
+	name     string        // Resolution name, also the metric_rollup_state key
+	table    string        // Rollup table
+	width    time.Duration // Bucket width
+	interval string        // Bucket width as a Postgres interval
+	source   string        // Table the tier is rolled up from
+	chunk    time.Duration // Most source time rolled up per statement
+}
+
+// rollupTiers are ordered finest first; each rolls up the previous one.
+var rollupTiers = []rollupTier{
+	{name: "1m", table: "metrics_1m", width: time.Minute, interval: "1 minute", source: "metrics", chunk: time.Hour},
+	{name: "1h", table: "metrics_1h", width: time.Hour, interval: "1 hour", source: "metrics_1m", chunk: 24 * time.Hour},
+	{name: "1d", table: "metrics_1d", width: 24 * time.Hour, interval: "1 day", source: "metrics_1h", chunk: 30 * 24 * time.Hour},
+}
+
+// RollupRetention is how many days each storage tier keeps, 0 keeps forever.
+type RollupRetention struct {
+	Raw    int
+	Minute int
+	Hour   int
+	Day    int
+}
+
+// days returns the retention of a tier by resolution name.
+func (r RollupRetention) days(resolution string) int {
+	switch resolution {
+	case "1m":
+		return r.Minute
+	case "1h":
+		return r.Hour
+	case "1d":
+		return r.Day
+	}
+	return r.Raw
+}
+
+// covers reports whether a tier still holds data from start.
+func (r RollupRetention) covers(resolution string, start, now time.Time) bool {
+	days := r.days(resolution)
+	return days == 0 || !start.Before(now.AddDate(0, 0, -days))
+}
+
+// findTier returns the rollup tier with the given resolution name.
+func findTier(resolution string) (rollupTier, bool) {
+	for _, tier := range rollupTiers {
+		if tier.name == resolution {
+			return tier, true
+		}
+	}
+	return rollupTier{}, false
+}
+
+// selectResolution picks the storage tier for a query. In auto mode it is the finest tier
+// whose buckets are at least range/limit wide, so the whole range fits in limit points,
+// moving coarser while the tier's retention doesn't reach back to the start.
+// Object paths are never rolled up, so an empty path always reads raw rows.
+func (s *MetricsService) selectResolution(query models.MetricQuery, limit int, now time.Time) string {
+	if query.Resolution != "" && query.Resolution != ResolutionAuto {
+		return query.Resolution
+	}
+	if query.Path == "" {
+		return ResolutionRaw
+	}
+
+	spacing := query.End.Sub(query.Start) / time.Duration(limit)
+	if spacing < rollupTiers[0].width && s.retention.covers(ResolutionRaw, query.Start, now) {
+		return ResolutionRaw
+	}
+	for _, tier := range rollupTiers {
+		if tier.width >= spacing && s.retention.covers(tier.name, query.Start, now) {
+			return tier.name
+		}
+	}
+	return rollupTiers[len(rollupTiers)-1].name // Coarsest tier keeps the most history
+}
+
+// prepareTierQuery prepares the bucket query of a rollup tier.
+// Value carries the bucket average; Min/Max/Count/Last carry the rest of the rollup.
+func (s *MetricsService) prepareTierQuery(ctx context.Context, tier rollupTier) (*sql.Stmt, error) {
+	return s.readDB.PrepareContext(ctx, fmt.Sprintf(`
+		SELECT bucket, to_jsonb(avg), min, max, count, last
+		FROM %s
+		WHERE device_id = $1 AND path = $2
+		  AND bucket > $3 AND bucket <= $4
+		ORDER BY bucket DESC
+		LIMIT $5`, tier.table))
+}
+
+// queryTier reads one device's buckets. The bucket containing start is included.
+func queryTier(ctx context.Context, stmt *sql.Stmt, tier rollupTier, deviceID int64, query models.MetricQuery, limit int) ([]*MetricResult, error) {
+	rows, err := stmt.QueryContext(ctx, deviceID, query.Path, query.Start.Add(-tier.width), query.End, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed for device %d: %w", deviceID, err)
+	}
+	defer rows.Close()
+
+	var results []*MetricResult
+	for rows.Next() {
+		mr := &MetricResult{}
+		var min, max, last float64
+		var count int64
+		if err := rows.Scan(&mr.Timestamp, &mr.Value, &min, &max, &count, &last); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		mr.Min, mr.Max, mr.Count, mr.Last = &min, &max, &count, &last
+		results = append(results, mr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,212 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// rollupLag keeps the newest minutes out of the 1m tier until their poll batches have landed.
+const rollupLag = 2 * time.Minute
+
+// bucketOrigin aligns date_bin buckets to UTC midnight, matching time.Truncate.
+const bucketOrigin = "TIMESTAMPTZ '2000-01-01 00:00:00+00'"
+
+// keyPattern is one concrete path segment; rolled-up and wildcard-expanded keys must match it.
+const keyPattern = `^[a-zA-Z][a-zA-Z0-9_]{0,31}$`
+
+// rollupRawQuery aggregates every numeric leaf of raw metrics into 1-minute buckets.
+// The CASE guards keep jsonb_each away from non-object values.
+var rollupRawQuery = fmt.Sprintf(`
+	WITH RECURSIVE leaves(device_id, ts, path, value) AS (
+		SELECT m.device_id, m.timestamp, e.key, e.value
+		FROM metrics m
+		CROSS JOIN LATERAL jsonb_each(CASE WHEN jsonb_typeof(m.data) = 'object' THEN m.data ELSE '{}'::jsonb END) e
+		WHERE m.timestamp >= $1 AND m.timestamp < $2 AND e.key ~ '%[1]s'
+	UNION ALL
+		SELECT l.device_id, l.ts, l.path || '.' || e.key, e.value
+		FROM leaves l
+		CROSS JOIN LATERAL jsonb_each(CASE WHEN jsonb_typeof(l.value) = 'object' THEN l.value ELSE '{}'::jsonb END) e
+		WHERE e.key ~ '%[1]s' AND length(l.path) < 128
+	)
+	INSERT INTO metrics_1m (device_id, path, bucket, min, max, avg, count, last, last_at)
+	SELECT device_id, path, date_bin('1 minute', ts, %[2]s) AS b,
+		MIN(v), MAX(v), AVG(v), COUNT(*), (ARRAY_AGG(v ORDER BY ts DESC))[1], MAX(ts)
+	FROM (
+		SELECT device_id, ts, path, (value #>> '{}')::double precision AS v
+		FROM leaves
+		WHERE jsonb_typeof(value) = 'number'
+	) numeric
+	GROUP BY device_id, path, b
+	ON CONFLICT (device_id, path, bucket) DO UPDATE SET
+		min = EXCLUDED.min, max = EXCLUDED.max, avg = EXCLUDED.avg,
+		count = EXCLUDED.count, last = EXCLUDED.last, last_at = EXCLUDED.last_at`,
+	keyPattern, bucketOrigin)
+
+// rollupTierQuery aggregates the buckets of one tier into the next coarser one.
+const rollupTierQuery = `
+	INSERT INTO %[1]s (device_id, path, bucket, min, max, avg, count, last, last_at)
+	SELECT device_id, path, date_bin('%[3]s', bucket, %[4]s) AS b,
+		MIN(min), MAX(max), SUM(avg * count) / SUM(count), SUM(count),
+		(ARRAY_AGG(last ORDER BY last_at DESC))[1], MAX(last_at)
+	FROM %[2]s
+	WHERE bucket >= $1 AND bucket < $2
+	GROUP BY device_id, path, b
+	ON CONFLICT (device_id, path, bucket) DO UPDATE SET
+		min = EXCLUDED.min, max = EXCLUDED.max, avg = EXCLUDED.avg,
+		count = EXCLUDED.count, last = EXCLUDED.last, last_at = EXCLUDED.last_at`
+
+// RollupService periodically aggregates numeric metric leaves into the 1m, 1h and 1d
+// tables and prunes each tier past its retention. Progress per tier is kept in
+// metric_rollup_state, so a restart resumes where the last run stopped.
+type RollupService struct {
+	db        *sql.DB
+	interval  time.Duration
+	retention RollupRetention
+}
+
+// NewRollupService creates a new RollupService instance.
+func NewRollupService(db *sql.DB, intervalSec int, retention RollupRetention) *RollupService {
+	return &RollupService{
+		db:        db,
+		interval:  time.Duration(intervalSec) * time.Second,
+		retention: retention,
+	}
+}
+
+// Run starts the rollup service's main loop.
+func (svc *RollupService) Run(ctx context.Context) {
+	slog.Info("Starting rollup service", "component", "RollupService", "interval", svc.interval)
+
+	ticker := time.NewTicker(svc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping rollup service", "component", "RollupService")
+			return
+		case now := <-ticker.C:
+			svc.rollupAll(ctx, now)
+			svc.prune(ctx, now)
+		}
+	}
+}
+
+// rollupAll brings every tier up to date. Each tier can only advance as far as
+// the tier it reads from is complete.
+func (svc *RollupService) rollupAll(ctx context.Context, now time.Time) {
+	complete := now.Add(-rollupLag) // Raw rows are complete up to here
+	for _, tier := range rollupTiers {
+		end := complete.Truncate(tier.width)
+		until, err := svc.rollupTier(ctx, tier, end)
+		if err != nil {
+			slog.Error("Rollup failed", "component", "RollupService", "tier", tier.name, "error", err)
+			return
+		}
+		complete = until
+	}
+}
+
+// rollupTier aggregates the source of a tier from its watermark up to end, one chunk per
+// transaction, and returns how far the tier is complete.
+func (svc *RollupService) rollupTier(ctx context.Context, tier rollupTier, end time.Time) (time.Time, error) {
+	from, err := svc.watermark(ctx, tier)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if from.IsZero() {
+		return end, nil // No source data yet
+	}
+
+	query := rollupRawQuery
+	if tier.source != "metrics" {
+		query = fmt.Sprintf(rollupTierQuery, tier.table, tier.source, tier.interval, bucketOrigin)
+	}
+
+	rolled := 0
+	for from.Before(end) && ctx.Err() == nil {
+		to := from.Add(tier.chunk)
+		if to.After(end) {
+			to = end
+		}
+		if err := svc.rollupChunk(ctx, tier, query, from, to); err != nil {
+			return from, err
+		}
+		from = to
+		rolled++
+	}
+	if rolled > 0 {
+		slog.Debug("Rolled up metrics", "component", "RollupService", "tier", tier.name, "chunks", rolled, "until", from)
+	}
+	return from, nil
+}
+
+// rollupChunk aggregates [from, to) and advances the watermark in one transaction,
+// so a failed chunk is simply redone on the next run.
+func (svc *RollupService) rollupChunk(ctx context.Context, tier rollupTier, query string, from, to time.Time) error {
+	tx, err := svc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, from, to); err != nil {
+		return fmt.Errorf("failed to roll up %s from %s: %w", tier.name, from.Format(time.RFC3339), err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO metric_rollup_state (tier, rolled_until, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (tier) DO UPDATE SET rolled_until = EXCLUDED.rolled_until, updated_at = NOW()`,
+		tier.name, to)
+	if err != nil {
+		return fmt.Errorf("failed to record %s rollup progress: %w", tier.name, err)
+	}
+	return tx.Commit()
+}
+
+// watermark returns where a tier's next rollup starts: its recorded progress, or the
+// oldest source data for a new tier. Zero means there is nothing to roll up yet.
+func (svc *RollupService) watermark(ctx context.Context, tier rollupTier) (time.Time, error) {
+	var until time.Time
+	err := svc.db.QueryRowContext(ctx, "SELECT rolled_until FROM metric_rollup_state WHERE tier = $1", tier.name).Scan(&until)
+	if err == nil {
+		return until, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("failed to read %s rollup progress: %w", tier.name, err)
+	}
+
+	column := "bucket"
+	if tier.source == "metrics" {
+		column = "timestamp"
+	}
+	var oldest sql.NullTime
+	if err := svc.db.QueryRowContext(ctx, fmt.Sprintf("SELECT MIN(%s) FROM %s", column, tier.source)).Scan(&oldest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to find oldest %s data: %w", tier.source, err)
+	}
+	if !oldest.Valid {
+		return time.Time{}, nil
+	}
+	return oldest.Time.Truncate(tier.width), nil
+}
+
+// prune deletes rollup buckets older than their tier's retention.
+func (svc *RollupService) prune(ctx context.Context, now time.Time) {
+	for _, tier := range rollupTiers {
+		days := svc.retention.days(tier.name)
+		if days == 0 {
+			continue
+		}
+		result, err := svc.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE bucket < $1", tier.table), now.AddDate(0, 0, -days))
+		if err != nil {
+			slog.Error("Failed to prune rollups", "component", "RollupService", "tier", tier.name, "error", err)
+			continue
+		}
+		if deleted, _ := result.RowsAffected(); deleted > 0 {
+			slog.Debug("Pruned rollups", "component", "RollupService", "tier", tier.name, "rows", deleted)
+		}
+	}
+}
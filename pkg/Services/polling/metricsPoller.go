@@ -2,11 +2,16 @@ package polling
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"nms/pkg/api"
+	"nms/pkg/fping"
 	"nms/pkg/models"
 	"nms/pkg/plugin"
 	"nms/pkg/pluginWorker"
@@ -25,8 +30,14 @@ type Poller struct {
 	// Input channel: receives batches of devices from scheduler
 	InputChan <-chan []*models.Device
 
+	// On-demand poll requests from the API
+	requests <-chan models.Request
+
 	// Output channel: sends aggregated poll results
 	OutputChan chan<- []plugin.Result
+
+	// Availability check for on-demand polls
+	pinger *fping.Pinger
 }
 
 // NewPoller creates a new Poller instance.
@@ -38,6 +49,9 @@ func NewPoller(
 	entityReqChan chan<- models.Request,
 	inputChan <-chan []*models.Device,
 	outputChan chan<- []plugin.Result,
+	requests <-chan models.Request,
+	fpingPath string,
+	fpingTimeoutMs, fpingRetries int,
 ) *Poller {
 	pool := pluginWorker.NewPool[plugin.Task, plugin.Result](workerCount, "PollPool", bufferSize)
 
@@ -49,6 +63,8 @@ func NewPoller(
 		entityReqChan: entityReqChan,
 		InputChan:     inputChan,
 		OutputChan:    outputChan,
+		requests:      requests,
+		pinger:        fping.NewPinger(fpingPath, fpingTimeoutMs, fpingRetries),
 	}
 	p.loadPlugins()
 	return p
@@ -119,8 +135,134 @@ func (poller *Poller) Run(ctx context.Context) {
 				tasks := poller.createTasks(deviceList)
 				poller.pool.Submit(binPath, tasks)
 			}
+
+		case req := <-poller.requests:
+			// Handled off the main loop so scheduled batches keep flowing
+			go poller.handleRequest(req)
+		}
+	}
+}
+
+// handleRequest answers on-demand requests from the API.
+func (poller *Poller) handleRequest(req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpPollNow:
+		opts, _ := req.Payload.(*models.PollNowRequest)
+		if opts == nil {
+			opts = &models.PollNowRequest{}
+		}
+		resp.Data, resp.Error = poller.pollNow(req.ID, opts.Store, opts.Timeout)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// pollNow runs availability and the device's plugin immediately, bypassing the deadline queue.
+// The plugin runs through the shared pool as a synchronous job; waiting for it stops after timeout (if set).
+// A failed plugin run without results returns the report along with ErrPluginFailed.
+func (poller *Poller) pollNow(deviceID int64, store bool, timeout time.Duration) (*models.PollNowResponse, error) {
+	device, err := poller.getDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	binPath, exists := poller.plugins[device.PluginID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q for device %d", models.ErrPluginNotFound, device.PluginID, deviceID)
+	}
+
+	report := &models.PollNowResponse{
+		DeviceID:  deviceID,
+		PluginID:  device.PluginID,
+		Reachable: true,
+		PolledAt:  time.Now(),
+	}
+
+	// 1. Availability (reported, but the plugin still runs so operators see its output)
+	if device.ShouldPing {
+		start := time.Now()
+		report.PingChecked = true
+		report.Reachable = poller.pinger.Check([]string{device.IPAddress})[device.IPAddress]
+		report.PingDurationMs = time.Since(start).Milliseconds()
+	}
+
+	// 2. Plugin
+	tasks := poller.createTasks([]*models.Device{device})
+	jobCh := make(chan pluginWorker.JobResult[plugin.Result], 1)
+	go func() {
+		// Enqueueing can block while the pool is busy, so it happens off this goroutine too
+		jobCh <- <-poller.pool.SubmitSync(binPath, tasks)
+	}()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var job pluginWorker.JobResult[plugin.Result]
+	select {
+	case job = <-jobCh:
+	case <-deadline:
+		return nil, fmt.Errorf("%w: plugin %q on device %d did not finish within %s", models.ErrPollTimeout, device.PluginID, deviceID, timeout)
+	}
+	report.PluginDurationMs = job.Duration.Milliseconds()
+	report.Stderr = job.Stderr
+	if job.Err != nil {
+		report.Error = job.Err.Error()
+	}
+
+	for i := range job.Results {
+		if job.Results[i].DeviceID == deviceID || report.Result == nil {
+			report.Result = &job.Results[i]
 		}
 	}
+	if report.Result != nil && report.Result.DeviceID == 0 {
+		report.Result.DeviceID = deviceID // Plugin did not echo the ID back
+	}
+	if job.Err != nil && report.Result == nil {
+		return report, fmt.Errorf("%w: %v", models.ErrPluginFailed, job.Err)
+	}
+
+	// 3. Optionally persist through MetricsService
+	if store && report.Result != nil {
+		poller.OutputChan <- []plugin.Result{*report.Result}
+		report.Stored = true
+	}
+
+	slog.Info("On-demand poll complete", "component", "Poller", "device_id", deviceID,
+		"reachable", report.Reachable, "plugin_duration_ms", report.PluginDurationMs, "stored", report.Stored)
+	return report, nil
+}
+
+// getDevice fetches a device by ID from EntityService.
+func (poller *Poller) getDevice(deviceID int64) (*models.Device, error) {
+	replyCh := make(chan models.Response, 1)
+	poller.entityReqChan <- models.Request{
+		Operation:  models.OpGet,
+		EntityType: "Device",
+		ID:         deviceID,
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	if errors.Is(resp.Error, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", models.ErrDeviceNotFound, deviceID)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("failed to get device %d: %w", deviceID, resp.Error)
+	}
+
+	device, ok := resp.Data.(*models.Device)
+	if !ok {
+		return nil, fmt.Errorf("invalid device response type")
+	}
+	return device, nil
 }
 
 // groupByProtocol groups devices by their PluginID.
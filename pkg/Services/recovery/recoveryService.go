@@ -0,0 +1,264 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/api"
+	"nms/pkg/fping"
+	"nms/pkg/models"
+	"nms/pkg/plugin"
+	"nms/pkg/pluginWorker"
+)
+
+// RecoveryService periodically health-checks inactive devices and reactivates
+// the ones that pass enough consecutive checks. Devices deactivated through the API
+// (maintenance, decommissioning) are never probed.
+// A check is a ping (for should_ping devices) followed by a lightweight plugin probe
+// (the plugin's -discovery mode), so a device must answer at the protocol level to recover.
+type RecoveryService struct {
+	pool          *pluginWorker.PluginWorkerPool[plugin.Task, plugin.Result]
+	pinger        *fping.Pinger
+	pluginDir     string
+	encryptionKey string
+
+	// Request channel to EntityService for device/credential lookups and reactivation
+	entityReqChan chan<- models.Request
+
+	// Consecutive successful checks per inactive device (owned by the Run goroutine)
+	streaks map[int64]int
+
+	// Config
+	interval         time.Duration
+	successThreshold int
+}
+
+// NewRecoveryService creates a new RecoveryService instance.
+func NewRecoveryService(
+	entityReqChan chan<- models.Request,
+	pluginDir string,
+	encryptionKey string,
+	fpingPath string,
+	fpingTimeoutMs, fpingRetries int,
+	workerCount int,
+	bufferSize int,
+	intervalSec int,
+	successThreshold int,
+) *RecoveryService {
+	pool := pluginWorker.NewPool[plugin.Task, plugin.Result](workerCount, "RecoveryPool", bufferSize, "-discovery")
+	return &RecoveryService{
+		pool:             pool,
+		pinger:           fping.NewPinger(fpingPath, fpingTimeoutMs, fpingRetries),
+		pluginDir:        pluginDir,
+		encryptionKey:    encryptionKey,
+		entityReqChan:    entityReqChan,
+		streaks:          make(map[int64]int),
+		interval:         time.Duration(intervalSec) * time.Second,
+		successThreshold: successThreshold,
+	}
+}
+
+// Run starts the recovery loop.
+func (recovery *RecoveryService) Run(ctx context.Context) {
+	slog.Info("Starting recovery service", "component", "RecoveryService", "interval", recovery.interval.String(), "success_threshold", recovery.successThreshold)
+
+	recovery.pool.Start(ctx)
+
+	ticker := time.NewTicker(recovery.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping recovery service", "component", "RecoveryService")
+			return
+
+		case <-ticker.C:
+			recovery.checkInactive()
+
+		case results, ok := <-recovery.pool.Results():
+			if !ok {
+				return
+			}
+			for _, res := range results {
+				recovery.handleProbeResult(res)
+			}
+		}
+	}
+}
+
+// checkInactive pings inactive devices and submits plugin probes for the reachable ones.
+func (recovery *RecoveryService) checkInactive() {
+	devices := recovery.getInactiveDevices()
+
+	// Forget streaks of devices that are no longer inactive (deleted or reactivated elsewhere)
+	inactive := make(map[int64]bool, len(devices))
+	for _, dev := range devices {
+		inactive[dev.ID] = true
+	}
+	for id := range recovery.streaks {
+		if !inactive[id] {
+			delete(recovery.streaks, id)
+		}
+	}
+
+	if len(devices) == 0 {
+		slog.Debug("No inactive devices to probe", "component", "RecoveryService")
+		return
+	}
+
+	// 1. Ping devices that require it
+	ips := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		if dev.ShouldPing {
+			ips = append(ips, dev.IPAddress)
+		}
+	}
+	reachable := recovery.pinger.Check(ips)
+
+	// 2. Group reachable devices by plugin for probing
+	grouped := make(map[string][]*models.Device)
+	for _, dev := range devices {
+		if dev.ShouldPing && !reachable[dev.IPAddress] {
+			recovery.resetStreak(dev.ID, "ping")
+			continue
+		}
+		grouped[dev.PluginID] = append(grouped[dev.PluginID], dev)
+	}
+
+	// 3. Submit probes
+	for pluginID, deviceList := range grouped {
+		binPath, err := pluginWorker.ResolveBinary(recovery.pluginDir, pluginID)
+		if err != nil {
+			slog.Error("Plugin not found for recovery probe", "component", "RecoveryService", "plugin_id", pluginID, "error", err)
+			continue
+		}
+		recovery.pool.Submit(binPath, recovery.createTasks(deviceList))
+	}
+
+	slog.Info("Recovery probes submitted", "component", "RecoveryService", "inactive", len(devices), "reachable", len(reachable))
+}
+
+// handleProbeResult updates the device's success streak and reactivates it at the threshold.
+func (recovery *RecoveryService) handleProbeResult(res plugin.Result) {
+	if !res.Success {
+		recovery.resetStreak(res.DeviceID, "probe")
+		return
+	}
+
+	recovery.streaks[res.DeviceID]++
+	streak := recovery.streaks[res.DeviceID]
+	slog.Debug("Recovery check passed", "component", "RecoveryService", "device_id", res.DeviceID, "streak", streak, "threshold", recovery.successThreshold)
+
+	if streak < recovery.successThreshold {
+		return
+	}
+
+	delete(recovery.streaks, res.DeviceID)
+	reason := fmt.Sprintf("recovered after %d consecutive successful checks (ping + plugin probe)", streak)
+	recovery.activateDevice(res.DeviceID, reason)
+}
+
+// resetStreak clears a device's success streak after a failed check.
+func (recovery *RecoveryService) resetStreak(deviceID int64, check string) {
+	if recovery.streaks[deviceID] > 0 {
+		slog.Debug("Recovery streak reset", "component", "RecoveryService", "device_id", deviceID, "failed_check", check)
+	}
+	delete(recovery.streaks, deviceID)
+}
+
+// getInactiveDevices fetches the recoverable inactive devices from EntityService cache.
+func (recovery *RecoveryService) getInactiveDevices() []*models.Device {
+	replyCh := make(chan models.Response, 1)
+	recovery.entityReqChan <- models.Request{
+		Operation: models.OpGetInactiveDevices,
+		ReplyCh:   replyCh,
+	}
+
+	resp := <-replyCh
+	if resp.Error != nil {
+		slog.Error("Failed to get inactive devices", "component", "RecoveryService", "error", resp.Error)
+		return nil
+	}
+
+	devices, ok := resp.Data.([]*models.Device)
+	if !ok {
+		slog.Error("Invalid inactive devices response type", "component", "RecoveryService")
+		return nil
+	}
+	return devices
+}
+
+// createTasks converts devices to probe tasks, fetching credentials from EntityService.
+func (recovery *RecoveryService) createTasks(devices []*models.Device) []plugin.Task {
+	tasks := make([]plugin.Task, 0, len(devices))
+	credCache := make(map[int64]*models.CredentialProfile)
+
+	for _, d := range devices {
+		cred, exists := credCache[d.CredentialProfileID]
+		if !exists {
+			cred = recovery.getCredential(d.CredentialProfileID)
+			credCache[d.CredentialProfileID] = cred
+		}
+
+		payload, err := api.DecryptPayload(cred, recovery.encryptionKey)
+		if err != nil {
+			slog.Error("Failed to decrypt credentials", "component", "RecoveryService", "device_id", d.ID, "error", err)
+			payload = nil // Probe will fail and reset the streak
+		}
+
+		tasks = append(tasks, plugin.Task{
+			DeviceID:    d.ID,
+			Target:      d.IPAddress,
+			Port:        d.Port,
+			Credentials: payload,
+		})
+	}
+	return tasks
+}
+
+// getCredential fetches a credential from EntityService cache.
+func (recovery *RecoveryService) getCredential(profileID int64) *models.CredentialProfile {
+	replyCh := make(chan models.Response, 1)
+	recovery.entityReqChan <- models.Request{
+		Operation: models.OpGetCredential,
+		ID:        profileID,
+		ReplyCh:   replyCh,
+	}
+
+	resp := <-replyCh
+	if resp.Error != nil {
+		slog.Error("Failed to get credential", "component", "RecoveryService", "profile_id", profileID, "error", resp.Error)
+		return nil
+	}
+
+	cred, ok := resp.Data.(*models.CredentialProfile)
+	if !ok {
+		return nil
+	}
+	return cred
+}
+
+// activateDevice asks EntityService to transition the device back to active.
+func (recovery *RecoveryService) activateDevice(deviceID int64, reason string) {
+	replyCh := make(chan models.Response, 1)
+	recovery.entityReqChan <- models.Request{
+		Operation:  models.OpActivateDevice,
+		EntityType: "Device",
+		ID:         deviceID,
+		Payload:    reason,
+		ReplyCh:    replyCh,
+	}
+
+	// Wait for response without holding up probe handling
+	go func() {
+		resp := <-replyCh
+		if resp.Error != nil {
+			slog.Error("Failed to reactivate device", "component", "RecoveryService", "device_id", deviceID, "error", resp.Error)
+			return
+		}
+		slog.Info("Device reactivated", "component", "RecoveryService", "device_id", deviceID, "reason", reason)
+	}()
+}
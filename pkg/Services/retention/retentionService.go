@@ -0,0 +1,278 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Partition intervals
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// listPartitionsQuery reads the bounds of every metrics partition back from the catalog.
+// Bounds are cast by Postgres itself, so the session time zone doesn't matter.
+const listPartitionsQuery = `
+	SELECT c.relname AS name,
+		(regexp_match(pg_get_expr(c.relpartbound, c.oid), 'FROM \(''([^'']+)''\)'))[1]::timestamptz AS range_start,
+		(regexp_match(pg_get_expr(c.relpartbound, c.oid), 'TO \(''([^'']+)''\)'))[1]::timestamptz AS range_end,
+		pg_total_relation_size(c.oid) AS total_bytes,
+		GREATEST(c.reltuples, 0)::bigint AS estimated_rows
+	FROM pg_inherits i
+	JOIN pg_class c ON c.oid = i.inhrelid
+	WHERE i.inhparent = 'metrics'::regclass
+	ORDER BY range_start NULLS FIRST`
+
+// RetentionService manages the partitions of the metrics table: it creates upcoming
+// partitions ahead of time, drops partitions older than the retention age and deletes
+// rows of discovery profiles with a shorter retention.
+type RetentionService struct {
+	requests <-chan models.Request // Input: partition report requests from the API
+
+	db *sqlx.DB
+
+	interval      string
+	premake       int // Partitions kept ready beyond the current one
+	retentionDays int // Global retention, 0 keeps everything
+	checkInterval time.Duration
+}
+
+// NewRetentionService creates a new RetentionService instance.
+func NewRetentionService(
+	requests <-chan models.Request,
+	db *sqlx.DB,
+	interval string,
+	premake int,
+	retentionDays int,
+	checkIntervalMin int,
+) *RetentionService {
+	return &RetentionService{
+		requests:      requests,
+		db:            db,
+		interval:      interval,
+		premake:       premake,
+		retentionDays: retentionDays,
+		checkInterval: time.Duration(checkIntervalMin) * time.Minute,
+	}
+}
+
+// EnsurePartitions creates the current and upcoming partitions.
+// Call it before metrics are written: rows without a partition are rejected.
+func (svc *RetentionService) EnsurePartitions(ctx context.Context) error {
+	partitions, err := svc.listPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	start := svc.periodStart(now)
+	horizon := start
+	for i := 0; i <= svc.premake; i++ {
+		horizon = svc.nextPeriod(horizon)
+	}
+
+	created := 0
+	for _, r := range missingRanges(partitions, start, horizon, svc.nextPeriod) {
+		name := "metrics_p" + r.start.Format("20060102")
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF metrics FOR VALUES FROM ('%s') TO ('%s')",
+			name, r.start.Format(time.RFC3339), r.end.Format(time.RFC3339))
+		if _, err := svc.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+		created++
+	}
+	if created > 0 {
+		slog.Info("Created metrics partitions", "component", "RetentionService", "count", created, "interval", svc.interval)
+	}
+	return nil
+}
+
+// Run starts the retention service's main loop.
+func (svc *RetentionService) Run(ctx context.Context) {
+	slog.Info("Starting retention service", "component", "RetentionService",
+		"interval", svc.interval, "retention_days", svc.retentionDays)
+
+	svc.maintain(ctx)
+
+	ticker := time.NewTicker(svc.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping retention service", "component", "RetentionService")
+			return
+		case <-ticker.C:
+			svc.maintain(ctx)
+		case req := <-svc.requests:
+			// Reports only read the catalog, so they don't hold up maintenance
+			go svc.handleRequest(ctx, req)
+		}
+	}
+}
+
+// maintain creates upcoming partitions and applies retention.
+func (svc *RetentionService) maintain(ctx context.Context) {
+	if err := svc.EnsurePartitions(ctx); err != nil {
+		slog.Error("Failed to create metrics partitions", "component", "RetentionService", "error", err)
+	}
+	if err := svc.applyRetention(ctx, time.Now()); err != nil {
+		slog.Error("Failed to apply metrics retention", "component", "RetentionService", "error", err)
+	}
+}
+
+// applyRetention drops whole partitions past the longest retention in use, then deletes
+// older rows of devices whose discovery profile (or the global setting) keeps less.
+func (svc *RetentionService) applyRetention(ctx context.Context, now time.Time) error {
+	var profiles []struct {
+		ID   int64 `db:"id"`
+		Days int   `db:"metrics_retention_days"`
+	}
+	if err := svc.db.SelectContext(ctx, &profiles, "SELECT id, metrics_retention_days FROM discovery_profiles"); err != nil {
+		return fmt.Errorf("failed to read profile retention: %w", err)
+	}
+
+	// A partition can only go once no profile still needs its rows
+	longest := svc.retentionDays
+	for _, profile := range profiles {
+		if profile.Days == 0 && svc.retentionDays == 0 {
+			return nil // Some devices keep metrics forever
+		}
+		if profile.Days > longest {
+			longest = profile.Days
+		}
+	}
+	if longest == 0 {
+		return nil
+	}
+
+	partitions, err := svc.listPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	cutoff := now.AddDate(0, 0, -longest)
+	for _, partition := range partitions {
+		if partition.RangeEnd == nil || partition.RangeEnd.After(cutoff) {
+			continue
+		}
+		if _, err := svc.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", partition.Name)); err != nil {
+			return fmt.Errorf("failed to drop partition %s: %w", partition.Name, err)
+		}
+		slog.Info("Dropped metrics partition", "component", "RetentionService",
+			"partition", partition.Name, "range_end", partition.RangeEnd, "bytes", partition.TotalBytes)
+	}
+
+	for _, profile := range profiles {
+		days := profile.Days
+		if days == 0 {
+			days = svc.retentionDays
+		}
+		if days >= longest {
+			continue // Covered by the partition drop
+		}
+		result, err := svc.db.ExecContext(ctx, `
+			DELETE FROM metrics m USING devices d
+			WHERE m.device_id = d.id AND d.discovery_profile_id = $1 AND m.timestamp < $2`,
+			profile.ID, now.AddDate(0, 0, -days))
+		if err != nil {
+			return fmt.Errorf("failed to prune metrics of discovery profile %d: %w", profile.ID, err)
+		}
+		if deleted, _ := result.RowsAffected(); deleted > 0 {
+			slog.Info("Pruned metrics", "component", "RetentionService", "profile_id", profile.ID, "retention_days", days, "rows", deleted)
+		}
+	}
+	return nil
+}
+
+// handleRequest answers partition report requests.
+func (svc *RetentionService) handleRequest(ctx context.Context, req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpListPartitions:
+		partitions, err := svc.listPartitions(ctx)
+		if err != nil {
+			resp.Error = err
+			break
+		}
+		report := &models.PartitionReport{Interval: svc.interval, RetentionDays: svc.retentionDays, Partitions: partitions}
+		for _, partition := range partitions {
+			report.TotalBytes += partition.TotalBytes
+		}
+		resp.Data = report
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// listPartitions returns the metrics partitions ordered by range start.
+func (svc *RetentionService) listPartitions(ctx context.Context) ([]*models.MetricPartition, error) {
+	partitions := make([]*models.MetricPartition, 0)
+	if err := svc.db.SelectContext(ctx, &partitions, listPartitionsQuery); err != nil {
+		return nil, fmt.Errorf("failed to list metrics partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// periodStart returns the start of the partition period containing t (UTC; weeks start on Monday).
+func (svc *RetentionService) periodStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if svc.interval == IntervalWeek {
+		offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// nextPeriod returns the first period boundary after t.
+func (svc *RetentionService) nextPeriod(t time.Time) time.Time {
+	if svc.interval == IntervalWeek {
+		return svc.periodStart(t).AddDate(0, 0, 7)
+	}
+	return svc.periodStart(t).AddDate(0, 0, 1)
+}
+
+// timeRange is a half-open [start, end) range.
+type timeRange struct {
+	start, end time.Time
+}
+
+// missingRanges returns the parts of [start, horizon) not covered by existing partitions,
+// split at period boundaries. Ranges left over from a different interval are filled around,
+// so changing METRICS_PARTITION_INTERVAL never leaves gaps or overlaps.
+func missingRanges(partitions []*models.MetricPartition, start, horizon time.Time, next func(time.Time) time.Time) []timeRange {
+	var missing []timeRange
+	cursor := start
+	for cursor.Before(horizon) {
+		end := next(cursor)
+		covered := false
+		for _, p := range partitions {
+			if p.RangeEnd == nil {
+				continue
+			}
+			startsBefore := p.RangeStart == nil || !p.RangeStart.After(cursor)
+			if startsBefore && p.RangeEnd.After(cursor) {
+				cursor, covered = p.RangeEnd.UTC(), true // Skip past the existing partition
+				break
+			}
+			if p.RangeStart != nil && p.RangeStart.After(cursor) && p.RangeStart.Before(end) {
+				end = p.RangeStart.UTC() // Stop where the next existing partition begins
+			}
+		}
+		if covered {
+			continue
+		}
+		missing = append(missing, timeRange{cursor, end})
+		cursor = end
+	}
+	return missing
+}
@@ -0,0 +1,143 @@
+package scheduling
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// deviceState is the Scheduler's view of a single device.
+// Only the queue entry matching nextDeadline is live; any other entry for the device is a stale duplicate.
+type deviceState struct {
+	nextDeadline      time.Time
+	baseInterval      time.Duration
+	effectiveInterval time.Duration
+	failureStreak     int
+	lastOutcome       string
+	lastOutcomeAt     time.Time
+	shouldPing        bool
+	blockedBy         int64 // Unreachable ancestor this device is attributed to, 0 if none
+	blockedSince      time.Time
+}
+
+// trackDeadline records the live queue deadline for a device, creating its state on first use.
+func (sched *Scheduler) trackDeadline(deviceID int64, deadline time.Time) *deviceState {
+	state, exists := sched.states[deviceID]
+	if !exists {
+		state = &deviceState{}
+		sched.states[deviceID] = state
+	}
+	state.nextDeadline = deadline
+	return state
+}
+
+// isLive reports whether a popped queue entry is the device's current deadline.
+func (sched *Scheduler) isLive(entry *DeviceDeadline) bool {
+	state, exists := sched.states[entry.DeviceID]
+	return exists && state.nextDeadline.Equal(entry.Deadline)
+}
+
+// baseInterval returns the configured polling interval of a device.
+func (sched *Scheduler) baseInterval(dev *models.Device) time.Duration {
+	interval := time.Duration(dev.PollingIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = sched.tickInterval
+	}
+	return interval
+}
+
+// backoffInterval grows the base interval exponentially with the failure streak, up to the cap.
+// Devices configured above the cap keep their own interval.
+func (sched *Scheduler) backoffInterval(base time.Duration, streak int) time.Duration {
+	if streak == 0 || base >= sched.backoffMax {
+		return base
+	}
+	interval := float64(base) * math.Pow(sched.backoffMultiplier, float64(streak))
+	if interval > float64(sched.backoffMax) {
+		return sched.backoffMax
+	}
+	return time.Duration(interval)
+}
+
+// refreshInterval recomputes the effective interval for a device from its current streak.
+func (sched *Scheduler) refreshInterval(dev *models.Device, state *deviceState) {
+	state.baseInterval = sched.baseInterval(dev)
+	state.effectiveInterval = sched.backoffInterval(state.baseInterval, state.failureStreak)
+}
+
+// recordFailure extends the failure streak of a device and publishes the new backoff.
+func (sched *Scheduler) recordFailure(deviceID int64, state *deviceState) {
+	state.failureStreak++
+	state.effectiveInterval = sched.backoffInterval(state.baseInterval, state.failureStreak)
+	slog.Debug("Backoff increased", "component", "Scheduler", "device_id", deviceID,
+		"failure_streak", state.failureStreak, "effective_interval", state.effectiveInterval.String())
+	sched.publishBackoff(deviceID, state)
+}
+
+// handlePollOutcome adjusts backoff from poll results reported by MetricsService.
+// The first success drops the device back to its configured interval and pulls its deadline in.
+func (sched *Scheduler) handlePollOutcome(event models.Event) {
+	outcome, ok := event.Payload.(*models.PollOutcomeEvent)
+	if !ok {
+		slog.Error("Invalid payload type in poll outcome event", "component", "Scheduler")
+		return
+	}
+
+	state, exists := sched.states[outcome.DeviceID]
+	if !exists {
+		return // Device no longer scheduled
+	}
+
+	// Pinged devices are judged reachable by fping; the rest by their polls
+	if !state.shouldPing {
+		sched.unreachable[outcome.DeviceID] = !outcome.Success
+	}
+
+	if !outcome.Success {
+		if state.blockedBy == 0 {
+			sched.recordFailure(outcome.DeviceID, state)
+		}
+		return
+	}
+
+	if state.failureStreak == 0 {
+		return
+	}
+
+	state.failureStreak = 0
+	state.effectiveInterval = state.baseInterval
+
+	// Re-anchor on the configured interval; the old, backed-off entry becomes stale
+	next := outcome.Timestamp.Add(state.baseInterval)
+	if stats, exists := sched.stats[outcome.DeviceID]; exists && !stats.LastDispatch.IsZero() {
+		next = stats.LastDispatch.Add(state.baseInterval)
+	}
+	if next.Before(state.nextDeadline) {
+		sched.queue.PushEntry(outcome.DeviceID, next)
+		state.nextDeadline = next
+	}
+
+	slog.Info("Device recovered, backoff reset", "component", "Scheduler", "device_id", outcome.DeviceID,
+		"next_deadline", state.nextDeadline.Format(time.RFC3339))
+	sched.publishBackoff(outcome.DeviceID, state)
+}
+
+// publishBackoff sends the device's backoff state to EntityService without blocking.
+func (sched *Scheduler) publishBackoff(deviceID int64, state *deviceState) {
+	event := models.Event{
+		Type: models.EventBackoffUpdate,
+		Payload: &models.DeviceBackoff{
+			DeviceID:                 deviceID,
+			EffectiveIntervalSeconds: int(state.effectiveInterval / time.Second),
+			FailureStreak:            state.failureStreak,
+		},
+	}
+
+	select {
+	case sched.entityEvents <- event:
+	default:
+		slog.Warn("Entity event channel full, dropping backoff update", "component", "Scheduler", "device_id", deviceID)
+	}
+}
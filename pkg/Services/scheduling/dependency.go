@@ -0,0 +1,95 @@
+package scheduling
+
+import (
+	"log/slog"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// maxDependencyDepth bounds ancestor walks in case the topology ever contains a cycle.
+const maxDependencyDepth = 32
+
+// trackTopology records a device's parent so ancestor walks work for devices not due in this tick.
+func (sched *Scheduler) trackTopology(dev *models.Device) {
+	if dev.ParentDeviceID != nil {
+		sched.parents[dev.ID] = *dev.ParentDeviceID
+	} else {
+		delete(sched.parents, dev.ID)
+	}
+}
+
+// forgetTopology drops a deleted device and detaches its children (the DB sets their parent to NULL).
+func (sched *Scheduler) forgetTopology(deviceID int64) {
+	delete(sched.parents, deviceID)
+	delete(sched.unreachable, deviceID)
+	for child, parent := range sched.parents {
+		if parent == deviceID {
+			delete(sched.parents, child)
+		}
+	}
+}
+
+// rootCause returns the highest unreachable ancestor of a device, or 0 if every ancestor is reachable.
+// Unreachability survives deactivation, so children of a parent taken out of rotation stay attributed to it.
+func (sched *Scheduler) rootCause(deviceID int64) int64 {
+	var root int64
+	current := deviceID
+	for depth := 0; depth < maxDependencyDepth; depth++ {
+		parent, exists := sched.parents[current]
+		if !exists {
+			break
+		}
+		if sched.unreachable[parent] {
+			root = parent
+		}
+		current = parent
+	}
+	return root
+}
+
+// setBlocked records whether a device is blocked by an unreachable ancestor and publishes changes.
+func (sched *Scheduler) setBlocked(deviceID int64, state *deviceState, blockedBy int64) {
+	if state.blockedBy == blockedBy {
+		return
+	}
+
+	if blockedBy != 0 {
+		slog.Info("Device unreachable by dependency", "component", "Scheduler", "device_id", deviceID, "blocked_by", blockedBy)
+		state.blockedSince = time.Now()
+	} else {
+		slog.Info("Device no longer blocked by dependency", "component", "Scheduler", "device_id", deviceID, "was_blocked_by", state.blockedBy)
+		state.blockedSince = time.Time{}
+	}
+	state.blockedBy = blockedBy
+
+	event := models.Event{
+		Type: models.EventDependencyUpdate,
+		Payload: &models.DeviceDependency{
+			DeviceID:  deviceID,
+			State:     models.DependencyUnreachable,
+			BlockedBy: blockedBy,
+			Since:     state.blockedSince,
+		},
+	}
+
+	select {
+	case sched.entityEvents <- event:
+	default:
+		slog.Warn("Entity event channel full, dropping dependency update", "component", "Scheduler", "device_id", deviceID)
+	}
+}
+
+// emitDependencyFailure tells FailureService a failure belongs to an unreachable ancestor, not the device.
+func (sched *Scheduler) emitDependencyFailure(deviceID, blockedBy int64) {
+	sched.setOutcome(deviceID, models.DispatchOutcomeBlocked)
+	sched.emitHealth(deviceID, models.Event{
+		Type: models.EventDeviceFailure,
+		Payload: &models.DeviceFailureEvent{
+			DeviceID:  deviceID,
+			Timestamp: time.Now(),
+			Reason:    "dependency",
+			BlockedBy: blockedBy,
+		},
+	})
+}
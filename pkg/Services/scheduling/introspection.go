@@ -0,0 +1,114 @@
+package scheduling
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// defaultDeadlineLimit caps the number of upcoming deadlines in a status snapshot.
+const defaultDeadlineLimit = 20
+
+// handleRequest answers introspection requests from inside the Run goroutine.
+// Replies carry copies so callers never share scheduler state.
+func (sched *Scheduler) handleRequest(req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpGetPollStats:
+		resp = sched.pollStats(req.ID)
+	case models.OpSchedulerStatus:
+		limit, _ := req.Payload.(int)
+		resp.Data = sched.status(limit)
+	case models.OpGetSchedule:
+		resp = sched.deviceSchedule(req.ID)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// pollStats returns dispatch counters for one device, or all devices when deviceID is 0.
+func (sched *Scheduler) pollStats(deviceID int64) models.Response {
+	if deviceID != 0 {
+		stats, exists := sched.stats[deviceID]
+		if !exists {
+			return models.Response{Error: fmt.Errorf("no scheduling stats for device %d", deviceID)}
+		}
+		statsCopy := *stats
+		return models.Response{Data: &statsCopy}
+	}
+
+	all := make([]*models.DevicePollStats, 0, len(sched.stats))
+	for _, stats := range sched.stats {
+		statsCopy := *stats
+		all = append(all, &statsCopy)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].DeviceID < all[j].DeviceID })
+	return models.Response{Data: all}
+}
+
+// status builds a snapshot of the queue: live deadlines in order, stale duplicates and last tick timings.
+func (sched *Scheduler) status(limit int) *models.SchedulerStatus {
+	if limit <= 0 {
+		limit = defaultDeadlineLimit
+	}
+
+	live := make([]models.DeadlineInfo, 0, len(sched.states))
+	stale := 0
+	for _, entry := range sched.queue {
+		if sched.isLive(entry) {
+			live = append(live, models.DeadlineInfo{DeviceID: entry.DeviceID, Deadline: entry.Deadline})
+		} else {
+			stale++
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].Deadline.Before(live[j].Deadline) })
+	if len(live) > limit {
+		live = live[:limit]
+	}
+
+	status := &models.SchedulerStatus{
+		QueueLength:      sched.queue.Len(),
+		TrackedDevices:   len(sched.states),
+		StaleEntries:     stale,
+		PendingCoalesced: len(sched.pending),
+		PendingHealth:    len(sched.healthBacklog),
+		OverloadPolicy:   sched.overloadPolicy,
+		TickIntervalSec:  int(sched.tickInterval / time.Second),
+		NextDeadlines:    live,
+	}
+	if sched.lastTick != nil {
+		tickCopy := *sched.lastTick
+		status.LastTick = &tickCopy
+	}
+	return status
+}
+
+// deviceSchedule returns the next deadline, backoff and last dispatch outcome of a device.
+func (sched *Scheduler) deviceSchedule(deviceID int64) models.Response {
+	state, exists := sched.states[deviceID]
+	if !exists {
+		return models.Response{Error: fmt.Errorf("device %d is not scheduled", deviceID)}
+	}
+
+	_, pending := sched.pending[deviceID]
+	schedule := &models.DeviceSchedule{
+		DeviceID:                 deviceID,
+		NextDeadline:             state.nextDeadline,
+		EffectiveIntervalSeconds: int(state.effectiveInterval / time.Second),
+		FailureStreak:            state.failureStreak,
+		LastOutcome:              state.lastOutcome,
+		LastOutcomeAt:            state.lastOutcomeAt,
+		Pending:                  pending,
+		BlockedBy:                state.blockedBy,
+	}
+	if stats, exists := sched.stats[deviceID]; exists {
+		statsCopy := *stats
+		schedule.Stats = &statsCopy
+	}
+	return models.Response{Data: schedule}
+}
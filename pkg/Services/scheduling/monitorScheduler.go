@@ -1,14 +1,11 @@
 package scheduling
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"log/slog"
-	"os/exec"
-	"strings"
 	"time"
 
+	"nms/pkg/fping"
 	"nms/pkg/models"
 )
 
@@ -23,14 +20,55 @@ type Scheduler struct {
 
 	// Channels - received from outside for event-driven communication
 	deviceEvents <-chan models.Event     // Device create/update events (to add to queue)
+	pollOutcomes <-chan models.Event     // Poll success/failure from MetricsService (drives backoff)
+	requests     <-chan models.Request   // Introspection requests from the API
 	OutputChan   chan<- []*models.Device // Sends qualified devices to poller
 	FailureChan  chan<- models.Event     // Sends failure events to HealthMonitor
+	entityEvents chan<- models.Event     // Publishes backoff and dependency state to EntityService
+
+	// Per-device state and dispatch accounting (owned by the Run goroutine)
+	states  map[int64]*deviceState
+	stats   map[int64]*models.DevicePollStats
+	pending map[int64]*pendingDispatch // Coalesced devices waiting for the Poller
+
+	// Health events HealthMonitor couldn't take yet, drained in order by Run
+	healthBacklog []deferredHealth
+
+	// Topology for dependency attribution; kept for inactive devices so their children stay attributed
+	parents     map[int64]int64 // Child -> parent device ID
+	unreachable map[int64]bool  // Last reachability check failed
+
+	// Tick timings for introspection
+	lastTick    *models.SchedulerTickStats
+	currentTick *models.SchedulerTickStats
+	tickLagSum  time.Duration
+
+	// Availability check
+	pinger *fping.Pinger
 
 	// Config
-	fpingPath    string
-	tickInterval time.Duration
-	fpingTimeout int
-	fpingRetries int
+	tickInterval   time.Duration
+	overloadPolicy string
+	lateTolerance  time.Duration
+
+	// Adaptive backoff
+	backoffMultiplier float64
+	backoffMax        time.Duration
+}
+
+// pendingDispatch is a qualified device held back because the Poller was saturated.
+type pendingDispatch struct {
+	device   *models.Device
+	deadline time.Time
+}
+
+// maxHealthBacklog bounds the deferred health events; beyond it the oldest is dropped and counted.
+const maxHealthBacklog = 10000
+
+// deferredHealth is a health event waiting for room on FailureChan.
+type deferredHealth struct {
+	deviceID int64
+	event    models.Event
 }
 
 // NewScheduler creates a new Scheduler instance.
@@ -39,19 +77,36 @@ func NewScheduler(
 	entityReqChan chan<- models.Request,
 	outputChan chan<- []*models.Device,
 	failureChan chan<- models.Event,
+	pollOutcomes <-chan models.Event,
+	entityEvents chan<- models.Event,
+	requests <-chan models.Request,
 	fpingPath string,
 	tickIntervalSec, fpingTimeoutMs, fpingRetries int,
+	overloadPolicy string,
+	lateToleranceSec int,
+	backoffMultiplier float64,
+	backoffMaxSec int,
 ) *Scheduler {
 	return &Scheduler{
-		queue:         make(DeadlineQueue, 0),
-		entityReqChan: entityReqChan,
-		deviceEvents:  deviceEvents,
-		OutputChan:    outputChan,
-		FailureChan:   failureChan,
-		fpingPath:     fpingPath,
-		tickInterval:  time.Duration(tickIntervalSec) * time.Second,
-		fpingTimeout:  fpingTimeoutMs,
-		fpingRetries:  fpingRetries,
+		queue:             make(DeadlineQueue, 0),
+		entityReqChan:     entityReqChan,
+		deviceEvents:      deviceEvents,
+		pollOutcomes:      pollOutcomes,
+		requests:          requests,
+		OutputChan:        outputChan,
+		FailureChan:       failureChan,
+		entityEvents:      entityEvents,
+		states:            make(map[int64]*deviceState),
+		stats:             make(map[int64]*models.DevicePollStats),
+		pending:           make(map[int64]*pendingDispatch),
+		parents:           make(map[int64]int64),
+		unreachable:       make(map[int64]bool),
+		pinger:            fping.NewPinger(fpingPath, fpingTimeoutMs, fpingRetries),
+		tickInterval:      time.Duration(tickIntervalSec) * time.Second,
+		overloadPolicy:    overloadPolicy,
+		lateTolerance:     time.Duration(lateToleranceSec) * time.Second,
+		backoffMultiplier: backoffMultiplier,
+		backoffMax:        time.Duration(backoffMaxSec) * time.Second,
 	}
 }
 
@@ -60,25 +115,45 @@ func NewScheduler(
 func (sched *Scheduler) InitQueue(deviceIDs []int64) {
 	now := time.Now()
 	sched.queue.InitQueue(deviceIDs, now)
+	for _, id := range deviceIDs {
+		sched.trackDeadline(id, now)
+	}
 	slog.Info("Priority queue initialized", "component", "Scheduler", "device_count", len(deviceIDs))
 }
 
 // Run starts the main loop.
 func (sched *Scheduler) Run(ctx context.Context) {
-	slog.Info("Starting main loop", "component", "Scheduler", "tick_interval", sched.tickInterval.String())
+	slog.Info("Starting main loop", "component", "Scheduler", "tick_interval", sched.tickInterval.String(), "overload_policy", sched.overloadPolicy)
 	ticker := time.NewTicker(sched.tickInterval)
 	defer ticker.Stop()
 
 	for {
+		// The send case is only enabled while health events are waiting (nil channels never fire)
+		var healthOut chan<- models.Event
+		var nextHealth models.Event
+		if len(sched.healthBacklog) > 0 {
+			healthOut = sched.FailureChan
+			nextHealth = sched.healthBacklog[0].event
+		}
+
 		select {
 		case <-ctx.Done():
 			slog.Info("Context cancelled, shutting down", "component", "Scheduler")
 			return
 
+		case healthOut <- nextHealth:
+			sched.popHealth()
+
 		case event := <-sched.deviceEvents:
 			slog.Debug("Received device event", "component", "Scheduler", "event_type", event.Type)
 			sched.processDeviceEvent(event)
 
+		case event := <-sched.pollOutcomes:
+			sched.handlePollOutcome(event)
+
+		case req := <-sched.requests:
+			sched.handleRequest(req)
+
 		case <-ticker.C:
 			slog.Debug("Tick - running schedule()", "component", "Scheduler")
 			sched.schedule()
@@ -98,15 +173,25 @@ func (sched *Scheduler) processDeviceEvent(event models.Event) {
 	switch event.Type {
 	case models.EventCreate:
 		// New device: add to queue with immediate deadline
-		sched.queue.PushEntry(payload.ID, time.Now())
+		now := time.Now()
+		sched.queue.PushEntry(payload.ID, now)
+		sched.trackDeadline(payload.ID, now)
+		sched.trackTopology(payload)
 		slog.Info("Added new device to queue", "component", "Scheduler", "device_id", payload.ID)
 	case models.EventUpdate:
 		// Updated device: add a new entry with immediate deadline
-		// The old entry may still exist (lazy management) but will be handled gracefully
-		sched.queue.PushEntry(payload.ID, time.Now())
+		// The old entry may still exist (lazy management) but is discarded as stale when popped
+		now := time.Now()
+		sched.queue.PushEntry(payload.ID, now)
+		sched.trackDeadline(payload.ID, now)
+		sched.trackTopology(payload)
 		slog.Info("Re-added updated device to queue", "component", "Scheduler", "device_id", payload.ID)
 	case models.EventDelete:
 		// Lazy deletion: don't remove from queue, EntityService won't return it
+		delete(sched.states, payload.ID)
+		delete(sched.stats, payload.ID)
+		delete(sched.pending, payload.ID)
+		sched.forgetTopology(payload.ID)
 		slog.Debug("Device delete event received (lazy queue management)", "component", "Scheduler", "device_id", payload.ID)
 	}
 }
@@ -117,6 +202,13 @@ func (sched *Scheduler) schedule() {
 	now := time.Now()
 	slog.Debug("Checking deadlines", "component", "Scheduler", "now", now.Format(time.RFC3339), "queue_size", sched.queue.Len())
 
+	tick := &models.SchedulerTickStats{StartedAt: now}
+	sched.currentTick, sched.tickLagSum = tick, 0
+	defer sched.finishTick(tick)
+
+	// Retry devices coalesced on a previous tick before dispatching new ones
+	sched.flushPending()
+
 	// 1. Pop all expired entries from queue
 	expired := sched.queue.PopExpired(now)
 	if len(expired) == 0 {
@@ -124,13 +216,25 @@ func (sched *Scheduler) schedule() {
 		return
 	}
 
-	// Collect device IDs
+	// Collect device IDs, discarding stale duplicates left behind by lazy queue management
 	deviceIDs := make([]int64, 0, len(expired))
 	deadlineMap := make(map[int64]time.Time) // Track original deadlines for re-insertion
+	live := expired[:0]
+	tick.Due = len(expired)
 	for _, entry := range expired {
+		if !sched.isLive(entry) {
+			tick.StaleDropped++
+			continue
+		}
+		live = append(live, entry)
 		deviceIDs = append(deviceIDs, entry.DeviceID)
 		deadlineMap[entry.DeviceID] = entry.Deadline
 	}
+	expired = live
+	if len(expired) == 0 {
+		slog.Debug("Only stale entries were due", "component", "Scheduler")
+		return
+	}
 
 	slog.Debug("Expired entries popped", "component", "Scheduler", "count", len(deviceIDs))
 
@@ -147,7 +251,9 @@ func (sched *Scheduler) schedule() {
 		slog.Error("Failed to get devices from EntityService", "component", "Scheduler", "error", resp.Error)
 		// Re-add entries back to queue to retry later
 		for _, entry := range expired {
-			sched.queue.PushEntry(entry.DeviceID, entry.Deadline.Add(sched.tickInterval))
+			retryAt := entry.Deadline.Add(sched.tickInterval)
+			sched.queue.PushEntry(entry.DeviceID, retryAt)
+			sched.trackDeadline(entry.DeviceID, retryAt)
 		}
 		return
 	}
@@ -160,6 +266,20 @@ func (sched *Scheduler) schedule() {
 
 	slog.Debug("Got devices from EntityService", "component", "Scheduler", "to_ping", len(batchResp.ToPing), "to_skip", len(batchResp.ToSkip))
 
+	// Devices not returned are deleted or no longer active: stop tracking them
+	returned := make(map[int64]bool, len(batchResp.ToPing)+len(batchResp.ToSkip))
+	for _, dev := range batchResp.ToPing {
+		returned[dev.ID] = true
+	}
+	for _, dev := range batchResp.ToSkip {
+		returned[dev.ID] = true
+	}
+	for _, id := range deviceIDs {
+		if !returned[id] {
+			delete(sched.states, id)
+		}
+	}
+
 	// 3. Collect IPs for fping (only from ToPing list)
 	ips := make([]string, 0)
 	ipSet := make(map[string]bool)
@@ -171,45 +291,95 @@ func (sched *Scheduler) schedule() {
 	}
 
 	// 4. Perform batch fping
-	reachableIPs := sched.performBatchFping(ips)
+	fpingStart := time.Now()
+	reachableIPs := sched.pinger.Check(ips)
+	tick.FpingDurationMs = time.Since(fpingStart).Milliseconds()
 	slog.Debug("Fping results", "component", "Scheduler", "reachable_count", len(reachableIPs), "total_ips", len(ips))
 
+	// Record reachability for the whole batch first so parents due in this tick are known
+	for _, dev := range batchResp.ToPing {
+		sched.trackTopology(dev)
+		sched.states[dev.ID].shouldPing = true
+		sched.unreachable[dev.ID] = !reachableIPs[dev.IPAddress]
+	}
+	for _, dev := range batchResp.ToSkip {
+		sched.trackTopology(dev)
+		sched.states[dev.ID].shouldPing = false
+	}
+
 	// 5. Filter qualified devices and collect entries for re-add
 	qualified := make([]*models.Device, 0)
 	toRequeue := make([]*DeviceDeadline, 0, len(batchResp.ToPing)+len(batchResp.ToSkip))
 
 	// Process ToPing devices
 	for _, dev := range batchResp.ToPing {
+		state := sched.states[dev.ID]
+		sched.refreshInterval(dev, state)
+
+		// Failures behind an unreachable parent are attributed to it and don't back off
+		var blockedBy int64
+		if !reachableIPs[dev.IPAddress] {
+			blockedBy = sched.rootCause(dev.ID)
+			if blockedBy == 0 {
+				sched.recordFailure(dev.ID, state)
+			}
+		}
+		sched.setBlocked(dev.ID, state, blockedBy)
+
 		oldDeadline := deadlineMap[dev.ID]
-		newDeadline := oldDeadline.Add(time.Duration(dev.PollingIntervalSeconds) * time.Second)
+		newDeadline := sched.nextDeadline(dev.ID, state.effectiveInterval, oldDeadline, now)
+		state.nextDeadline = newDeadline
 
 		if reachableIPs[dev.IPAddress] {
 			qualified = append(qualified, dev)
 			slog.Info("Device qualified (ping OK)", "component", "Scheduler", "device_id", dev.ID, "next_deadline", newDeadline.Format(time.RFC3339))
+			sched.emitHealth(dev.ID, models.Event{
+				Type: models.EventDeviceSuccess,
+				Payload: &models.DeviceSuccessEvent{
+					DeviceID:  dev.ID,
+					Timestamp: time.Now(),
+					Reason:    "ping",
+				},
+			})
+		} else if blockedBy != 0 {
+			slog.Debug("Device not reachable, parent down", "component", "Scheduler", "device_id", dev.ID, "blocked_by", blockedBy)
+			sched.emitDependencyFailure(dev.ID, blockedBy)
 		} else {
 			slog.Debug("Device not reachable", "component", "Scheduler", "device_id", dev.ID, "ip", dev.IPAddress)
+			sched.setOutcome(dev.ID, models.DispatchOutcomePingFailed)
 			// Emit failure event to HealthMonitor
-			sched.FailureChan <- models.Event{
+			sched.emitHealth(dev.ID, models.Event{
 				Type: models.EventDeviceFailure,
 				Payload: &models.DeviceFailureEvent{
 					DeviceID:  dev.ID,
 					Timestamp: time.Now(),
 					Reason:    "ping",
 				},
-			}
+			})
 		}
 
 		// Collect for batch re-add
 		toRequeue = append(toRequeue, &DeviceDeadline{DeviceID: dev.ID, Deadline: newDeadline})
 	}
 
-	// Process ToSkip devices (no ping needed, always qualified)
+	// Process ToSkip devices (no ping needed, qualified unless a parent is unreachable)
 	for _, dev := range batchResp.ToSkip {
-		oldDeadline := deadlineMap[dev.ID]
-		newDeadline := oldDeadline.Add(time.Duration(dev.PollingIntervalSeconds) * time.Second)
+		state := sched.states[dev.ID]
+		sched.refreshInterval(dev, state)
 
-		qualified = append(qualified, dev)
-		slog.Info("Device qualified (ping skipped)", "component", "Scheduler", "device_id", dev.ID, "next_deadline", newDeadline.Format(time.RFC3339))
+		oldDeadline := deadlineMap[dev.ID]
+		newDeadline := sched.nextDeadline(dev.ID, state.effectiveInterval, oldDeadline, now)
+		state.nextDeadline = newDeadline
+
+		blockedBy := sched.rootCause(dev.ID)
+		sched.setBlocked(dev.ID, state, blockedBy)
+		if blockedBy != 0 {
+			slog.Debug("Device poll skipped, parent down", "component", "Scheduler", "device_id", dev.ID, "blocked_by", blockedBy)
+			sched.emitDependencyFailure(dev.ID, blockedBy)
+		} else {
+			qualified = append(qualified, dev)
+			slog.Info("Device qualified (ping skipped)", "component", "Scheduler", "device_id", dev.ID, "next_deadline", newDeadline.Format(time.RFC3339))
+		}
 
 		// Collect for batch re-add
 		toRequeue = append(toRequeue, &DeviceDeadline{DeviceID: dev.ID, Deadline: newDeadline})
@@ -220,63 +390,167 @@ func (sched *Scheduler) schedule() {
 		sched.queue.PushBatch(toRequeue)
 	}
 
-	// 7. Dispatch qualified list to OutputChan
+	// 7. Dispatch qualified list to OutputChan (never blocks the event loop)
 	if len(qualified) > 0 {
-		slog.Info("Dispatching qualified devices", "component", "Scheduler", "count", len(qualified))
-		sched.OutputChan <- qualified
+		sched.dispatch(qualified, deadlineMap)
 	} else {
 		slog.Debug("No devices qualified", "component", "Scheduler")
 	}
 }
 
-// performBatchFping runs fping against a list of IPs and returns reachability.
-func (sched *Scheduler) performBatchFping(ips []string) map[string]bool {
-	reachable := make(map[string]bool)
-
-	if len(ips) == 0 {
-		slog.Debug("No IPs to check with fping", "component", "Scheduler")
-		return reachable
-	}
-
-	slog.Info("Checking IPs with fping", "component", "Scheduler", "count", len(ips), "timeout_ms", sched.fpingTimeout, "retries", sched.fpingRetries)
-
-	// Build fping command
-	// -a: show alive hosts
-	// -q: quiet (don't show per-target results)
-	// -t: timeout in ms
-	// -r: retry count
-	args := []string{
-		"-a",
-		"-q",
-		"-t", fmt.Sprintf("%d", sched.fpingTimeout),
-		"-r", fmt.Sprintf("%d", sched.fpingRetries),
-	}
-	args = append(args, ips...)
-
-	cmd := exec.Command(sched.fpingPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	// fping returns non-zero if some hosts are unreachable, so we don't treat that as an error
-	if err != nil {
-		slog.Debug("fping exited with error (normal if some hosts down)", "component", "Scheduler", "error", err)
-	}
-
-	// Parse stdout for reachable IPs (one per line)
-	output := strings.TrimSpace(stdout.String())
-	if output != "" {
-		lines := strings.Split(output, "\n")
-		for _, line := range lines {
-			ip := strings.TrimSpace(line)
-			if ip != "" {
-				reachable[ip] = true
-				slog.Debug("IP is reachable", "component", "Scheduler", "ip", ip)
+// nextDeadline computes the following deadline for a device from its effective interval.
+// Cycles that were already missed (scheduler fell behind) are skipped rather than replayed.
+func (sched *Scheduler) nextDeadline(deviceID int64, interval time.Duration, oldDeadline, now time.Time) time.Time {
+	next := oldDeadline.Add(interval)
+	if next.After(now) {
+		return next
+	}
+
+	missed := int64(now.Sub(oldDeadline) / interval)
+	sched.deviceStats(deviceID).SkippedPolls += missed
+	slog.Warn("Skipping missed poll cycles", "component", "Scheduler", "device_id", deviceID, "missed", missed)
+	return oldDeadline.Add(time.Duration(missed+1) * interval)
+}
+
+// dispatch hands qualified devices to the Poller without blocking.
+// When the Poller is saturated the configured overload policy decides what happens to the batch.
+func (sched *Scheduler) dispatch(devices []*models.Device, deadlines map[int64]time.Time) {
+	select {
+	case sched.OutputChan <- devices:
+		slog.Info("Dispatched qualified devices", "component", "Scheduler", "count", len(devices))
+		sched.recordDispatch(devices, deadlines, time.Now())
+		return
+	default:
+	}
+
+	switch sched.overloadPolicy {
+	case models.OverloadPolicyCoalesce:
+		for _, dev := range devices {
+			if _, exists := sched.pending[dev.ID]; exists {
+				// Older pending cycle is merged into this one
+				sched.deviceStats(dev.ID).SkippedPolls++
+			}
+			sched.pending[dev.ID] = &pendingDispatch{device: dev, deadline: deadlines[dev.ID]}
+			sched.setOutcome(dev.ID, models.DispatchOutcomeCoalesced)
+		}
+		slog.Warn("Poller saturated, coalescing batch", "component", "Scheduler", "count", len(devices), "pending", len(sched.pending))
+	default:
+		for _, dev := range devices {
+			sched.deviceStats(dev.ID).SkippedPolls++
+			sched.setOutcome(dev.ID, models.DispatchOutcomeSkipped)
+		}
+		slog.Warn("Poller saturated, skipping batch", "component", "Scheduler", "count", len(devices))
+	}
+}
+
+// flushPending retries devices held back by the coalesce policy.
+func (sched *Scheduler) flushPending() {
+	if len(sched.pending) == 0 {
+		return
+	}
+
+	devices := make([]*models.Device, 0, len(sched.pending))
+	deadlines := make(map[int64]time.Time, len(sched.pending))
+	for id, p := range sched.pending {
+		devices = append(devices, p.device)
+		deadlines[id] = p.deadline
+	}
+
+	select {
+	case sched.OutputChan <- devices:
+		slog.Info("Dispatched coalesced devices", "component", "Scheduler", "count", len(devices))
+		sched.recordDispatch(devices, deadlines, time.Now())
+		sched.pending = make(map[int64]*pendingDispatch)
+	default:
+		slog.Warn("Poller still saturated, keeping coalesced devices", "component", "Scheduler", "pending", len(devices))
+	}
+}
+
+// recordDispatch updates lag and late-poll counters for dispatched devices.
+func (sched *Scheduler) recordDispatch(devices []*models.Device, deadlines map[int64]time.Time, at time.Time) {
+	for _, dev := range devices {
+		stats := sched.deviceStats(dev.ID)
+		lag := at.Sub(deadlines[dev.ID])
+		if lag < 0 {
+			lag = 0
+		}
+
+		stats.Dispatched++
+		stats.LastDispatch = at
+		stats.LastLagMs = lag.Milliseconds()
+		if stats.LastLagMs > stats.MaxLagMs {
+			stats.MaxLagMs = stats.LastLagMs
+		}
+		sched.setOutcome(dev.ID, models.DispatchOutcomeDispatched)
+
+		if tick := sched.currentTick; tick != nil {
+			tick.Dispatched++
+			sched.tickLagSum += lag
+			if lag.Milliseconds() > tick.MaxLagMs {
+				tick.MaxLagMs = lag.Milliseconds()
 			}
 		}
+
+		// A deadline can fall anywhere inside a tick, so only lag beyond one tick counts as late
+		if lag > sched.tickInterval+sched.lateTolerance {
+			stats.LatePolls++
+			slog.Warn("Late poll dispatched", "component", "Scheduler", "device_id", dev.ID, "lag", lag.String())
+		}
+	}
+}
+
+// setOutcome records the latest dispatch outcome for a tracked device.
+func (sched *Scheduler) setOutcome(deviceID int64, outcome string) {
+	if state, exists := sched.states[deviceID]; exists {
+		state.lastOutcome = outcome
+		state.lastOutcomeAt = time.Now()
+	}
+}
+
+// finishTick finalizes timings for the schedule() run and publishes them for introspection.
+func (sched *Scheduler) finishTick(tick *models.SchedulerTickStats) {
+	tick.DurationMs = time.Since(tick.StartedAt).Milliseconds()
+	if tick.Dispatched > 0 {
+		tick.AvgLagMs = (sched.tickLagSum / time.Duration(tick.Dispatched)).Milliseconds()
 	}
+	sched.lastTick = tick
+	sched.currentTick = nil
+}
+
+// deviceStats returns the stats entry for a device, creating it on first use.
+func (sched *Scheduler) deviceStats(deviceID int64) *models.DevicePollStats {
+	stats, exists := sched.stats[deviceID]
+	if !exists {
+		stats = &models.DevicePollStats{DeviceID: deviceID}
+		sched.stats[deviceID] = stats
+	}
+	return stats
+}
+
+// emitHealth sends a failure or success event to HealthMonitor without blocking.
+// When FailureChan is full (or older events are still waiting) the event is deferred to the
+// backlog Run drains, so HealthMonitor sees every event in order.
+func (sched *Scheduler) emitHealth(deviceID int64, event models.Event) {
+	if len(sched.healthBacklog) == 0 {
+		select {
+		case sched.FailureChan <- event:
+			return
+		default:
+		}
+	}
+
+	sched.deviceStats(deviceID).HealthEventsDeferred++
+	if len(sched.healthBacklog) >= maxHealthBacklog {
+		oldest := sched.healthBacklog[0]
+		sched.popHealth()
+		sched.deviceStats(oldest.deviceID).HealthEventsDropped++
+		slog.Warn("Health backlog full, dropping oldest event", "component", "Scheduler", "device_id", oldest.deviceID, "event_type", oldest.event.Type)
+	}
+	sched.healthBacklog = append(sched.healthBacklog, deferredHealth{deviceID: deviceID, event: event})
+}
 
-	slog.Info("Fping check complete", "component", "Scheduler", "reachable_count", len(reachable), "total_ips", len(ips))
-	return reachable
+// popHealth removes the oldest deferred health event.
+func (sched *Scheduler) popHealth() {
+	sched.healthBacklog[0] = deferredHealth{}
+	sched.healthBacklog = sched.healthBacklog[1:]
 }
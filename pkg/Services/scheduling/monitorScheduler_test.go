@@ -0,0 +1,70 @@
+package scheduling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nms/pkg/models"
+)
+
+func healthEvent(deviceID int64) models.Event {
+	return models.Event{
+		Type:    models.EventDeviceFailure,
+		Payload: &models.DeviceFailureEvent{DeviceID: deviceID, Timestamp: time.Now(), Reason: "ping"},
+	}
+}
+
+func TestEmitHealthDefersWhenChannelFull(t *testing.T) {
+	failures := make(chan models.Event, 1)
+	sched := NewScheduler(nil, nil, nil, failures, nil, nil, nil, "", 3600, 100, 1, models.OverloadPolicySkip, 0, 2, 60)
+
+	for id := int64(1); id <= 4; id++ {
+		sched.emitHealth(id, healthEvent(id))
+	}
+	if len(sched.healthBacklog) != 3 {
+		t.Fatalf("backlog has %d events, want 3", len(sched.healthBacklog))
+	}
+	if stats := sched.stats[2]; stats == nil || stats.HealthEventsDeferred != 1 || stats.HealthEventsDropped != 0 {
+		t.Errorf("device 2 stats = %+v, want one deferred event", stats)
+	}
+
+	// Once there is room, Run delivers the backlog in order
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.Run(ctx)
+
+	for want := int64(1); want <= 4; want++ {
+		select {
+		case event := <-failures:
+			if got := event.Payload.(*models.DeviceFailureEvent).DeviceID; got != want {
+				t.Fatalf("got event of device %d, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event of device %d was not delivered", want)
+		}
+	}
+}
+
+func TestEmitHealthDropsOldestOnOverflow(t *testing.T) {
+	sched := NewScheduler(nil, nil, nil, make(chan models.Event), nil, nil, nil, "", 1, 100, 1, models.OverloadPolicySkip, 0, 2, 60)
+
+	sched.emitHealth(1, healthEvent(1))
+	for i := 1; i < maxHealthBacklog; i++ {
+		sched.emitHealth(2, healthEvent(2))
+	}
+	sched.emitHealth(3, healthEvent(3))
+
+	if len(sched.healthBacklog) != maxHealthBacklog {
+		t.Fatalf("backlog has %d events, want %d", len(sched.healthBacklog), maxHealthBacklog)
+	}
+	if sched.stats[1].HealthEventsDropped != 1 || sched.stats[2].HealthEventsDropped != 0 {
+		t.Errorf("dropped: device 1=%d device 2=%d, want 1 and 0", sched.stats[1].HealthEventsDropped, sched.stats[2].HealthEventsDropped)
+	}
+	if last := sched.healthBacklog[len(sched.healthBacklog)-1]; last.deviceID != 3 {
+		t.Errorf("newest event belongs to device %d, want 3", last.deviceID)
+	}
+	if status := sched.status(1); status.PendingHealth != maxHealthBacklog {
+		t.Errorf("status pending_health = %d, want %d", status.PendingHealth, maxHealthBacklog)
+	}
+}
@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"encoding/json"
+
+	"nms/pkg/models"
+)
+
+// filter is a compiled StreamFilter; nil sets match everything.
+type filter struct {
+	devices map[int64]bool
+	types   map[string]bool
+	paths   []string
+}
+
+func newFilter(f models.StreamFilter) *filter {
+	compiled := &filter{paths: f.Paths}
+	if len(f.DeviceIDs) > 0 {
+		compiled.devices = make(map[int64]bool, len(f.DeviceIDs))
+		for _, id := range f.DeviceIDs {
+			compiled.devices[id] = true
+		}
+	}
+	if len(f.Types) > 0 {
+		compiled.types = make(map[string]bool, len(f.Types))
+		for _, t := range f.Types {
+			compiled.types[t] = true
+		}
+	}
+	return compiled
+}
+
+// apply returns the event as the subscriber should see it, or nil when it does not match.
+// Poll results are reduced to the filter's paths; the shared event is never modified.
+func (f *filter) apply(event *models.StreamEvent) *models.StreamEvent {
+	if f.types != nil && !f.types[event.Type] {
+		return nil
+	}
+	if f.devices != nil && !f.devices[event.DeviceID] {
+		return nil
+	}
+	if len(f.paths) == 0 || event.Type != models.StreamPollResult {
+		return event
+	}
+
+	data, ok := event.Data.(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	values := make(map[string]json.RawMessage, len(f.paths))
+	for _, path := range f.paths {
+		if value := models.ExtractPath(data, path); value != nil {
+			values[path] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	reduced := *event
+	reduced.Data = values
+	return &reduced
+}
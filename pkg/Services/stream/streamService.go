@@ -0,0 +1,223 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"nms/pkg/models"
+)
+
+// ErrTooManySubscribers is returned when STREAM_MAX_SUBSCRIBERS streams are already open.
+var ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+// Subscription is an open stream as handed to the API. The API calls Close when the client
+// goes away; the service then drops the subscriber without needing a request.
+type Subscription struct {
+	ID     int64
+	Events <-chan *models.StreamEvent // Closed when the subscriber falls behind or the service stops
+	Replay []*models.StreamEvent      // Buffered events after LastEventID, oldest first
+	Gap    bool                       // Some events after LastEventID are no longer buffered
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Close marks the subscription as finished. It never blocks and may be called more than once.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// subscriber is an open stream as kept by the service.
+type subscriber struct {
+	filter *filter
+	events chan *models.StreamEvent
+	done   <-chan struct{} // Closed by Subscription.Close
+}
+
+// closed reports whether the API has finished with the subscriber.
+func (sub *subscriber) closed() bool {
+	select {
+	case <-sub.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// StreamService fans live events out to SSE subscribers. Every event gets the next ID and
+// enters a bounded replay buffer, so reconnecting clients resume from their Last-Event-ID.
+// Subscribers are never waited for: one whose buffer is full is disconnected and resumes
+// from the replay buffer when it reconnects.
+type StreamService struct {
+	events   <-chan models.StreamEvent // From MetricsService, EntityService and FailureService
+	requests <-chan models.Request
+
+	// Replay ring buffer; it holds the consecutive IDs lastID-replayLen+1 .. lastID
+	replay     []*models.StreamEvent
+	replayNext int
+	replayLen  int
+	lastID     uint64
+
+	subscribers      map[int64]*subscriber
+	nextSubscriberID int64
+	subscriberBuffer int
+	maxSubscribers   int
+}
+
+// NewStreamService creates a new StreamService instance.
+func NewStreamService(
+	events <-chan models.StreamEvent,
+	requests <-chan models.Request,
+	replaySize int,
+	subscriberBuffer int,
+	maxSubscribers int,
+) *StreamService {
+	return &StreamService{
+		events:           events,
+		requests:         requests,
+		replay:           make([]*models.StreamEvent, replaySize),
+		subscribers:      make(map[int64]*subscriber),
+		subscriberBuffer: subscriberBuffer,
+		maxSubscribers:   maxSubscribers,
+	}
+}
+
+// Publish hands an event to StreamService without blocking. A full channel drops the event;
+// live streams are best effort. A nil channel disables publishing.
+func Publish(ch chan<- models.StreamEvent, event models.StreamEvent) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- event:
+	default:
+		slog.Warn("Stream channel full, dropping event", "component", "StreamService", "type", event.Type, "device_id", event.DeviceID)
+	}
+}
+
+// Run starts the stream service's main loop.
+func (svc *StreamService) Run(ctx context.Context) {
+	slog.Info("Starting stream service", "component", "StreamService", "replay_size", len(svc.replay))
+
+	for {
+		select {
+		case <-ctx.Done():
+			for id := range svc.subscribers {
+				svc.unsubscribe(id)
+			}
+			slog.Info("Stopping stream service", "component", "StreamService")
+			return
+		case event := <-svc.events:
+			svc.publish(&event)
+		case req := <-svc.requests:
+			svc.handleRequest(req)
+		}
+	}
+}
+
+// publish numbers an event, buffers it for replay and offers it to every matching subscriber.
+func (svc *StreamService) publish(event *models.StreamEvent) {
+	svc.lastID++
+	event.ID = svc.lastID
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if len(svc.replay) > 0 {
+		svc.replay[svc.replayNext] = event
+		svc.replayNext = (svc.replayNext + 1) % len(svc.replay)
+		svc.replayLen = min(svc.replayLen+1, len(svc.replay))
+	}
+
+	for id, sub := range svc.subscribers {
+		if sub.closed() {
+			svc.unsubscribe(id)
+			continue
+		}
+		matched := sub.filter.apply(event)
+		if matched == nil {
+			continue
+		}
+		select {
+		case sub.events <- matched:
+		default:
+			slog.Warn("Stream subscriber too slow, disconnecting", "component", "StreamService", "subscriber_id", id)
+			svc.unsubscribe(id)
+		}
+	}
+}
+
+// handleRequest answers subscribe requests.
+func (svc *StreamService) handleRequest(req models.Request) {
+	var resp models.Response
+
+	switch req.Operation {
+	case models.OpStreamSubscribe:
+		subscribe, ok := req.Payload.(*models.StreamSubscribeRequest)
+		if !ok {
+			resp.Error = fmt.Errorf("invalid payload for stream subscription")
+			break
+		}
+		resp.Data, resp.Error = svc.subscribe(subscribe)
+	default:
+		resp.Error = fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+
+	req.ReplyCh <- resp
+}
+
+// subscribe registers a subscriber and collects its replay in the same step, so no event
+// is both replayed and delivered live, or lost between the two.
+func (svc *StreamService) subscribe(req *models.StreamSubscribeRequest) (*Subscription, error) {
+	// Closed subscribers that no event has reached yet still count until they are dropped here
+	for id, sub := range svc.subscribers {
+		if sub.closed() {
+			svc.unsubscribe(id)
+		}
+	}
+	if len(svc.subscribers) >= svc.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	svc.nextSubscriberID++
+	done := make(chan struct{})
+	sub := &subscriber{
+		filter: newFilter(req.Filter),
+		events: make(chan *models.StreamEvent, svc.subscriberBuffer),
+		done:   done,
+	}
+	svc.subscribers[svc.nextSubscriberID] = sub
+
+	subscription := &Subscription{ID: svc.nextSubscriberID, Events: sub.events, done: done}
+	if req.LastEventID == 0 {
+		return subscription, nil
+	}
+
+	// IDs restart after a restart, so an ID ahead of the newest one is a gap as well
+	oldestID := svc.lastID - uint64(svc.replayLen) + 1
+	subscription.Gap = req.LastEventID > svc.lastID || req.LastEventID+1 < oldestID
+
+	start := (svc.replayNext - svc.replayLen + len(svc.replay)) % max(len(svc.replay), 1)
+	for i := 0; i < svc.replayLen; i++ {
+		event := svc.replay[(start+i)%len(svc.replay)]
+		if event.ID <= req.LastEventID && !subscription.Gap {
+			continue
+		}
+		if matched := sub.filter.apply(event); matched != nil {
+			subscription.Replay = append(subscription.Replay, matched)
+		}
+	}
+	return subscription, nil
+}
+
+// unsubscribe closes a subscriber's channel and forgets it. Unknown IDs (already disconnected) are ignored.
+func (svc *StreamService) unsubscribe(id int64) {
+	if sub, exists := svc.subscribers[id]; exists {
+		close(sub.events)
+		delete(svc.subscribers, id)
+	}
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"nms/pkg/models"
+)
+
+func TestClosedSubscriptionsFreeTheirSlot(t *testing.T) {
+	svc := NewStreamService(nil, nil, 16, 4, 2)
+	narrow := &models.StreamSubscribeRequest{Filter: models.StreamFilter{DeviceIDs: []int64{42}}}
+
+	for i := 0; i < 3; i++ {
+		first, err := svc.subscribe(narrow)
+		if err != nil {
+			t.Fatalf("round %d: subscribe: %v", i, err)
+		}
+		second, err := svc.subscribe(narrow)
+		if err != nil {
+			t.Fatalf("round %d: subscribe: %v", i, err)
+		}
+		if _, err := svc.subscribe(narrow); !errors.Is(err, ErrTooManySubscribers) {
+			t.Fatalf("round %d: subscribe over the limit returned %v", i, err)
+		}
+		first.Close()
+		second.Close()
+		second.Close() // Closing twice is harmless
+	}
+}
+
+func TestPublishDropsClosedSubscriptions(t *testing.T) {
+	svc := NewStreamService(nil, nil, 16, 4, 2)
+	subscription, err := svc.subscribe(&models.StreamSubscribeRequest{})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	subscription.Close()
+
+	svc.publish(&models.StreamEvent{Type: models.StreamFailure, DeviceID: 1})
+	if len(svc.subscribers) != 0 {
+		t.Fatalf("%d subscribers left, want 0", len(svc.subscribers))
+	}
+	if _, ok := <-subscription.Events; ok {
+		t.Error("closed subscription received an event")
+	}
+}
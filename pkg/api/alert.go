@@ -0,0 +1,224 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Alert history page size
+const (
+	alertPageDefault = 50
+	alertPageMax     = 500
+)
+
+// RegisterAlertRoutes creates alert lifecycle and silence routes (rules use RegisterEntityRoutes)
+func RegisterAlertRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/alerts", listAlertsHandler(reqCh))
+	g.GET("/alerts/:id", alertRequestHandler(reqCh, models.OpGetAlert))
+	g.POST("/alerts/:id/acknowledge", alertRequestHandler(reqCh, models.OpAcknowledgeAlert))
+	g.POST("/alerts/:id/resolve", alertRequestHandler(reqCh, models.OpResolveAlert))
+
+	g.GET("/alert_silences", listSilencesHandler(reqCh))
+	g.POST("/alert_silences", createSilenceHandler(reqCh))
+	g.DELETE("/alert_silences/:id", expireSilenceHandler(reqCh))
+}
+
+// listAlertsHandler returns alert history, newest first.
+// Optional filters: ?state=&rule_id=&device_id=&severity=&start=&end= (RFC3339, on fired_at)
+// Pagination: ?limit= (default 50, max 500) and ?offset=
+func listAlertsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := &models.AlertListRequest{State: c.Query("state"), Severity: c.Query("severity")}
+		switch filter.State {
+		case "", models.AlertStateFiring, models.AlertStateAcknowledged, models.AlertStateSilenced, models.AlertStateResolved:
+		default:
+			respondError(c, http.StatusBadRequest, "state must be one of firing, acknowledged, silenced, resolved")
+			return
+		}
+
+		var err error
+		if filter.RuleID, err = parseInt64Param(c, "rule_id"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if filter.DeviceID, err = parseInt64Param(c, "device_id"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if filter.Start, err = parseTimeParam(c, "start"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if filter.End, err = parseTimeParam(c, "end"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(alertPageDefault)))
+		if err != nil || limit < 1 || limit > alertPageMax {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", alertPageMax))
+			return
+		}
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			respondError(c, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Limit, filter.Offset = limit, offset
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpListAlerts,
+			Payload:   filter,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// alertRequestHandler sends a single-alert operation on behalf of the logged-in user.
+// Unknown alerts are 404 and acknowledging or resolving an alert that is no longer open is 409.
+func alertRequestHandler(reqCh chan<- models.Request, operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid alert id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: operation,
+			ID:        id,
+			Payload:   currentUser(c),
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		switch {
+		case resp.Error == nil:
+			c.JSON(http.StatusOK, resp.Data)
+		case errors.Is(resp.Error, models.ErrAlertNotFound):
+			respondError(c, http.StatusNotFound, resp.Error.Error())
+		case errors.Is(resp.Error, models.ErrAlertNotOpen):
+			respondError(c, http.StatusConflict, resp.Error.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+		}
+	}
+}
+
+// listSilencesHandler returns silences that have not ended
+func listSilencesHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpListSilences,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// createSilenceHandler mutes matching alerts for duration_minutes, starting now
+func createSilenceHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body models.AlertSilenceRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		now := time.Now()
+		silence := &models.AlertSilence{
+			DeviceID:  body.DeviceID,
+			RuleID:    body.RuleID,
+			Label:     body.Label,
+			Comment:   body.Comment,
+			CreatedBy: currentUser(c),
+			StartsAt:  now,
+			EndsAt:    now.Add(time.Duration(body.DurationMinutes) * time.Minute),
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpCreateSilence,
+			Payload:   silence,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusCreated, resp.Data)
+	}
+}
+
+// expireSilenceHandler ends a silence now; muted alerts resume
+func expireSilenceHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid silence id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpExpireSilence,
+			ID:        id,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusNotFound, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// currentUser returns the username claim stored by JWTMiddleware
+func currentUser(c *gin.Context) string {
+	if username, ok := c.Get("username"); ok {
+		if name, ok := username.(string); ok && name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// parseInt64Param parses an optional integer query parameter; absent means 0
+func parseInt64Param(c *gin.Context, name string) (int64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return value, nil
+}
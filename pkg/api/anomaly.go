@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anomaly list size
+const (
+	anomalyLimitDefault = 100
+	anomalyLimitMax     = 1000
+)
+
+// RegisterAnomalyRoutes creates anomaly episode and baseline routes (served by AlertService)
+func RegisterAnomalyRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/anomalies", listAnomaliesHandler(reqCh))
+	g.GET("/devices/:id/baselines", deviceBaselinesHandler(reqCh))
+}
+
+// listAnomaliesHandler returns anomaly episodes, newest first.
+// Optional filters: ?device_id=&path=&active=true, and ?limit= (default 100, max 1000)
+func listAnomaliesHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := &models.AnomalyListRequest{Path: c.Query("path")}
+
+		var err error
+		if filter.DeviceID, err = parseInt64Param(c, "device_id"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if raw := c.Query("active"); raw != "" {
+			if filter.Active, err = strconv.ParseBool(raw); err != nil {
+				respondError(c, http.StatusBadRequest, "active must be true or false")
+				return
+			}
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(anomalyLimitDefault)))
+		if err != nil || limit < 1 || limit > anomalyLimitMax {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", anomalyLimitMax))
+			return
+		}
+		filter.Limit = limit
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpListAnomalies,
+			Payload:   filter,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// deviceBaselinesHandler returns the current rolling baselines of a device's tracked metrics
+func deviceBaselinesHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpGetBaselines,
+			ID:        id,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
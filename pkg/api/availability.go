@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAvailabilityRoutes creates availability (SLA) report routes.
+// Both accept ?start=&end= (RFC3339) and ?format=json|csv.
+func RegisterAvailabilityRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/availability/devices/:id", availabilityReportHandler(reqCh, false))
+	g.GET("/availability/discovery_profiles/:id", availabilityReportHandler(reqCh, true))
+}
+
+// availabilityReportHandler returns uptime, MTTR, MTBF and outages for a device or discovery profile
+func availabilityReportHandler(reqCh chan<- models.Request, byProfile bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			respondError(c, http.StatusBadRequest, "format must be 'json' or 'csv'")
+			return
+		}
+
+		query := &models.AvailabilityReportRequest{}
+		if byProfile {
+			query.DiscoveryProfileID = id
+		} else {
+			query.DeviceID = id
+		}
+		if query.Start, err = parseTimeParam(c, "start"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if query.End, err = parseTimeParam(c, "end"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpAvailabilityReport,
+			ID:        id,
+			Payload:   query,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
+
+		reports, _ := resp.Data.([]*models.AvailabilityReport)
+		if format == "csv" {
+			writeAvailabilityCSV(c, reports)
+			return
+		}
+		if !byProfile && len(reports) == 1 {
+			c.JSON(http.StatusOK, reports[0])
+			return
+		}
+		c.JSON(http.StatusOK, reports)
+	}
+}
+
+// parseTimeParam parses an optional RFC3339 query parameter
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return parsed, nil
+}
+
+// writeAvailabilityCSV writes one summary row per device as a CSV attachment
+func writeAvailabilityCSV(c *gin.Context, reports []*models.AvailabilityReport) {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="availability.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"device_id", "hostname", "start", "end", "uptime_percent",
+		"up_seconds", "down_seconds", "unreachable_seconds", "unknown_seconds",
+		"outage_count", "mttr_seconds", "mtbf_seconds",
+	})
+	for _, r := range reports {
+		w.Write([]string{
+			strconv.FormatInt(r.DeviceID, 10),
+			r.Hostname,
+			r.Start.Format(time.RFC3339),
+			r.End.Format(time.RFC3339),
+			formatOptionalFloat(r.UptimePercent),
+			strconv.FormatInt(r.UpSeconds, 10),
+			strconv.FormatInt(r.DownSeconds, 10),
+			strconv.FormatInt(r.UnreachableSeconds, 10),
+			strconv.FormatInt(r.UnknownSeconds, 10),
+			strconv.Itoa(r.OutageCount),
+			formatOptionalInt(r.MTTRSeconds),
+			formatOptionalInt(r.MTBFSeconds),
+		})
+	}
+	w.Flush()
+}
+
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 3, 64)
+}
+
+func formatOptionalInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetParentRequest represents the request body for assigning a device's parent
+type SetParentRequest struct {
+	ParentDeviceID int64 `json:"parent_device_id" binding:"required,min=1"`
+}
+
+// RegisterDependencyRoutes creates device dependency (parent/child) routes
+func RegisterDependencyRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.PUT("/devices/:id/parent", setParentHandler(reqCh))
+	g.DELETE("/devices/:id/parent", clearParentHandler(reqCh))
+	g.GET("/devices/:id/children", listChildrenHandler(reqCh))
+}
+
+// setParentHandler makes the device depend on another device
+func setParentHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		var req SetParentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		sendParentRequest(c, reqCh, id, &req.ParentDeviceID)
+	}
+}
+
+// clearParentHandler removes the device's parent
+func clearParentHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		sendParentRequest(c, reqCh, id, nil)
+	}
+}
+
+// sendParentRequest forwards a parent change to EntityService and writes the updated device
+func sendParentRequest(c *gin.Context, reqCh chan<- models.Request, id int64, parentID *int64) {
+	replyCh := make(chan models.Response, 1)
+	reqCh <- models.Request{
+		Operation:  models.OpSetParent,
+		EntityType: "Device",
+		ID:         id,
+		Payload:    parentID,
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	if resp.Error != nil {
+		respondError(c, http.StatusBadRequest, resp.Error.Error())
+		return
+	}
+	c.JSON(http.StatusOK, resp.Data)
+}
+
+// listChildrenHandler returns the devices that directly depend on a device
+func listChildrenHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation:  models.OpListChildren,
+			EntityType: "Device",
+			ID:         id,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusNotFound, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
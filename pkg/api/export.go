@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterExportRoutes creates metrics export admin routes
+func RegisterExportRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/admin/exporters", exporterStatsHandler(reqCh))
+}
+
+// exporterStatsHandler returns queue, lag and drop counters of every export sink
+func exporterStatsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpExportStats,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
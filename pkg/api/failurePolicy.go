@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssignPolicyRequest represents the request body for assigning a failure policy
+type AssignPolicyRequest struct {
+	FailurePolicyID int64 `json:"failure_policy_id" binding:"required,min=1"`
+}
+
+// RegisterFailurePolicyRoutes creates failure policy assignment and dry-run routes.
+// Policy CRUD itself is served by RegisterEntityRoutes.
+func RegisterFailurePolicyRoutes(g *gin.RouterGroup, crudCh chan<- models.Request, failureCh chan<- models.Request) {
+	g.POST("/failure_policies/dry_run", dryRunPolicyHandler(failureCh))
+	g.PUT("/devices/:id/failure_policy", assignPolicyHandler("Device", crudCh))
+	g.DELETE("/devices/:id/failure_policy", clearPolicyHandler("Device", crudCh))
+	g.PUT("/discovery_profiles/:id/failure_policy", assignPolicyHandler("DiscoveryProfile", crudCh))
+	g.DELETE("/discovery_profiles/:id/failure_policy", clearPolicyHandler("DiscoveryProfile", crudCh))
+}
+
+// assignPolicyHandler assigns a failure policy to a device or discovery profile
+func assignPolicyHandler(entityType string, reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var req AssignPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		sendAssignPolicyRequest(c, reqCh, entityType, id, &req.FailurePolicyID)
+	}
+}
+
+// clearPolicyHandler removes a failure policy assignment, falling back to the profile or default policy
+func clearPolicyHandler(entityType string, reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		sendAssignPolicyRequest(c, reqCh, entityType, id, nil)
+	}
+}
+
+// sendAssignPolicyRequest forwards an assignment to EntityService and writes the updated entity
+func sendAssignPolicyRequest(c *gin.Context, reqCh chan<- models.Request, entityType string, id int64, policyID *int64) {
+	replyCh := make(chan models.Response, 1)
+	reqCh <- models.Request{
+		Operation:  models.OpAssignFailurePolicy,
+		EntityType: entityType,
+		ID:         id,
+		Payload:    policyID,
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	if resp.Error != nil {
+		respondError(c, http.StatusBadRequest, resp.Error.Error())
+		return
+	}
+	c.JSON(http.StatusOK, resp.Data)
+}
+
+// dryRunPolicyHandler evaluates a stored or inline policy against current failure history without acting
+func dryRunPolicyHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PolicyDryRunRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpDryRunPolicy,
+			Payload:   &req,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
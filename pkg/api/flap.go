@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterFlapRoutes creates flap detection routes
+func RegisterFlapRoutes(g *gin.RouterGroup, crudCh chan<- models.Request, failureCh chan<- models.Request) {
+	g.GET("/devices/:id/flap", flapStatusHandler(failureCh))
+	g.GET("/devices/:id/flap_history", flapHistoryHandler(crudCh))
+}
+
+// flapStatusHandler returns the live flap score and recent checks of a device
+func flapStatusHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpGetFlapStatus,
+			ID:        id,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusNotFound, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// flapHistoryHandler returns when a device started and stopped flapping (newest first)
+func flapHistoryHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation:  models.OpListFlapHistory,
+			EntityType: "Device",
+			ID:         id,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
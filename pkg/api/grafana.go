@@ -0,0 +1,383 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nms/pkg/Services/persistence"
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Grafana JSON datasource limits
+const (
+	grafanaDefaultDataPoints = 1000
+	grafanaMaxDataPoints     = 10000 // MetricsService rejects step queries spanning more buckets
+	grafanaMaxSearchResults  = 1000
+	grafanaMaxAnnotations    = 1000
+)
+
+// grafanaSteps are the bucket widths a query step is rounded up to. Multiples of a rollup
+// tier's width let MetricsService read whole rollup buckets instead of raw rows.
+var grafanaSteps = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// grafanaRange is the dashboard time range sent with queries and annotation requests.
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// valid reports whether the range is set and ends after it starts.
+func (r grafanaRange) valid() bool {
+	return !r.From.IsZero() && r.To.After(r.From)
+}
+
+type grafanaSearchRequest struct {
+	Target string `json:"target"` // Device name filter, or "<device>:" followed by a path filter
+}
+
+type grafanaQueryRequest struct {
+	Range         grafanaRange `json:"range"`
+	IntervalMs    int64        `json:"intervalMs"`
+	MaxDataPoints int          `json:"maxDataPoints"`
+	Targets       []struct {
+		Target  string          `json:"target"` // <device>:<path>, device by hostname or ID; the path may contain "*" segments
+		Hide    bool            `json:"hide"`
+		Payload json.RawMessage `json:"payload"` // Optional {"aggregate": "max"}; avg by default
+	} `json:"targets"`
+}
+
+// grafanaSeries is one time series in the datasource's response: [value, unix ms] pairs.
+type grafanaSeries struct {
+	Target     string   `json:"target"`
+	Datapoints [][2]any `json:"datapoints"`
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange    `json:"range"`
+	Annotation json.RawMessage `json:"annotation"` // Echoed back with every event
+}
+
+// grafanaAnnotationEvent is a device status change shown on dashboards.
+type grafanaAnnotationEvent struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"` // Unix ms
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+// RegisterGrafanaRoutes creates the Grafana JSON datasource routes, guarded by their own
+// bearer token like the Prometheus scrape route. Nothing is registered without a token.
+func RegisterGrafanaRoutes(g *gin.RouterGroup, token string, metricsCh chan<- models.Request, crudCh chan<- models.Request) {
+	if token == "" {
+		return
+	}
+	grafana := g.Group("/grafana", BearerTokenMiddleware(token))
+	grafana.GET("/", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	grafana.POST("/search", grafanaSearchHandler(metricsCh, crudCh))
+	grafana.POST("/query", grafanaQueryHandler(metricsCh, crudCh))
+	grafana.POST("/annotations", grafanaAnnotationsHandler(crudCh))
+}
+
+// grafanaSearchHandler lists targets for the query editor: device names containing the
+// target, or "<device>:<path>" for the numeric paths of one device once the target names it.
+func grafanaSearchHandler(metricsCh chan<- models.Request, crudCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req grafanaSearchRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		devices, err := listDevices(crudCh)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		results := make([]string, 0)
+		name, pathFilter, hasPath := strings.Cut(req.Target, ":")
+		if !hasPath {
+			filter := strings.ToLower(req.Target)
+			for _, device := range devices {
+				if strings.Contains(strings.ToLower(deviceName(device)), filter) {
+					results = append(results, deviceName(device))
+				}
+			}
+			sort.Strings(results)
+		} else {
+			device := findDevice(devices, name)
+			if device == nil {
+				c.JSON(http.StatusOK, results)
+				return
+			}
+
+			replyCh := make(chan models.Response, 1)
+			metricsCh <- models.Request{
+				Operation:  models.OpListMetricPaths,
+				EntityType: "Metric",
+				ID:         device.ID,
+				ReplyCh:    replyCh,
+			}
+			resp := <-replyCh
+			if resp.Error != nil {
+				respondError(c, http.StatusInternalServerError, resp.Error.Error())
+				return
+			}
+			for _, path := range resp.Data.([]string) {
+				if strings.Contains(path, pathFilter) {
+					results = append(results, name+":"+path)
+				}
+			}
+		}
+
+		if len(results) > grafanaMaxSearchResults {
+			results = results[:grafanaMaxSearchResults]
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+// grafanaQueryHandler answers each "<device>:<path>" target with a step query bucketed to
+// the panel's interval. Wildcard paths yield one series per concrete path.
+func grafanaQueryHandler(metricsCh chan<- models.Request, crudCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req grafanaQueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !req.Range.valid() {
+			respondError(c, http.StatusBadRequest, "range.from and range.to are required, from before to")
+			return
+		}
+
+		devices, err := listDevices(crudCh)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		step := grafanaStep(req.Range, req.IntervalMs, req.MaxDataPoints)
+		series := make([]grafanaSeries, 0, len(req.Targets))
+		for _, target := range req.Targets {
+			if target.Hide || target.Target == "" {
+				continue
+			}
+			name, path, ok := strings.Cut(target.Target, ":")
+			if !ok || path == "" {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("target %q must be <device>:<path>", target.Target))
+				return
+			}
+			device := findDevice(devices, name)
+			if device == nil {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("target %q: unknown device %q", target.Target, name))
+				return
+			}
+
+			// The payload is free-form in the datasource's editor; anything but an object is ignored
+			var options struct {
+				Aggregate string `json:"aggregate"`
+			}
+			_ = json.Unmarshal(target.Payload, &options)
+
+			replyCh := make(chan models.Response, 1)
+			metricsCh <- models.Request{
+				Operation:  models.OpQuery,
+				EntityType: "Metric",
+				Payload: &persistence.MetricQueryRequest{
+					DeviceIDs: []int64{device.ID},
+					Query: models.MetricQuery{
+						Path:      path,
+						Start:     req.Range.From,
+						End:       req.Range.To,
+						Step:      step.String(),
+						Aggregate: options.Aggregate,
+					},
+				},
+				ReplyCh: replyCh,
+			}
+
+			resp := <-replyCh
+			if errors.Is(resp.Error, persistence.ErrInvalidMetricQuery) {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("target %q: %v", target.Target, resp.Error))
+				return
+			}
+			if resp.Error != nil {
+				respondError(c, http.StatusInternalServerError, resp.Error.Error())
+				return
+			}
+
+			switch data := resp.Data.(type) {
+			case []*persistence.MetricSeries:
+				series = append(series, toGrafanaSeries(target.Target, data))
+			case map[string]any:
+				paths := make([]string, 0, len(data))
+				for concrete := range data {
+					paths = append(paths, concrete)
+				}
+				sort.Strings(paths)
+				for _, concrete := range paths {
+					perPath, _ := data[concrete].([]*persistence.MetricSeries)
+					series = append(series, toGrafanaSeries(name+":"+concrete, perPath))
+				}
+			}
+		}
+		c.JSON(http.StatusOK, series)
+	}
+}
+
+// grafanaAnnotationsHandler shows device status changes within the range. The annotation's
+// query optionally limits them to a comma-separated list of devices.
+func grafanaAnnotationsHandler(crudCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req grafanaAnnotationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !req.Range.valid() {
+			respondError(c, http.StatusBadRequest, "range.from and range.to are required, from before to")
+			return
+		}
+		var annotation struct {
+			Query string `json:"query"`
+		}
+		_ = json.Unmarshal(req.Annotation, &annotation)
+
+		devices, err := listDevices(crudCh)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		query := &models.TransitionQuery{Start: req.Range.From, End: req.Range.To, Limit: grafanaMaxAnnotations}
+		for _, name := range strings.Split(annotation.Query, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			device := findDevice(devices, name)
+			if device == nil {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("unknown device %q", name))
+				return
+			}
+			query.DeviceIDs = append(query.DeviceIDs, device.ID)
+		}
+
+		replyCh := make(chan models.Response, 1)
+		crudCh <- models.Request{
+			Operation:  models.OpSearchTransitions,
+			EntityType: "Device",
+			Payload:    query,
+			ReplyCh:    replyCh,
+		}
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+
+		names := make(map[int64]string, len(devices))
+		for _, device := range devices {
+			names[device.ID] = deviceName(device)
+		}
+		transitions := resp.Data.([]*models.DeviceTransition)
+		events := make([]grafanaAnnotationEvent, 0, len(transitions))
+		for _, transition := range transitions {
+			name, ok := names[transition.DeviceID]
+			if !ok {
+				name = strconv.FormatInt(transition.DeviceID, 10)
+			}
+			events = append(events, grafanaAnnotationEvent{
+				Annotation: req.Annotation,
+				Time:       transition.CreatedAt.UnixMilli(),
+				Title:      fmt.Sprintf("%s: %s → %s", name, transition.FromStatus, transition.ToStatus),
+				Text:       transition.Reason,
+				Tags:       []string{name, transition.ToStatus},
+			})
+		}
+		c.JSON(http.StatusOK, events)
+	}
+}
+
+// grafanaStep picks the bucket width for a panel: at least its interval, few enough buckets
+// for maxDataPoints, rounded up to the next of grafanaSteps (or whole days beyond them).
+func grafanaStep(r grafanaRange, intervalMs int64, maxDataPoints int) time.Duration {
+	if maxDataPoints <= 0 {
+		maxDataPoints = grafanaDefaultDataPoints
+	}
+	maxDataPoints = min(maxDataPoints, grafanaMaxDataPoints)
+
+	step := max(time.Duration(intervalMs)*time.Millisecond, r.To.Sub(r.From)/time.Duration(maxDataPoints))
+	for _, candidate := range grafanaSteps {
+		if step <= candidate {
+			return candidate
+		}
+	}
+	day := grafanaSteps[len(grafanaSteps)-1]
+	return (step + day - 1) / day * day
+}
+
+// toGrafanaSeries flattens the series of a step query into [value, unix ms] datapoints.
+func toGrafanaSeries(target string, series []*persistence.MetricSeries) grafanaSeries {
+	out := grafanaSeries{Target: target, Datapoints: make([][2]any, 0)}
+	for _, s := range series {
+		for _, point := range s.Points {
+			out.Datapoints = append(out.Datapoints, [2]any{point.Value, point.Timestamp.UnixMilli()})
+		}
+	}
+	return out
+}
+
+// listDevices fetches every device from EntityService.
+func listDevices(crudCh chan<- models.Request) ([]*models.Device, error) {
+	replyCh := make(chan models.Response, 1)
+	crudCh <- models.Request{
+		Operation:  models.OpList,
+		EntityType: "Device",
+		ReplyCh:    replyCh,
+	}
+	resp := <-replyCh
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Data.([]*models.Device), nil
+}
+
+// deviceName is how targets name a device: its hostname, or its ID when it has none.
+func deviceName(device *models.Device) string {
+	if device.Hostname != "" {
+		return device.Hostname
+	}
+	return strconv.FormatInt(device.ID, 10)
+}
+
+// findDevice resolves a target's device by hostname, then by ID.
+func findDevice(devices []*models.Device, name string) *models.Device {
+	for _, device := range devices {
+		if device.Hostname != "" && device.Hostname == name {
+			return device
+		}
+	}
+	if id, err := strconv.ParseInt(name, 10, 64); err == nil {
+		for _, device := range devices {
+			if device.ID == id {
+				return device
+			}
+		}
+	}
+	return nil
+}
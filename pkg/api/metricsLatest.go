@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nms/pkg/Services/persistence"
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterLatestMetricsRoutes creates the current-value routes, answered from the
+// MetricsService latest-value cache
+func RegisterLatestMetricsRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/devices/:id/metrics/latest", deviceLatestHandler(reqCh))
+	g.POST("/metrics/latest", latestMetricsHandler(reqCh))
+}
+
+// deviceLatestHandler returns a device's newest poll result, or the value at ?path=
+func deviceLatestHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		values, ok := requestLatest(c, reqCh, &models.LatestQuery{DeviceIDs: []int64{id}, Path: c.Query("path")})
+		if !ok {
+			return
+		}
+		if len(values) == 0 {
+			respondError(c, http.StatusNotFound, "no poll result stored for device")
+			return
+		}
+		c.JSON(http.StatusOK, values[0])
+	}
+}
+
+// latestMetricsHandler returns the newest poll results of device_ids and/or the devices of
+// discovery_profile_id, or their values at path. Devices without a result are left out.
+func latestMetricsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.LatestQuery
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(req.DeviceIDs) == 0 && req.DiscoveryProfileID == 0 {
+			respondError(c, http.StatusBadRequest, "device_ids or discovery_profile_id is required")
+			return
+		}
+
+		values, ok := requestLatest(c, reqCh, &req)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, values)
+	}
+}
+
+// requestLatest asks MetricsService for cached results, responding with the error if it fails.
+func requestLatest(c *gin.Context, reqCh chan<- models.Request, query *models.LatestQuery) ([]*persistence.LatestValue, bool) {
+	replyCh := make(chan models.Response, 1)
+	reqCh <- models.Request{
+		Operation:  models.OpGetLatest,
+		EntityType: "Metric",
+		Payload:    query,
+		ReplyCh:    replyCh,
+	}
+
+	resp := <-replyCh
+	if errors.Is(resp.Error, persistence.ErrInvalidMetricQuery) {
+		respondError(c, http.StatusBadRequest, resp.Error.Error())
+		return nil, false
+	}
+	if resp.Error != nil {
+		respondError(c, http.StatusInternalServerError, resp.Error.Error())
+		return nil, false
+	}
+	return resp.Data.([]*persistence.LatestValue), true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nms/pkg/Services/persistence"
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Top-N ranking size and window
+const (
+	topNDefault      = 10
+	topNMax          = 100
+	topWindowDefault = time.Hour
+	topWindowMin     = time.Minute
+)
+
+// RegisterTopMetricsRoutes creates the top-N ranking route (ranked by MetricsService,
+// hostname and IP from the EntityService device cache)
+func RegisterTopMetricsRoutes(g *gin.RouterGroup, metricsCh chan<- models.Request, crudCh chan<- models.Request) {
+	g.GET("/metrics/top", topMetricsHandler(metricsCh, crudCh))
+}
+
+// topMetricsHandler ranks devices by a path aggregated over a trailing window.
+// ?path= (required), ?aggregate=avg|min|max|last (default avg), ?window= (Go duration,
+// default 1h), ?n= (default 10, max 100), ?order=desc|asc (default desc), ?discovery_profile_id=
+func topMetricsHandler(metricsCh chan<- models.Request, crudCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		top := &models.TopMetricsRequest{
+			Path:      c.Query("path"),
+			Aggregate: c.DefaultQuery("aggregate", "avg"),
+			Order:     c.DefaultQuery("order", "desc"),
+		}
+		if top.Path == "" {
+			respondError(c, http.StatusBadRequest, "path is required")
+			return
+		}
+
+		var err error
+		top.Window, err = time.ParseDuration(c.DefaultQuery("window", topWindowDefault.String()))
+		if err != nil || top.Window < topWindowMin {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("window must be a duration of at least %s, e.g. \"1h\"", topWindowMin))
+			return
+		}
+		top.N, err = strconv.Atoi(c.DefaultQuery("n", strconv.Itoa(topNDefault)))
+		if err != nil || top.N < 1 || top.N > topNMax {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("n must be between 1 and %d", topNMax))
+			return
+		}
+		if top.DiscoveryProfileID, err = parseInt64Param(c, "discovery_profile_id"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		metricsCh <- models.Request{
+			Operation:  models.OpTopMetrics,
+			EntityType: "Metric",
+			Payload:    top,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if errors.Is(resp.Error, persistence.ErrInvalidMetricQuery) {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+
+		result := resp.Data.(*persistence.TopMetricsResult)
+		ids := make([]int64, 0, len(result.Entries))
+		for _, entry := range result.Entries {
+			ids = append(ids, entry.DeviceID)
+		}
+		if len(ids) > 0 {
+			crudCh <- models.Request{
+				Operation:  models.OpLookupDevices,
+				EntityType: "Device",
+				IDs:        ids,
+				ReplyCh:    replyCh,
+			}
+			devices, _ := (<-replyCh).Data.(map[int64]models.Device)
+			for _, entry := range result.Entries {
+				if device, ok := devices[entry.DeviceID]; ok {
+					entry.Hostname, entry.IPAddress = device.Hostname, device.IPAddress
+				}
+			}
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
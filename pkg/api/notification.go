@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Delivery log page size
+const (
+	deliveryPageDefault = 100
+	deliveryPageMax     = 1000
+)
+
+// RegisterNotificationRoutes creates channel test and delivery log routes
+// (channels and routes themselves use RegisterEntityRoutes)
+func RegisterNotificationRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.POST("/notification_channels/:id/test", testChannelHandler(reqCh))
+	g.GET("/notification_deliveries", listDeliveriesHandler(reqCh))
+	g.POST("/notification_deliveries/:id/retry", retryDeliveryHandler(reqCh))
+}
+
+// testChannelHandler sends a sample message through a channel and reports the send error, if any
+func testChannelHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid channel id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpTestChannel,
+			ID:        id,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusBadGateway, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "test notification sent"})
+	}
+}
+
+// listDeliveriesHandler returns the delivery log, newest first.
+// Optional filters: ?status=pending|sent|retrying|failed&channel_id=&limit=
+func listDeliveriesHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := &models.DeliveryListRequest{Status: c.Query("status")}
+		switch filter.Status {
+		case "", models.DeliveryPending, models.DeliverySent, models.DeliveryRetrying, models.DeliveryFailed:
+		default:
+			respondError(c, http.StatusBadRequest, "status must be one of pending, sent, retrying, failed")
+			return
+		}
+
+		var err error
+		if filter.ChannelID, err = parseInt64Param(c, "channel_id"); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(deliveryPageDefault)))
+		if err != nil || filter.Limit < 1 || filter.Limit > deliveryPageMax {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", deliveryPageMax))
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpListDeliveries,
+			Payload:   filter,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// retryDeliveryHandler queues a failed delivery for an immediate attempt
+func retryDeliveryHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid delivery id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpRetryDelivery,
+			ID:        id,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusConflict, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusAccepted, resp.Data)
+	}
+}
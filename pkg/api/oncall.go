@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOnCallRoutes creates on-call lookup routes (schedules, overrides and
+// escalation policies use RegisterEntityRoutes)
+func RegisterOnCallRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/oncall_schedules/:id/oncall", onCallHandler(reqCh))
+}
+
+// onCallHandler returns who is on call for a schedule at ?at= (RFC3339, default now)
+func onCallHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid schedule id")
+			return
+		}
+
+		at, err := parseTimeParam(c, "at")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if at.IsZero() {
+			at = time.Now()
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpResolveOnCall,
+			ID:        id,
+			Payload:   at,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusNotFound, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
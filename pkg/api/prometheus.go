@@ -0,0 +1,70 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"nms/pkg/Services/exporter"
+	"nms/pkg/Services/persistence"
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusContentType is the text exposition format served to scrapers.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// RegisterPrometheusRoutes creates the Prometheus scrape route, guarded by its own bearer
+// token instead of a JWT so scrapers need no login. Nothing is registered without a token.
+func RegisterPrometheusRoutes(g *gin.RouterGroup, token string, maxAge time.Duration, mapper *exporter.Mapper, reqCh chan<- models.Request) {
+	if token == "" {
+		return
+	}
+	g.GET("/metrics/devices", BearerTokenMiddleware(token), prometheusDevicesHandler(maxAge, mapper, reqCh))
+}
+
+// BearerTokenMiddleware accepts requests whose Authorization header carries a static token,
+// for clients such as Prometheus and Grafana that can't log in for a JWT.
+func BearerTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" ||
+			subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+			respondError(c, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		c.Next()
+	}
+}
+
+// prometheusDevicesHandler exposes the newest poll result of every monitored device
+func prometheusDevicesHandler(maxAge time.Duration, mapper *exporter.Mapper, reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation:  models.OpLatestMetrics,
+			EntityType: "Metric",
+			Payload:    maxAge,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+
+		var samples []exporter.Sample
+		for _, latest := range resp.Data.([]*persistence.LatestMetric) {
+			samples = append(samples, mapper.DeviceSamples(latest.DeviceID, latest.Hostname, latest.PluginID, latest.Timestamp, latest.Data)...)
+		}
+
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", prometheusContentType)
+		if err := exporter.WritePrometheus(c.Writer, samples); err != nil {
+			c.Error(err)
+		}
+	}
+}
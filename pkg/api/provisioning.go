@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"nms/pkg/models"
 
@@ -33,6 +35,32 @@ func RunDiscoveryHandler(eventChan chan<- models.Event) gin.HandlerFunc {
 	}
 }
 
+// DeviceTransitionsHandler returns the recorded status transitions of a device (newest first)
+func DeviceTransitionsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation:  models.OpListTransitions,
+			EntityType: "Device",
+			ID:         id,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
 // ProvisionRequest represents the request body for device activation
 type ProvisionRequest struct {
 	PollingIntervalSeconds int `json:"polling_interval_seconds" binding:"required,min=60,max=3600"`
@@ -69,3 +97,49 @@ func ProvisionDeviceHandler(provisionCh chan<- models.Event) gin.HandlerFunc {
 		})
 	}
 }
+
+// PollNowHandler polls a device immediately through the Poller and returns the plugin result.
+// With ?store=true the result is also written through MetricsService.
+// Unknown devices and plugins are 404, a plugin that fails is 502 (with the report), a timeout is 504.
+func PollNowHandler(pollCh chan<- models.Request, timeoutSec int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid device id")
+			return
+		}
+
+		store, _ := strconv.ParseBool(c.DefaultQuery("store", "false"))
+
+		timeout := time.Duration(timeoutSec) * time.Second
+		replyCh := make(chan models.Response, 1)
+		pollCh <- models.Request{
+			Operation:  models.OpPollNow,
+			EntityType: "Device",
+			ID:         id,
+			Payload:    &models.PollNowRequest{Store: store, Timeout: timeout},
+			ReplyCh:    replyCh,
+		}
+
+		select {
+		case resp := <-replyCh:
+			switch {
+			case resp.Error == nil:
+				c.JSON(http.StatusOK, resp.Data)
+			case errors.Is(resp.Error, models.ErrDeviceNotFound), errors.Is(resp.Error, models.ErrPluginNotFound):
+				respondError(c, http.StatusNotFound, resp.Error.Error())
+			case errors.Is(resp.Error, models.ErrPollTimeout):
+				respondError(c, http.StatusGatewayTimeout, resp.Error.Error())
+			case errors.Is(resp.Error, models.ErrPluginFailed) && resp.Data != nil:
+				c.JSON(http.StatusBadGateway, resp.Data)
+			default:
+				respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			}
+		case <-time.After(timeout + time.Second):
+			// Fallback when the Poller itself doesn't answer in time
+			respondError(c, http.StatusGatewayTimeout, "poll timed out")
+		case <-c.Request.Context().Done():
+			// Client went away; the buffered reply is discarded
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pollNowStatus serves one POST /devices/7/poll with a Poller that replies resp.
+func pollNowStatus(t *testing.T, resp models.Response) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	pollCh := make(chan models.Request, 1)
+	go func() {
+		req := <-pollCh
+		req.ReplyCh <- resp
+	}()
+
+	router := gin.New()
+	router.POST("/devices/:id/poll", PollNowHandler(pollCh, 5))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/devices/7/poll", nil))
+	return recorder.Code
+}
+
+func TestPollNowHandlerStatusCodes(t *testing.T) {
+	report := &models.PollNowResponse{DeviceID: 7, Error: "exit status 1"}
+
+	for name, tc := range map[string]struct {
+		resp models.Response
+		want int
+	}{
+		"ok":               {models.Response{Data: report}, http.StatusOK},
+		"device not found": {models.Response{Error: fmt.Errorf("%w: 7", models.ErrDeviceNotFound)}, http.StatusNotFound},
+		"plugin not found": {models.Response{Error: fmt.Errorf("%w: \"snmp\"", models.ErrPluginNotFound)}, http.StatusNotFound},
+		"timeout":          {models.Response{Error: fmt.Errorf("%w: 30s", models.ErrPollTimeout)}, http.StatusGatewayTimeout},
+		"plugin failed":    {models.Response{Data: report, Error: fmt.Errorf("%w: exit status 1", models.ErrPluginFailed)}, http.StatusBadGateway},
+		"internal":         {models.Response{Error: fmt.Errorf("failed to get device 7: connection refused")}, http.StatusInternalServerError},
+	} {
+		if got := pollNowStatus(t, tc.resp); got != tc.want {
+			t.Errorf("%s: status %d, want %d", name, got, tc.want)
+		}
+	}
+}
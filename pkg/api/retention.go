@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRetentionRoutes creates metrics storage admin routes
+func RegisterRetentionRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/admin/metrics/partitions", listPartitionsHandler(reqCh))
+}
+
+// listPartitionsHandler returns every metrics partition with its range and size
+func listPartitionsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpListPartitions,
+			ReplyCh:   replyCh,
+		}
+
+		resp := <-replyCh
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"nms/pkg/Services/persistence"
 	"strconv"
@@ -26,18 +27,36 @@ func RegisterEntityRoutes[T any](
 	r.DELETE("/:id", deleteHandler(entityType, reqCh))
 }
 
-// RegisterMetricsRoute creates metrics query route
+// RegisterMetricsRoute creates metrics query and search routes
 func RegisterMetricsRoute(g *gin.RouterGroup, reqCh chan<- models.Request) {
 	g.POST("/metrics", metricsHandler(reqCh))
+	g.POST("/metrics/search", metricsSearchHandler(reqCh))
 }
 
+// hiddenValue replaces sensitive fields in responses
+const hiddenValue = "[HIDDEN]"
+
 // maskCredentialPayload hides sensitive payload data
 func maskCredentialPayload(cred *models.CredentialProfile) {
 	if cred != nil {
-		cred.Payload = "[HIDDEN]"
+		cred.Payload = hiddenValue
 	}
 }
 
+// maskChannelSecret hides a notification channel's signing secret
+func maskChannelSecret(channel *models.NotificationChannel) {
+	if channel != nil && channel.Secret != "" {
+		channel.Secret = hiddenValue
+	}
+}
+
+// keepsChannelSecret reports whether an update leaves a notification channel's secret as stored:
+// it is omitted, or still the masked value a client read back from GET.
+func keepsChannelSecret(entity any) bool {
+	channel, ok := entity.(*models.NotificationChannel)
+	return ok && (channel.Secret == "" || channel.Secret == hiddenValue)
+}
+
 // listHandler returns all entities
 func listHandler[T any](entityType string, encryptionKey string, reqCh chan<- models.Request) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -63,6 +82,9 @@ func listHandler[T any](entityType string, encryptionKey string, reqCh chan<- mo
 				if cred, ok := any(&dec).(*models.CredentialProfile); ok {
 					maskCredentialPayload(cred)
 				}
+				if channel, ok := any(&dec).(*models.NotificationChannel); ok {
+					maskChannelSecret(channel)
+				}
 				decryptedItems[i] = &dec
 			}
 			c.JSON(http.StatusOK, decryptedItems)
@@ -102,6 +124,9 @@ func getHandler[T any](entityType string, encryptionKey string, reqCh chan<- mod
 			if cred, ok := any(&dec).(*models.CredentialProfile); ok {
 				maskCredentialPayload(cred)
 			}
+			if channel, ok := any(&dec).(*models.NotificationChannel); ok {
+				maskChannelSecret(channel)
+			}
 			c.JSON(http.StatusOK, &dec)
 			return
 		}
@@ -117,6 +142,10 @@ func createHandler[T any](entityType string, encryptionKey string, reqCh chan<-
 			respondError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if channel, ok := any(&entity).(*models.NotificationChannel); ok && channel.Secret == hiddenValue {
+			respondError(c, http.StatusBadRequest, "secret must be the signing key, not the masked value")
+			return
+		}
 
 		// Encrypt sensitive fields if present
 		encryptedEntity, err := EncryptStruct(entity, encryptionKey)
@@ -142,6 +171,9 @@ func createHandler[T any](entityType string, encryptionKey string, reqCh chan<-
 		// Decrypt for response
 		if item, ok := resp.Data.(*T); ok {
 			dec, _ := DecryptStruct(*item, encryptionKey)
+			if channel, ok := any(&dec).(*models.NotificationChannel); ok {
+				maskChannelSecret(channel)
+			}
 			c.JSON(http.StatusCreated, &dec)
 			return
 		}
@@ -164,12 +196,18 @@ func updateHandler[T any](entityType string, encryptionKey string, reqCh chan<-
 			return
 		}
 
+		keepSecret := keepsChannelSecret(&entity)
+
 		// Encrypt sensitive fields if present
 		encryptedEntity, err := EncryptStruct(entity, encryptionKey)
 		if err != nil {
 			respondError(c, http.StatusInternalServerError, "encryption failed: "+err.Error())
 			return
 		}
+		if keepSecret {
+			// Even an empty secret encrypts to a value, so clear it to leave the column out of the UPDATE
+			any(&encryptedEntity).(*models.NotificationChannel).Secret = ""
+		}
 
 		replyCh := make(chan models.Response, 1)
 		reqCh <- models.Request{
@@ -189,6 +227,9 @@ func updateHandler[T any](entityType string, encryptionKey string, reqCh chan<-
 		// Decrypt for response
 		if item, ok := resp.Data.(*T); ok {
 			dec, _ := DecryptStruct(*item, encryptionKey)
+			if channel, ok := any(&dec).(*models.NotificationChannel); ok {
+				maskChannelSecret(channel)
+			}
 			c.JSON(http.StatusOK, &dec)
 			return
 		}
@@ -222,9 +263,11 @@ func deleteHandler(entityType string, reqCh chan<- models.Request) gin.HandlerFu
 	}
 }
 
-// BatchMetricQuery represents a batch query for metrics
+// BatchMetricQuery represents a batch query for metrics.
+// Devices are device_ids plus every device of discovery_profile_id; at least one is required.
 type BatchMetricQuery struct {
-	DeviceIDs []int64 `json:"device_ids" binding:"required"`
+	DeviceIDs          []int64 `json:"device_ids"`
+	DiscoveryProfileID int64   `json:"discovery_profile_id"`
 	models.MetricQuery
 }
 
@@ -237,8 +280,8 @@ func metricsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
 			return
 		}
 
-		if len(req.DeviceIDs) == 0 {
-			respondError(c, http.StatusBadRequest, "device_ids is required")
+		if len(req.DeviceIDs) == 0 && req.DiscoveryProfileID == 0 {
+			respondError(c, http.StatusBadRequest, "device_ids or discovery_profile_id is required")
 			return
 		}
 
@@ -247,13 +290,50 @@ func metricsHandler(reqCh chan<- models.Request) gin.HandlerFunc {
 			Operation:  models.OpQuery,
 			EntityType: "Metric",
 			Payload: &persistence.MetricQueryRequest{
-				DeviceIDs: req.DeviceIDs,
-				Query:     req.MetricQuery,
+				DeviceIDs:          req.DeviceIDs,
+				DiscoveryProfileID: req.DiscoveryProfileID,
+				Query:              req.MetricQuery,
 			},
 			ReplyCh: replyCh,
 		}
 
 		resp := <-replyCh
+		if errors.Is(resp.Error, persistence.ErrInvalidMetricQuery) {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		c.JSON(http.StatusOK, resp.Data)
+	}
+}
+
+// metricsSearchHandler finds samples whose value at a path satisfies a comparison,
+// e.g. memory.free lt 1e9 over the last hour. Without device_ids or discovery_profile_id
+// every device is searched.
+func metricsSearchHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MetricSearch
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation:  models.OpSearchMetrics,
+			EntityType: "Metric",
+			Payload:    &req,
+			ReplyCh:    replyCh,
+		}
+
+		resp := <-replyCh
+		if errors.Is(resp.Error, persistence.ErrInvalidMetricQuery) {
+			respondError(c, http.StatusBadRequest, resp.Error.Error())
+			return
+		}
 		if resp.Error != nil {
 			respondError(c, http.StatusInternalServerError, resp.Error.Error())
 			return
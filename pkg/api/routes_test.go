@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nms/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testEncryptionKey = "1234567890123456789012345678901212345678901234567890123456789012"
+
+// channelRequest serves one request against the notification channel routes. The fake
+// EntityService echoes the payload it receives, which is returned for inspection.
+func channelRequest(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, *models.NotificationChannel) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	reqCh := make(chan models.Request, 1)
+	received := make(chan *models.NotificationChannel, 1)
+	go func() {
+		req := <-reqCh
+		channel, _ := req.Payload.(*models.NotificationChannel)
+		received <- channel
+		stored := *channel
+		if stored.Secret == "" {
+			stored.Secret, _ = encryptSecret("stored-key")
+		}
+		req.ReplyCh <- models.Response{Data: &stored}
+	}()
+
+	router := gin.New()
+	RegisterEntityRoutes[models.NotificationChannel](router.Group(""), "/notification_channels", "NotificationChannel", testEncryptionKey, reqCh)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	select {
+	case channel := <-received:
+		return recorder, channel
+	default:
+		return recorder, nil
+	}
+}
+
+func encryptSecret(secret string) (string, error) {
+	channel, err := EncryptStruct(models.NotificationChannel{Secret: secret}, testEncryptionKey)
+	return channel.Secret, err
+}
+
+func responseSecret(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var channel models.NotificationChannel
+	if err := json.Unmarshal(recorder.Body.Bytes(), &channel); err != nil {
+		t.Fatalf("invalid response %s: %v", recorder.Body, err)
+	}
+	return channel.Secret
+}
+
+func TestUpdateChannelKeepsStoredSecret(t *testing.T) {
+	for name, secret := range map[string]string{"omitted": "", "masked": `,"secret":"[HIDDEN]"`} {
+		recorder, payload := channelRequest(t, http.MethodPut, "/notification_channels/1",
+			`{"name":"ops","type":"webhook","url":"http://hook"`+secret+`}`)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", name, recorder.Code, recorder.Body)
+		}
+		if payload.Secret != "" {
+			t.Errorf("%s: update would overwrite the stored secret with %q", name, payload.Secret)
+		}
+		if got := responseSecret(t, recorder); got != hiddenValue {
+			t.Errorf("%s: response secret = %q, want it masked", name, got)
+		}
+	}
+}
+
+func TestUpdateChannelReplacesSecret(t *testing.T) {
+	recorder, payload := channelRequest(t, http.MethodPut, "/notification_channels/1",
+		`{"name":"ops","type":"webhook","url":"http://hook","secret":"new-key"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	decrypted, err := DecryptStruct(*payload, testEncryptionKey)
+	if err != nil || decrypted.Secret != "new-key" {
+		t.Errorf("stored secret = %q (%v), want new-key", decrypted.Secret, err)
+	}
+	if got := responseSecret(t, recorder); got != hiddenValue {
+		t.Errorf("response secret = %q, want it masked", got)
+	}
+}
+
+func TestCreateChannel(t *testing.T) {
+	recorder, payload := channelRequest(t, http.MethodPost, "/notification_channels",
+		`{"name":"ops","type":"webhook","url":"http://hook","secret":"[HIDDEN]"}`)
+	if recorder.Code != http.StatusBadRequest || payload != nil {
+		t.Errorf("masked secret on create: status %d, want 400 without reaching EntityService", recorder.Code)
+	}
+
+	recorder, _ = channelRequest(t, http.MethodPost, "/notification_channels",
+		`{"name":"ops","type":"webhook","url":"http://hook","secret":"key"}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	if got := responseSecret(t, recorder); got != hiddenValue {
+		t.Errorf("response secret = %q, want it masked", got)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nms/pkg/Services/stream"
+	"nms/pkg/models"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	streamHeartbeat = 15 * time.Second // Keeps proxies from closing idle streams
+	maxStreamPaths  = 20
+)
+
+// streamTypes are the event types a stream may be filtered to.
+var streamTypes = map[string]bool{
+	models.StreamPollResult:   true,
+	models.StreamDeviceStatus: true,
+	models.StreamDiscovery:    true,
+	models.StreamFailure:      true,
+}
+
+// RegisterStreamRoutes creates the live event stream route
+func RegisterStreamRoutes(g *gin.RouterGroup, reqCh chan<- models.Request) {
+	g.GET("/stream", streamHandler(reqCh))
+}
+
+// streamHandler streams live events as Server-Sent Events, filtered by the comma-separated
+// device_ids, types and paths parameters. A client resuming with Last-Event-ID (or
+// ?last_event_id=) first gets the buffered events it missed; a "gap" event tells it that
+// some of them were no longer buffered.
+func streamHandler(reqCh chan<- models.Request) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subscribe, err := parseStreamRequest(c)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		replyCh := make(chan models.Response, 1)
+		reqCh <- models.Request{
+			Operation: models.OpStreamSubscribe,
+			Payload:   subscribe,
+			ReplyCh:   replyCh,
+		}
+		resp := <-replyCh
+		if errors.Is(resp.Error, stream.ErrTooManySubscribers) {
+			respondError(c, http.StatusServiceUnavailable, resp.Error.Error())
+			return
+		}
+		if resp.Error != nil {
+			respondError(c, http.StatusInternalServerError, resp.Error.Error())
+			return
+		}
+		subscription := resp.Data.(*stream.Subscription)
+		// Never blocks, even after shutdown; StreamService drops the subscriber on its next event or subscribe
+		defer subscription.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+		c.Status(http.StatusOK)
+
+		if subscription.Gap {
+			c.Render(-1, sse.Event{Event: "gap", Data: gin.H{"last_event_id": subscribe.LastEventID}})
+		}
+		for _, event := range subscription.Replay {
+			writeStreamEvent(c, event)
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case event, ok := <-subscription.Events:
+				if !ok {
+					return // Too slow or shutting down; the client reconnects with Last-Event-ID
+				}
+				writeStreamEvent(c, event)
+			case <-heartbeat.C:
+				fmt.Fprint(c.Writer, ": ping\n\n")
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeStreamEvent writes one event, named after its type and carrying its ID for resumption.
+func writeStreamEvent(c *gin.Context, event *models.StreamEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
+
+// parseStreamRequest reads the stream filter and resume position from the request.
+func parseStreamRequest(c *gin.Context) (*models.StreamSubscribeRequest, error) {
+	req := &models.StreamSubscribeRequest{}
+
+	for _, raw := range splitList(c.Query("device_ids")) {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid device id %q", raw)
+		}
+		req.Filter.DeviceIDs = append(req.Filter.DeviceIDs, id)
+	}
+
+	for _, eventType := range splitList(c.Query("types")) {
+		if !streamTypes[eventType] {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		req.Filter.Types = append(req.Filter.Types, eventType)
+	}
+
+	req.Filter.Paths = splitList(c.Query("paths"))
+	if len(req.Filter.Paths) > maxStreamPaths {
+		return nil, fmt.Errorf("at most %d paths allowed", maxStreamPaths)
+	}
+	for _, path := range req.Filter.Paths {
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" || segment == "*" {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid last event id")
+		}
+		req.LastEventID = id
+	}
+
+	return req, nil
+}
+
+// splitList splits a comma-separated parameter, skipping empty entries.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
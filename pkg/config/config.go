@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os/exec"
+	"regexp"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -30,11 +33,22 @@ type Config struct {
 	PollWorkerCount int `mapstructure:"POLL_WORKER_COUNT"`
 	DiscWorkerCount int `mapstructure:"DISC_WORKER_COUNT"`
 
+	// On-demand Polling
+	PollNowTimeoutSec int `mapstructure:"POLL_NOW_TIMEOUT_SEC"` // Max time the API waits for an on-demand poll
+
 	// Scheduler Configurations
 	PollIntervalSec  int `mapstructure:"POLL_INTERVAL_SEC"`
 	AvCheckTimeoutMs int `mapstructure:"AV_CHECK_TIMEOUT_MS"`
 	AvCheckRetries   int `mapstructure:"AV_CHECK_RETRIES"`
 
+	// Scheduler Backpressure
+	SchedulerOverloadPolicy   string `mapstructure:"SCHEDULER_OVERLOAD_POLICY"`    // "skip" or "coalesce" when the poller is saturated
+	SchedulerLateToleranceSec int    `mapstructure:"SCHEDULER_LATE_TOLERANCE_SEC"` // Lag beyond one tick + tolerance counts as a late poll
+
+	// Adaptive Backoff
+	BackoffMultiplier     float64 `mapstructure:"BACKOFF_MULTIPLIER"`       // Interval growth factor per consecutive failure
+	BackoffMaxIntervalSec int     `mapstructure:"BACKOFF_MAX_INTERVAL_SEC"` // Upper bound for the backed-off interval
+
 	// Security/Encryption Configurations
 	JWTSecret     string `mapstructure:"JWT_SECRET"`
 	EncryptionKey string `mapstructure:"ENCRYPTION_KEY"`
@@ -48,6 +62,18 @@ type Config struct {
 	MetricsDefaultLimit         int `mapstructure:"METRICS_DEFAULT_LIMIT"`
 	MetricsDefaultLookbackHours int `mapstructure:"METRICS_DEFAULT_LOOKBACK_HOURS"`
 
+	// Metrics Retention
+	MetricsPartitionInterval string `mapstructure:"METRICS_PARTITION_INTERVAL"`  // day or week
+	MetricsPartitionPremake  int    `mapstructure:"METRICS_PARTITION_PREMAKE"`   // Future partitions kept ready
+	MetricsRetentionDays     int    `mapstructure:"METRICS_RETENTION_DAYS"`      // 0 keeps metrics forever; discovery profiles may override
+	MetricsRetentionCheckMin int    `mapstructure:"METRICS_RETENTION_CHECK_MIN"` // How often partitions are created and dropped
+
+	// Metrics Rollups (1m/1h/1d aggregates of numeric leaves)
+	MetricsRollupIntervalSec     int `mapstructure:"METRICS_ROLLUP_INTERVAL_SEC"`      // How often new raw metrics are rolled up
+	MetricsRollup1mRetentionDays int `mapstructure:"METRICS_ROLLUP_1M_RETENTION_DAYS"` // 0 keeps forever
+	MetricsRollup1hRetentionDays int `mapstructure:"METRICS_ROLLUP_1H_RETENTION_DAYS"`
+	MetricsRollup1dRetentionDays int `mapstructure:"METRICS_ROLLUP_1D_RETENTION_DAYS"`
+
 	// Connection Pool Settings (main GORM pool)
 	DBMaxOpenConns    int `mapstructure:"DB_MAX_OPEN_CONNS"`
 	DBMaxIdleConns    int `mapstructure:"DB_MAX_IDLE_CONNS"`
@@ -61,14 +87,95 @@ type Config struct {
 	MetricsReaderMaxOpen int `mapstructure:"METRICS_READER_MAX_OPEN"`
 	MetricsReaderMaxIdle int `mapstructure:"METRICS_READER_MAX_IDLE"`
 
-	// Health Monitor
-	FailureWindowMin int `mapstructure:"FAILURE_WINDOW_MIN"` // Time window for failure counting (minutes)
-	FailureThreshold int `mapstructure:"FAILURE_THRESHOLD"`  // Number of failures to trigger deactivation
+	// Health Monitor (default failure policy for devices without an assigned one)
+	FailureWindowMin int `mapstructure:"FAILURE_WINDOW_MIN"` // Sliding window for failure counting (minutes)
+	FailureThreshold int `mapstructure:"FAILURE_THRESHOLD"`  // Ping or poll failures within the window that trigger deactivation
+
+	// Flap Detection
+	FlapWindowChecks  int     `mapstructure:"FLAP_WINDOW_CHECKS"`  // Recent checks the flap score covers
+	FlapHighThreshold float64 `mapstructure:"FLAP_HIGH_THRESHOLD"` // Percent state change at which a device starts flapping
+	FlapLowThreshold  float64 `mapstructure:"FLAP_LOW_THRESHOLD"`  // Percent state change below which it is stable again
+
+	// Recovery Probing (inactive devices)
+	RecoveryIntervalSec      int `mapstructure:"RECOVERY_INTERVAL_SEC"`      // How often inactive devices are checked
+	RecoverySuccessThreshold int `mapstructure:"RECOVERY_SUCCESS_THRESHOLD"` // Consecutive successful checks to reactivate
+	RecoveryWorkerCount      int `mapstructure:"RECOVERY_WORKER_COUNT"`      // Concurrent plugin probe workers
+
+	// Availability Reports
+	AvailabilityDefaultRangeHours int `mapstructure:"AVAILABILITY_DEFAULT_RANGE_HOURS"` // Report range when no start is given
+
+	// Notifications
+	NotifyWorkerCount    int `mapstructure:"NOTIFY_WORKER_COUNT"`     // Concurrent deliveries
+	NotifyMaxAttempts    int `mapstructure:"NOTIFY_MAX_ATTEMPTS"`     // Attempts before a delivery is marked failed
+	NotifyRetryBaseSec   int `mapstructure:"NOTIFY_RETRY_BASE_SEC"`   // First retry delay, doubled per attempt
+	NotifyHTTPTimeoutSec int `mapstructure:"NOTIFY_HTTP_TIMEOUT_SEC"` // Webhook/Slack/Teams request timeout
+
+	// Escalation
+	EscalationCheckIntervalSec int `mapstructure:"ESCALATION_CHECK_INTERVAL_SEC"` // How often unacknowledged alerts are escalated
+
+	// Anomaly Detection
+	AnomalyPaths       string  `mapstructure:"ANOMALY_PATHS"`       // Comma-separated metric paths baselined on every device (anomaly rule paths are added)
+	AnomalyAlpha       float64 `mapstructure:"ANOMALY_ALPHA"`       // EWMA smoothing factor, higher adapts faster
+	AnomalyDeviations  float64 `mapstructure:"ANOMALY_DEVIATIONS"`  // Standard deviations that flag an anomaly
+	AnomalyMinSamples  int     `mapstructure:"ANOMALY_MIN_SAMPLES"` // Samples before a baseline is trusted
+	AnomalySeasonality bool    `mapstructure:"ANOMALY_SEASONALITY"` // Keep a separate baseline per hour of the week
+
+	// Prometheus Exposition (/metrics/devices)
+	PrometheusScrapeToken string                       `mapstructure:"PROMETHEUS_SCRAPE_TOKEN"` // Bearer token for scrapes, set via environment variable; empty disables the endpoint
+	PrometheusMaxAgeSec   int                          `mapstructure:"PROMETHEUS_MAX_AGE_SEC"`  // Devices without a poll result this recent are not exposed
+	PrometheusMappings    map[string]PrometheusMapping `mapstructure:"PROMETHEUS_MAPPINGS"`     // Per plugin ID: name prefix, label rules and excluded paths
+
+	// Grafana JSON Datasource (/grafana)
+	GrafanaAPIToken string `mapstructure:"GRAFANA_API_TOKEN"` // Bearer token for the datasource, set via environment variable; empty disables the endpoints
+
+	// Metrics Export (remote_write / OTLP)
+	ExportRemoteWriteURL         string                       `mapstructure:"EXPORT_REMOTE_WRITE_URL"`          // Prometheus remote_write endpoint; empty disables the sink
+	ExportRemoteWriteBearerToken string                       `mapstructure:"EXPORT_REMOTE_WRITE_BEARER_TOKEN"` // Set via environment variable
+	ExportRemoteWriteMappings    map[string]PrometheusMapping `mapstructure:"EXPORT_REMOTE_WRITE_MAPPINGS"`     // Per plugin ID; PROMETHEUS_MAPPINGS when empty
+	ExportOTLPURL                string                       `mapstructure:"EXPORT_OTLP_URL"`                  // OTLP/HTTP metrics endpoint (.../v1/metrics); empty disables the sink
+	ExportOTLPBearerToken        string                       `mapstructure:"EXPORT_OTLP_BEARER_TOKEN"`         // Set via environment variable
+	ExportOTLPMappings           map[string]PrometheusMapping `mapstructure:"EXPORT_OTLP_MAPPINGS"`             // Per plugin ID; PROMETHEUS_MAPPINGS when empty
+	ExportQueueSize              int                          `mapstructure:"EXPORT_QUEUE_SIZE"`                // Batches queued per sink before the oldest is dropped
+	ExportMaxAttempts            int                          `mapstructure:"EXPORT_MAX_ATTEMPTS"`              // Attempts before a batch is dropped
+	ExportRetryBaseSec           int                          `mapstructure:"EXPORT_RETRY_BASE_SEC"`            // First retry delay, doubled per attempt
+	ExportTimeoutSec             int                          `mapstructure:"EXPORT_TIMEOUT_SEC"`               // Request timeout
+
+	// Live Event Stream (SSE)
+	StreamReplaySize       int `mapstructure:"STREAM_REPLAY_SIZE"`       // Events kept for Last-Event-ID resumption; 0 disables replay
+	StreamSubscriberBuffer int `mapstructure:"STREAM_SUBSCRIBER_BUFFER"` // Events queued per subscriber before it is disconnected
+	StreamMaxSubscribers   int `mapstructure:"STREAM_MAX_SUBSCRIBERS"`   // Concurrent streams; further ones get 503
+
+	// SMTP for email channels
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"` // Empty disables SMTP AUTH
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"` // Set via environment variable
+	SMTPFrom     string `mapstructure:"SMTP_FROM"`
 
 	// Metrics Service Worker Pool
 	MetricsWorkerCount int `mapstructure:"METRICS_WORKER_COUNT"`
 }
 
+// PrometheusMapping is how one plugin's poll results are exposed to Prometheus.
+type PrometheusMapping struct {
+	Prefix  string                `mapstructure:"prefix"`  // Metric name prefix, "nms" when empty
+	Labels  []PrometheusLabelRule `mapstructure:"labels"`  // First matching rule wins
+	Exclude []string              `mapstructure:"exclude"` // Dotted path prefixes, "*" matching any key
+}
+
+// PrometheusLabelRule turns the keys at the "*" segments of Match into labels,
+// e.g. match "disk.drives.*" with labels [drive].
+type PrometheusLabelRule struct {
+	Match  string   `mapstructure:"match"`
+	Labels []string `mapstructure:"labels"` // One label name per "*" in Match
+}
+
+// prometheusName is a valid Prometheus metric or label name.
+var prometheusName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// prometheusBaseLabels are set on every exposed sample and can't be used by label rules.
+var prometheusBaseLabels = map[string]bool{"device_id": true, "hostname": true, "plugin_id": true}
+
 // LoadConfig reads configuration from file or environment variables.
 func LoadConfig(path string) (*Config, error) {
 	v := viper.New()
@@ -82,9 +189,14 @@ func LoadConfig(path string) (*Config, error) {
 	v.SetDefault("PLUGINS_DIR", "plugins")
 	v.SetDefault("POLL_WORKER_COUNT", 5)
 	v.SetDefault("DISC_WORKER_COUNT", 3)
+	v.SetDefault("POLL_NOW_TIMEOUT_SEC", 90)
 	v.SetDefault("POLL_INTERVAL_SEC", 30)
 	v.SetDefault("AV_CHECK_TIMEOUT_MS", 500)
 	v.SetDefault("AV_CHECK_RETRIES", 2)
+	v.SetDefault("SCHEDULER_OVERLOAD_POLICY", "skip")
+	v.SetDefault("SCHEDULER_LATE_TOLERANCE_SEC", 5)
+	v.SetDefault("BACKOFF_MULTIPLIER", 2.0)
+	v.SetDefault("BACKOFF_MAX_INTERVAL_SEC", 1800)
 	v.SetDefault("JWT_SECRET", "default-insecure-secret-change-me")
 	v.SetDefault("ENCRYPTION_KEY", "1234567890123456789012345678901212345678901234567890123456789012")
 	v.SetDefault("NMS_ADMIN_USER", "admin")
@@ -92,6 +204,14 @@ func LoadConfig(path string) (*Config, error) {
 	v.SetDefault("SESSION_DURATION_HOURS", 168)
 	v.SetDefault("METRICS_DEFAULT_LIMIT", 100)
 	v.SetDefault("METRICS_DEFAULT_LOOKBACK_HOURS", 1)
+	v.SetDefault("METRICS_PARTITION_INTERVAL", "day")
+	v.SetDefault("METRICS_PARTITION_PREMAKE", 3)
+	v.SetDefault("METRICS_RETENTION_DAYS", 30)
+	v.SetDefault("METRICS_RETENTION_CHECK_MIN", 60)
+	v.SetDefault("METRICS_ROLLUP_INTERVAL_SEC", 60)
+	v.SetDefault("METRICS_ROLLUP_1M_RETENTION_DAYS", 7)
+	v.SetDefault("METRICS_ROLLUP_1H_RETENTION_DAYS", 90)
+	v.SetDefault("METRICS_ROLLUP_1D_RETENTION_DAYS", 730)
 	v.SetDefault("DB_MAX_OPEN_CONNS", 25)
 	v.SetDefault("DB_MAX_IDLE_CONNS", 10)
 	v.SetDefault("DB_CONN_MAX_LIFE_MINS", 30)
@@ -101,7 +221,43 @@ func LoadConfig(path string) (*Config, error) {
 	v.SetDefault("METRICS_READER_MAX_IDLE", 2)
 	v.SetDefault("FAILURE_WINDOW_MIN", 3)
 	v.SetDefault("FAILURE_THRESHOLD", 3)
+	v.SetDefault("FLAP_WINDOW_CHECKS", 21)
+	v.SetDefault("FLAP_HIGH_THRESHOLD", 50.0)
+	v.SetDefault("FLAP_LOW_THRESHOLD", 25.0)
 	v.SetDefault("METRICS_WORKER_COUNT", 4)
+	v.SetDefault("RECOVERY_INTERVAL_SEC", 300)
+	v.SetDefault("RECOVERY_SUCCESS_THRESHOLD", 3)
+	v.SetDefault("RECOVERY_WORKER_COUNT", 2)
+	v.SetDefault("AVAILABILITY_DEFAULT_RANGE_HOURS", 720)
+	v.SetDefault("NOTIFY_WORKER_COUNT", 2)
+	v.SetDefault("NOTIFY_MAX_ATTEMPTS", 5)
+	v.SetDefault("NOTIFY_RETRY_BASE_SEC", 30)
+	v.SetDefault("NOTIFY_HTTP_TIMEOUT_SEC", 10)
+	v.SetDefault("ESCALATION_CHECK_INTERVAL_SEC", 30)
+	v.SetDefault("ANOMALY_PATHS", "")
+	v.SetDefault("ANOMALY_ALPHA", 0.05)
+	v.SetDefault("ANOMALY_DEVIATIONS", 3.0)
+	v.SetDefault("ANOMALY_MIN_SAMPLES", 30)
+	v.SetDefault("ANOMALY_SEASONALITY", false)
+	v.SetDefault("PROMETHEUS_SCRAPE_TOKEN", "")
+	v.SetDefault("PROMETHEUS_MAX_AGE_SEC", 900)
+	v.SetDefault("GRAFANA_API_TOKEN", "")
+	v.SetDefault("EXPORT_REMOTE_WRITE_URL", "")
+	v.SetDefault("EXPORT_REMOTE_WRITE_BEARER_TOKEN", "")
+	v.SetDefault("EXPORT_OTLP_URL", "")
+	v.SetDefault("EXPORT_OTLP_BEARER_TOKEN", "")
+	v.SetDefault("EXPORT_QUEUE_SIZE", 100)
+	v.SetDefault("EXPORT_MAX_ATTEMPTS", 5)
+	v.SetDefault("EXPORT_RETRY_BASE_SEC", 2)
+	v.SetDefault("EXPORT_TIMEOUT_SEC", 10)
+	v.SetDefault("STREAM_REPLAY_SIZE", 1000)
+	v.SetDefault("STREAM_SUBSCRIBER_BUFFER", 256)
+	v.SetDefault("STREAM_MAX_SUBSCRIBERS", 100)
+	v.SetDefault("SMTP_HOST", "")
+	v.SetDefault("SMTP_PORT", 25)
+	v.SetDefault("SMTP_USERNAME", "")
+	v.SetDefault("SMTP_PASSWORD", "")
+	v.SetDefault("SMTP_FROM", "nms@localhost")
 
 	// 2. Read app.yaml for non-sensitive configuration
 	v.AddConfigPath(path)
@@ -129,9 +285,142 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, errors.New("POLL_INTERVAL_SEC must be at least 20 seconds")
 	}
 
+	// Validate scheduler overload policy
+	if config.SchedulerOverloadPolicy != "skip" && config.SchedulerOverloadPolicy != "coalesce" {
+		return nil, errors.New("SCHEDULER_OVERLOAD_POLICY must be either 'skip' or 'coalesce'")
+	}
+
+	// Validate backoff settings
+	if config.BackoffMultiplier < 1 {
+		return nil, errors.New("BACKOFF_MULTIPLIER must be at least 1")
+	}
+
+	// Validate metrics retention settings
+	if config.MetricsPartitionInterval != "day" && config.MetricsPartitionInterval != "week" {
+		return nil, errors.New("METRICS_PARTITION_INTERVAL must be either 'day' or 'week'")
+	}
+	if config.MetricsPartitionPremake < 1 {
+		return nil, errors.New("METRICS_PARTITION_PREMAKE must be at least 1")
+	}
+	if config.MetricsRetentionDays < 0 {
+		return nil, errors.New("METRICS_RETENTION_DAYS must not be negative")
+	}
+	if config.MetricsRetentionCheckMin < 1 {
+		return nil, errors.New("METRICS_RETENTION_CHECK_MIN must be at least 1")
+	}
+
+	// Validate rollup settings
+	if config.MetricsRollupIntervalSec < 10 {
+		return nil, errors.New("METRICS_ROLLUP_INTERVAL_SEC must be at least 10")
+	}
+	if config.MetricsRollup1mRetentionDays < 0 || config.MetricsRollup1hRetentionDays < 0 || config.MetricsRollup1dRetentionDays < 0 {
+		return nil, errors.New("METRICS_ROLLUP_*_RETENTION_DAYS must not be negative")
+	}
+
+	// Validate flap detection settings
+	if config.FlapWindowChecks < 3 {
+		return nil, errors.New("FLAP_WINDOW_CHECKS must be at least 3")
+	}
+	if config.FlapLowThreshold >= config.FlapHighThreshold {
+		return nil, errors.New("FLAP_LOW_THRESHOLD must be below FLAP_HIGH_THRESHOLD")
+	}
+
+	// Validate notification settings
+	if config.NotifyWorkerCount < 1 {
+		return nil, errors.New("NOTIFY_WORKER_COUNT must be at least 1")
+	}
+	if config.NotifyMaxAttempts < 1 {
+		return nil, errors.New("NOTIFY_MAX_ATTEMPTS must be at least 1")
+	}
+	if config.EscalationCheckIntervalSec < 1 {
+		return nil, errors.New("ESCALATION_CHECK_INTERVAL_SEC must be at least 1")
+	}
+
+	// Validate anomaly detection settings
+	if config.AnomalyAlpha <= 0 || config.AnomalyAlpha > 1 {
+		return nil, errors.New("ANOMALY_ALPHA must be greater than 0 and at most 1")
+	}
+	if config.AnomalyDeviations <= 0 {
+		return nil, errors.New("ANOMALY_DEVIATIONS must be positive")
+	}
+	if config.AnomalyMinSamples < 2 {
+		return nil, errors.New("ANOMALY_MIN_SAMPLES must be at least 2")
+	}
+
+	// Validate Prometheus exposition settings
+	if config.PrometheusMaxAgeSec < 1 {
+		return nil, errors.New("PROMETHEUS_MAX_AGE_SEC must be at least 1")
+	}
+	if err := validatePrometheusMappings("PROMETHEUS_MAPPINGS", config.PrometheusMappings); err != nil {
+		return nil, err
+	}
+
+	// Validate metrics export settings
+	for key, endpoint := range map[string]string{"EXPORT_REMOTE_WRITE_URL": config.ExportRemoteWriteURL, "EXPORT_OTLP_URL": config.ExportOTLPURL} {
+		if endpoint == "" {
+			continue
+		}
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an http or https URL", key)
+		}
+	}
+	if config.ExportQueueSize < 1 {
+		return nil, errors.New("EXPORT_QUEUE_SIZE must be at least 1")
+	}
+	if config.ExportMaxAttempts < 1 {
+		return nil, errors.New("EXPORT_MAX_ATTEMPTS must be at least 1")
+	}
+	if config.ExportRetryBaseSec < 1 {
+		return nil, errors.New("EXPORT_RETRY_BASE_SEC must be at least 1")
+	}
+	if config.ExportTimeoutSec < 1 {
+		return nil, errors.New("EXPORT_TIMEOUT_SEC must be at least 1")
+	}
+	if err := validatePrometheusMappings("EXPORT_REMOTE_WRITE_MAPPINGS", config.ExportRemoteWriteMappings); err != nil {
+		return nil, err
+	}
+	if err := validatePrometheusMappings("EXPORT_OTLP_MAPPINGS", config.ExportOTLPMappings); err != nil {
+		return nil, err
+	}
+	if config.StreamReplaySize < 0 {
+		return nil, errors.New("STREAM_REPLAY_SIZE must not be negative")
+	}
+	if config.StreamSubscriberBuffer < 1 {
+		return nil, errors.New("STREAM_SUBSCRIBER_BUFFER must be at least 1")
+	}
+	if config.StreamMaxSubscribers < 1 {
+		return nil, errors.New("STREAM_MAX_SUBSCRIBERS must be at least 1")
+	}
+
 	return &config, nil
 }
 
+// validatePrometheusMappings checks that mappings can only produce valid metric and label names.
+func validatePrometheusMappings(key string, mappings map[string]PrometheusMapping) error {
+	for pluginID, mapping := range mappings {
+		if mapping.Prefix != "" && !prometheusName.MatchString(mapping.Prefix) {
+			return fmt.Errorf("%s.%s: prefix %q is not a valid metric name", key, pluginID, mapping.Prefix)
+		}
+		for _, rule := range mapping.Labels {
+			wildcards := 0
+			for _, segment := range strings.Split(rule.Match, ".") {
+				if segment == "*" {
+					wildcards++
+				}
+			}
+			if wildcards != len(rule.Labels) {
+				return fmt.Errorf("%s.%s: match %q has %d \"*\" segments but %d labels", key, pluginID, rule.Match, wildcards, len(rule.Labels))
+			}
+			for _, label := range rule.Labels {
+				if !prometheusName.MatchString(label) || strings.HasPrefix(label, "__") || prometheusBaseLabels[label] {
+					return fmt.Errorf("%s.%s: %q can't be used as a label name", key, pluginID, label)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // ValidateSecrets ensures critical secrets are not using insecure defaults.
 // Call this in production to fail fast if secrets are not properly configured.
 func (c *Config) ValidateSecrets() error {
@@ -144,6 +433,17 @@ func (c *Config) ValidateSecrets() error {
 	return nil
 }
 
+// AnomalyPathList returns the configured ANOMALY_PATHS as a list, skipping blanks.
+func (c *Config) AnomalyPathList() []string {
+	var paths []string
+	for _, path := range strings.Split(c.AnomalyPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // FindFpingPath attempts to find the fping binary in the system PATH.
 func FindFpingPath() (string, error) {
 	path, err := exec.LookPath("fping")
@@ -17,6 +17,7 @@ type Repository[T models.TableNamer] interface {
 	List(ctx context.Context) ([]*T, error)
 	Get(ctx context.Context, id int64) (*T, error)
 	GetByFields(ctx context.Context, filters map[string]any) (*T, error)
+	ListByFields(ctx context.Context, filters map[string]any) ([]*T, error)
 	Create(ctx context.Context, entity *T) (*T, error)
 	Update(ctx context.Context, id int64, entity *T) (*T, error)
 	Delete(ctx context.Context, id int64) error
@@ -80,6 +81,26 @@ func (r *SqlxRepository[T]) GetByFields(ctx context.Context, filters map[string]
 	return &entity, nil
 }
 
+// ListByFields returns all rows matching the filters, newest first.
+func (r *SqlxRepository[T]) ListByFields(ctx context.Context, filters map[string]any) ([]*T, error) {
+	var entities []*T
+
+	// Build WHERE clause dynamically
+	var conditions []string
+	var args []any
+	i := 1
+	for col, val := range filters {
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", col, i))
+		args = append(args, val)
+		i++
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY id DESC",
+		r.tableName(), strings.Join(conditions, " AND "))
+	err := r.db.SelectContext(ctx, &entities, query, args...)
+	return entities, err
+}
+
 func (r *SqlxRepository[T]) Create(ctx context.Context, entity *T) (*T, error) {
 	cols, placeholders, vals := buildInsertParts(entity)
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
@@ -0,0 +1,78 @@
+// Package fping wraps the fping binary for batch availability checks.
+package fping
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// Pinger runs batch reachability checks with fping.
+type Pinger struct {
+	path      string
+	timeoutMs int
+	retries   int
+}
+
+// NewPinger creates a Pinger for the fping binary at path.
+func NewPinger(path string, timeoutMs, retries int) *Pinger {
+	return &Pinger{
+		path:      path,
+		timeoutMs: timeoutMs,
+		retries:   retries,
+	}
+}
+
+// Check runs fping against a list of IPs and returns reachability.
+func (p *Pinger) Check(ips []string) map[string]bool {
+	reachable := make(map[string]bool)
+
+	if len(ips) == 0 {
+		slog.Debug("No IPs to check with fping", "component", "Fping")
+		return reachable
+	}
+
+	slog.Info("Checking IPs with fping", "component", "Fping", "count", len(ips), "timeout_ms", p.timeoutMs, "retries", p.retries)
+
+	// Build fping command
+	// -a: show alive hosts
+	// -q: quiet (don't show per-target results)
+	// -t: timeout in ms
+	// -r: retry count
+	args := []string{
+		"-a",
+		"-q",
+		"-t", fmt.Sprintf("%d", p.timeoutMs),
+		"-r", fmt.Sprintf("%d", p.retries),
+	}
+	args = append(args, ips...)
+
+	cmd := exec.Command(p.path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	// fping returns non-zero if some hosts are unreachable, so we don't treat that as an error
+	if err != nil {
+		slog.Debug("fping exited with error (normal if some hosts down)", "component", "Fping", "error", err)
+	}
+
+	// Parse stdout for reachable IPs (one per line)
+	output := strings.TrimSpace(stdout.String())
+	if output != "" {
+		lines := strings.Split(output, "\n")
+		for _, line := range lines {
+			ip := strings.TrimSpace(line)
+			if ip != "" {
+				reachable[ip] = true
+				slog.Debug("IP is reachable", "component", "Fping", "ip", ip)
+			}
+		}
+	}
+
+	slog.Info("Fping check complete", "component", "Fping", "reachable_count", len(reachable), "total_ips", len(ips))
+	return reachable
+}
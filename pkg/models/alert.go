@@ -0,0 +1,200 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Alert instance states. Every state but resolved is open and still tracked by the evaluator.
+const (
+	AlertStateFiring       = "firing"
+	AlertStateAcknowledged = "acknowledged"
+	AlertStateSilenced     = "silenced"
+	AlertStateResolved     = "resolved"
+)
+
+// Errors returned for alert lookups and lifecycle operations, so the API can tell them from failures
+var (
+	ErrAlertNotFound = errors.New("alert not found")
+	ErrAlertNotOpen  = errors.New("alert is not open") // Acknowledge/resolve of a resolved alert
+)
+
+// AlertRule represents the alert_rules table.
+// A rule applies to devices matching every non-empty scope field; an empty scope matches all devices.
+type AlertRule struct {
+	ID                 int64      `db:"id" json:"id"`
+	Name               string     `db:"name" json:"name" binding:"required"`
+	Path               string     `db:"path" json:"path" binding:"required"`                                    // Dotted metric path, same syntax as MetricQuery.Path
+	Condition          string     `db:"condition" json:"condition" binding:"omitempty,oneof=threshold anomaly"` // Defaults to threshold
+	Operator           string     `db:"operator" json:"operator" binding:"required,oneof=gt gte lt lte eq ne"`  // Anomaly: gt (above), lt (below), ne (either side)
+	Threshold          float64    `db:"threshold" json:"threshold"`
+	Deviations         float64    `db:"deviations" json:"deviations" binding:"min=0"`   // Anomaly: standard deviations from the baseline, 0 uses ANOMALY_DEVIATIONS
+	ForSeconds         int        `db:"for_seconds" json:"for_seconds" binding:"min=0"` // Breach must last this long before firing
+	Severity           string     `db:"severity" json:"severity" binding:"required,oneof=info warning critical"`
+	ScopeDeviceIDs     Int64List  `db:"scope_device_ids" json:"scope_device_ids"`
+	ScopeTags          StringList `db:"scope_tags" json:"scope_tags"` // Device must carry all tags
+	ScopePluginID      string     `db:"scope_plugin_id" json:"scope_plugin_id"`
+	EscalationPolicyID *int64     `db:"escalation_policy_id" json:"escalation_policy_id,omitempty"` // Escalate while unacknowledged
+	Disabled           bool       `db:"disabled" json:"disabled"`                                   // Zero value keeps new rules active
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (AlertRule) TableName() string { return "alert_rules" }
+
+// Matches reports whether the rule's scope covers a device.
+func (r *AlertRule) Matches(device *Device) bool {
+	if len(r.ScopeDeviceIDs) > 0 && !containsInt64(r.ScopeDeviceIDs, device.ID) {
+		return false
+	}
+	if r.ScopePluginID != "" && r.ScopePluginID != device.PluginID {
+		return false
+	}
+	for _, tag := range r.ScopeTags {
+		if !containsString(device.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnomalyBreached reports whether a z-score is more than k standard deviations from the baseline,
+// in the rule's direction.
+func (r *AlertRule) AnomalyBreached(zscore, k float64) bool {
+	switch r.Operator {
+	case "gt", "gte":
+		return zscore > k
+	case "lt", "lte":
+		return zscore < -k
+	case "ne":
+		return zscore > k || zscore < -k
+	}
+	return false
+}
+
+// Breached reports whether a value violates the rule threshold.
+func (r *AlertRule) Breached(value float64) bool {
+	switch r.Operator {
+	case "gt":
+		return value > r.Threshold
+	case "gte":
+		return value >= r.Threshold
+	case "lt":
+		return value < r.Threshold
+	case "lte":
+		return value <= r.Threshold
+	case "eq":
+		return value == r.Threshold
+	case "ne":
+		return value != r.Threshold
+	}
+	return false
+}
+
+// AlertInstance represents the alert_instances table: one alert per rule and device from firing until resolved.
+type AlertInstance struct {
+	ID             int64      `db:"id" json:"id"`
+	RuleID         int64      `db:"rule_id" json:"rule_id"`
+	DeviceID       int64      `db:"device_id" json:"device_id"`
+	Severity       string     `db:"severity" json:"severity"`
+	State          string     `db:"state" json:"state"`
+	Value          float64    `db:"value" json:"value"`           // Last evaluated value
+	StartedAt      time.Time  `db:"started_at" json:"started_at"` // First breaching sample
+	FiredAt        time.Time  `db:"fired_at" json:"fired_at"`
+	AcknowledgedBy string     `db:"acknowledged_by" json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `db:"acknowledged_at" json:"acknowledged_at,omitempty"`
+	SilenceID      *int64     `db:"silence_id" json:"silence_id,omitempty"`   // Silence currently muting the alert
+	ResolvedBy     string     `db:"resolved_by" json:"resolved_by,omitempty"` // Empty when the condition cleared by itself
+	ResolvedAt     *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (AlertInstance) TableName() string { return "alert_instances" }
+
+// Open reports whether the alert is not yet resolved.
+func (a *AlertInstance) Open() bool {
+	return a.State != AlertStateResolved
+}
+
+// AlertListRequest is the payload for OpListAlerts. Zero fields don't filter.
+type AlertListRequest struct {
+	State    string
+	RuleID   int64
+	DeviceID int64
+	Severity string
+	Start    time.Time // Fired at or after
+	End      time.Time // Fired at or before
+	Limit    int
+	Offset   int
+}
+
+// AlertPage is one page of alert history.
+type AlertPage struct {
+	Alerts []*AlertInstance `json:"alerts"`
+	Total  int              `json:"total"` // Matching alerts across all pages
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+// AlertSilence represents the alert_silences table.
+// It mutes open alerts matching every set matcher (device, rule, device tag) until EndsAt.
+type AlertSilence struct {
+	ID        int64     `db:"id" json:"id"`
+	DeviceID  *int64    `db:"device_id" json:"device_id,omitempty"`
+	RuleID    *int64    `db:"rule_id" json:"rule_id,omitempty"`
+	Label     string    `db:"label" json:"label,omitempty"` // Device tag
+	Comment   string    `db:"comment" json:"comment"`
+	CreatedBy string    `db:"created_by" json:"created_by"`
+	StartsAt  time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt    time.Time `db:"ends_at" json:"ends_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (AlertSilence) TableName() string { return "alert_silences" }
+
+// Active reports whether the silence is in effect at the given time.
+func (s *AlertSilence) Active(at time.Time) bool {
+	return !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
+
+// Matches reports whether the silence covers an alert of a rule on a device.
+func (s *AlertSilence) Matches(ruleID int64, device *Device) bool {
+	if s.DeviceID != nil && *s.DeviceID != device.ID {
+		return false
+	}
+	if s.RuleID != nil && *s.RuleID != ruleID {
+		return false
+	}
+	if s.Label != "" && !containsString(device.Tags, s.Label) {
+		return false
+	}
+	return true
+}
+
+// AlertSilenceRequest is the API body for creating a silence. At least one matcher is required.
+type AlertSilenceRequest struct {
+	DeviceID        *int64 `json:"device_id"`
+	RuleID          *int64 `json:"rule_id"`
+	Label           string `json:"label"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1,max=43200"`
+	Comment         string `json:"comment"`
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Alert rule condition types
+const (
+	ConditionThreshold = "threshold" // Compare the value with Threshold
+	ConditionAnomaly   = "anomaly"   // Compare the value's z-score against the device baseline with Deviations
+)
+
+// MetricAnomaly represents the metric_anomalies table: one episode of a metric leaving its baseline.
+type MetricAnomaly struct {
+	ID        int64      `db:"id" json:"id"`
+	DeviceID  int64      `db:"device_id" json:"device_id"`
+	Path      string     `db:"path" json:"path"`
+	Value     float64    `db:"value" json:"value"`   // Sample with the largest deviation seen during the episode
+	Mean      float64    `db:"mean" json:"mean"`     // Baseline at that sample
+	StdDev    float64    `db:"stddev" json:"stddev"` // Baseline at that sample
+	ZScore    float64    `db:"zscore" json:"zscore"` // Deviation of that sample (signed)
+	StartedAt time.Time  `db:"started_at" json:"started_at"`
+	EndedAt   *time.Time `db:"ended_at" json:"ended_at,omitempty"` // Nil while the metric is still anomalous
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (MetricAnomaly) TableName() string { return "metric_anomalies" }
+
+// MetricBaseline is the current rolling statistics of one device metric.
+type MetricBaseline struct {
+	DeviceID int64     `json:"device_id"`
+	Path     string    `json:"path"`
+	Mean     float64   `json:"mean"`
+	StdDev   float64   `json:"stddev"`
+	Samples  int       `json:"samples"`
+	Seasonal bool      `json:"seasonal"` // Mean/StdDev come from the current hour-of-week bucket
+	Ready    bool      `json:"ready"`    // Enough samples to flag anomalies
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// AnomalyListRequest is the payload for OpListAnomalies. Zero fields don't filter.
+type AnomalyListRequest struct {
+	DeviceID int64
+	Path     string
+	Active   bool // Only episodes that have not ended
+	Limit    int
+}
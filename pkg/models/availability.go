@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Availability states recorded per device
+const (
+	AvailabilityUp          = "up"
+	AvailabilityDown        = "down"
+	AvailabilityUnreachable = "unreachable" // Behind an unreachable parent; counts as neither up nor down
+)
+
+// AvailabilityInterval is a period during which a device stayed in one availability state.
+// EndedAt is nil for the device's current (open) interval.
+type AvailabilityInterval struct {
+	ID        int64      `db:"id" json:"id"`
+	DeviceID  int64      `db:"device_id" json:"device_id"`
+	State     string     `db:"state" json:"state"`
+	Reason    string     `db:"reason" json:"reason"` // Check that caused the change: "ping", "poll" or "dependency"
+	StartedAt time.Time  `db:"started_at" json:"started_at"`
+	EndedAt   *time.Time `db:"ended_at" json:"ended_at,omitempty"`
+}
+
+func (AvailabilityInterval) TableName() string { return "device_availability" }
+
+// AvailabilityReportRequest is the payload for OpAvailabilityReport.
+// Exactly one of DeviceID or DiscoveryProfileID is set.
+type AvailabilityReportRequest struct {
+	DeviceID           int64
+	DiscoveryProfileID int64
+	Start              time.Time
+	End                time.Time
+}
+
+// Outage is a contiguous period of downtime within a report range.
+type Outage struct {
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"` // Nil while the outage is ongoing
+	DurationSeconds int64      `json:"duration_seconds"`
+	Reason          string     `json:"reason"`
+}
+
+// AvailabilityReport summarizes a device's availability over a time range.
+// Uptime, MTTR and MTBF are nil when there is nothing to compute them from.
+type AvailabilityReport struct {
+	DeviceID           int64     `json:"device_id"`
+	Hostname           string    `json:"hostname"`
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+	UpSeconds          int64     `json:"up_seconds"`
+	DownSeconds        int64     `json:"down_seconds"`
+	UnreachableSeconds int64     `json:"unreachable_seconds"`
+	UnknownSeconds     int64     `json:"unknown_seconds"` // No recorded state (before first check)
+	UptimePercent      *float64  `json:"uptime_percent"`
+	MTTRSeconds        *int64    `json:"mttr_seconds"`
+	MTBFSeconds        *int64    `json:"mtbf_seconds"`
+	OutageCount        int       `json:"outage_count"`
+	Outages            []Outage  `json:"outages"`
+}
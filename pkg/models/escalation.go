@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// EscalationStep notifies a channel once an alert has stayed unacknowledged for DelayMinutes after firing.
+// With a schedule, the message is addressed to whoever is on call at that moment.
+type EscalationStep struct {
+	DelayMinutes int    `json:"delay_minutes"`
+	ChannelID    int64  `json:"channel_id"`
+	ScheduleID   *int64 `json:"schedule_id,omitempty"`
+}
+
+// EscalationSteps is a []EscalationStep stored as a JSONB array.
+type EscalationSteps []EscalationStep
+
+// Value implements driver.Valuer; nil is stored as an empty array.
+func (s EscalationSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]EscalationStep(s))
+}
+
+// Scan implements sql.Scanner.
+func (s *EscalationSteps) Scan(src any) error {
+	return scanJSON(src, (*[]EscalationStep)(s))
+}
+
+// EscalationPolicy represents the escalation_policies table. Alert rules opt in via escalation_policy_id.
+type EscalationPolicy struct {
+	ID        int64           `db:"id" json:"id"`
+	Name      string          `db:"name" json:"name" binding:"required"`
+	Steps     EscalationSteps `db:"steps" json:"steps" binding:"required,min=1"` // Ordered by delay
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+func (EscalationPolicy) TableName() string { return "escalation_policies" }
+
+// AlertEscalation represents the alert_escalations table: how far an alert has escalated.
+type AlertEscalation struct {
+	AlertID     int64     `db:"alert_id" json:"alert_id"`
+	PolicyID    int64     `db:"policy_id" json:"policy_id"`
+	Step        int       `db:"step" json:"step"` // Steps already notified
+	EscalatedAt time.Time `db:"escalated_at" json:"escalated_at"`
+}
+
+// OnCallSchedule represents the oncall_schedules table.
+// Participants take turns for RotationHours each; the first shift starts at HandoffAt.
+type OnCallSchedule struct {
+	ID            int64      `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name" binding:"required"`
+	Participants  StringList `db:"participants" json:"participants" binding:"required,min=1"` // Email addresses or usernames, in rotation order
+	RotationHours int        `db:"rotation_hours" json:"rotation_hours" binding:"required,min=1"`
+	HandoffAt     time.Time  `db:"handoff_at" json:"handoff_at" binding:"required"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (OnCallSchedule) TableName() string { return "oncall_schedules" }
+
+// OnCallOverride represents the oncall_overrides table: a recipient who takes over a schedule for a time window.
+type OnCallOverride struct {
+	ID         int64     `db:"id" json:"id"`
+	ScheduleID int64     `db:"schedule_id" json:"schedule_id" binding:"required"`
+	Recipient  string    `db:"recipient" json:"recipient" binding:"required"`
+	StartsAt   time.Time `db:"starts_at" json:"starts_at" binding:"required"`
+	EndsAt     time.Time `db:"ends_at" json:"ends_at" binding:"required"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (OnCallOverride) TableName() string { return "oncall_overrides" }
+
+// OnCallShift is who is on call for a schedule at a given instant.
+type OnCallShift struct {
+	ScheduleID int64     `json:"schedule_id"`
+	At         time.Time `json:"at"`
+	Recipient  string    `json:"recipient"`
+	ShiftStart time.Time `json:"shift_start"`
+	ShiftEnd   time.Time `json:"shift_end"`
+	OverrideID *int64    `json:"override_id,omitempty"` // Set when an override replaces the rotation
+}
+
+// OnCallAt resolves the recipient at an instant. The most recently created override covering it wins.
+func (s *OnCallSchedule) OnCallAt(at time.Time, overrides []*OnCallOverride) *OnCallShift {
+	shift := &OnCallShift{ScheduleID: s.ID, At: at}
+
+	var chosen *OnCallOverride
+	for _, override := range overrides {
+		if override.ScheduleID != s.ID || at.Before(override.StartsAt) || !at.Before(override.EndsAt) {
+			continue
+		}
+		if chosen == nil || override.ID > chosen.ID {
+			chosen = override
+		}
+	}
+	if chosen != nil {
+		shift.Recipient = chosen.Recipient
+		shift.ShiftStart, shift.ShiftEnd = chosen.StartsAt, chosen.EndsAt
+		shift.OverrideID = &chosen.ID
+		return shift
+	}
+
+	if len(s.Participants) == 0 || s.RotationHours <= 0 {
+		return shift
+	}
+
+	rotation := time.Duration(s.RotationHours) * time.Hour
+	turn := int64(at.Sub(s.HandoffAt) / rotation)
+	if at.Before(s.HandoffAt.Add(time.Duration(turn) * rotation)) {
+		turn-- // Floor for instants before the first handoff
+	}
+	index := turn % int64(len(s.Participants))
+	if index < 0 {
+		index += int64(len(s.Participants))
+	}
+
+	shift.Recipient = s.Participants[index]
+	shift.ShiftStart = s.HandoffAt.Add(time.Duration(turn) * rotation)
+	shift.ShiftEnd = shift.ShiftStart.Add(rotation)
+	return shift
+}
@@ -13,8 +13,14 @@ const (
 	// Command events for provisioning
 	EventTriggerDiscovery EventType = "trigger_discovery"
 	EventProvisionDevice  EventType = "provision_device"
-	EventDeviceFailure    EventType = "device_failure" // Ping or poll failure
-	EventRunDiscovery     EventType = "run_discovery"  // Explicitly run discovery for a profile
+	EventDeviceFailure    EventType = "device_failure"    // Ping or poll failure
+	EventDeviceSuccess    EventType = "device_success"    // Ping or poll success (availability history)
+	EventRunDiscovery     EventType = "run_discovery"     // Explicitly run discovery for a profile
+	EventPollOutcome      EventType = "poll_outcome"      // Poll succeeded or failed (MetricsService -> Scheduler)
+	EventBackoffUpdate    EventType = "backoff_update"    // Device backoff state changed (Scheduler -> EntityService)
+	EventDependencyUpdate EventType = "dependency_update" // Device blocked/unblocked by an unreachable parent (Scheduler -> EntityService)
+	EventFlapUpdate       EventType = "flap_update"       // Device started/stopped flapping (FailureService -> EntityService: *DeviceFlap, EntityService -> NotificationService: *Notification)
+	EventNotification     EventType = "notification"      // Something worth telling operators (EntityService, AlertService -> NotificationService)
 )
 
 // Event represents a CRUD event for scheduler cache synchronization.
@@ -36,7 +42,22 @@ type DeviceProvisionEvent struct {
 
 // DeviceFailureEvent represents a device failure from ping or polling
 type DeviceFailureEvent struct {
+	DeviceID  int64
+	Timestamp time.Time
+	Reason    string // "ping", "poll" or "dependency"
+	BlockedBy int64  // Unreachable ancestor the failure is attributed to (Reason "dependency")
+}
+
+// DeviceSuccessEvent represents a successful ping or poll
+type DeviceSuccessEvent struct {
 	DeviceID  int64
 	Timestamp time.Time
 	Reason    string // "ping" or "poll"
 }
+
+// PollOutcomeEvent reports whether a device poll produced data
+type PollOutcomeEvent struct {
+	DeviceID  int64
+	Timestamp time.Time
+	Success   bool
+}
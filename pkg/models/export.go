@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ExporterStats reports the queue and delivery counters of one metrics export sink.
+type ExporterStats struct {
+	Kind           string     `json:"kind"` // remote_write or otlp
+	URL            string     `json:"url"`
+	QueueLength    int        `json:"queue_length"`   // Poll batches waiting to be sent
+	QueueCapacity  int        `json:"queue_capacity"` // The oldest batch is dropped when full
+	SentBatches    int64      `json:"sent_batches"`
+	SentSamples    int64      `json:"sent_samples"`
+	FailedAttempts int64      `json:"failed_attempts"` // Includes attempts that were retried
+	DroppedBatches int64      `json:"dropped_batches"` // Queue overflow, rejected or out of attempts
+	LagSeconds     float64    `json:"lag_seconds"`     // Poll to delivery time of the last sent batch
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
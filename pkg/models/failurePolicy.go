@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Failure policy modes
+const (
+	FailureModeSliding     = "sliding"     // Failures counted within the last WindowSeconds
+	FailureModeConsecutive = "consecutive" // Failures counted since the last success
+)
+
+// Failure policy actions
+const (
+	FailureActionDeactivate = "deactivate" // Set status inactive (RecoveryService may bring it back)
+	FailureActionAlert      = "alert"      // Report only, device keeps its status
+	FailureActionDegrade    = "degrade"    // Set status degraded; still polled and restored on the next successful poll
+)
+
+// FailurePolicy represents the failure_policies table.
+// A threshold of 0 disables evaluation for that failure reason.
+type FailurePolicy struct {
+	ID            int64     `db:"id" json:"id"`
+	Name          string    `db:"name" json:"name" binding:"required"`
+	Mode          string    `db:"mode" json:"mode" binding:"required,oneof=sliding consecutive"`
+	WindowSeconds int       `db:"window_seconds" json:"window_seconds" binding:"omitempty,min=10,max=86400" update:"omitempty"` // Sliding mode only
+	PingThreshold int       `db:"ping_threshold" json:"ping_threshold" binding:"min=0"`
+	PollThreshold int       `db:"poll_threshold" json:"poll_threshold" binding:"min=0"`
+	Action        string    `db:"action" json:"action" binding:"required,oneof=deactivate alert degrade"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (FailurePolicy) TableName() string { return "failure_policies" }
+
+// Threshold returns the policy threshold for a failure reason ("ping" or "poll").
+func (p *FailurePolicy) Threshold(reason string) int {
+	switch reason {
+	case "ping":
+		return p.PingThreshold
+	case "poll":
+		return p.PollThreshold
+	}
+	return 0
+}
+
+// PolicyDryRunRequest is the payload for OpDryRunPolicy.
+// Either PolicyID or Policy is set; empty DeviceIDs evaluates every device with recorded failures.
+type PolicyDryRunRequest struct {
+	PolicyID  int64          `json:"policy_id"`
+	Policy    *FailurePolicy `json:"policy"`
+	DeviceIDs []int64        `json:"device_ids"`
+}
+
+// PolicyEvaluation is the verdict of a failure policy for one device.
+type PolicyEvaluation struct {
+	DeviceID      int64          `json:"device_id"`
+	Policy        *FailurePolicy `json:"policy"`
+	PingFailures  int            `json:"ping_failures"` // Counted per the policy mode
+	PollFailures  int            `json:"poll_failures"`
+	WouldTrigger  bool           `json:"would_trigger"`
+	TriggerReason string         `json:"trigger_reason,omitempty"`
+	Action        string         `json:"action,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Notification states of flap start/stop notifications (kind device_status). A flapping
+// device keeps its status, so it is still checked and can stabilise; the Device.Flap
+// attachment carries the flap state instead.
+const (
+	FlapStarted = "flapping"
+	FlapStopped = "stable"
+)
+
+// DeviceFlap is the flap state of a device as published by FailureService.
+// Routed notifications for the device are suppressed while Flapping is set.
+type DeviceFlap struct {
+	DeviceID int64     `json:"-"`
+	Flapping bool      `json:"flapping"`
+	Score    float64   `json:"score"` // Weighted percent state change over recent checks
+	Since    time.Time `json:"since"`
+}
+
+// FlapHistory represents the device_flap_history table: one row per flap start/stop.
+type FlapHistory struct {
+	ID        int64     `db:"id" json:"id"`
+	DeviceID  int64     `db:"device_id" json:"device_id"`
+	Flapping  bool      `db:"flapping" json:"flapping"`
+	Score     float64   `db:"score" json:"score"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+func (FlapHistory) TableName() string { return "device_flap_history" }
+
+// FlapStatus is the live flap view of a device, including the checks the score is computed from.
+type FlapStatus struct {
+	DeviceID      int64     `json:"device_id"`
+	Flapping      bool      `json:"flapping"`
+	Score         float64   `json:"score"`
+	Since         time.Time `json:"since,omitzero"`
+	RecentChecks  []string  `json:"recent_checks"` // Oldest first, AvailabilityUp / AvailabilityDown
+	WindowChecks  int       `json:"window_checks"`
+	HighThreshold float64   `json:"high_threshold"`
+	LowThreshold  float64   `json:"low_threshold"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -32,14 +33,16 @@ type CredentialProfile struct {
 
 // DiscoveryProfile represents the discovery_profiles table
 type DiscoveryProfile struct {
-	ID                  int64     `db:"id" json:"id"`
-	Name                string    `db:"name" json:"name" binding:"required"`
-	Target              string    `db:"target" json:"target" binding:"required"` // CIDR or IP
-	Port                int       `db:"port" json:"port" binding:"required,min=1,max=65535"`
-	CredentialProfileID int64     `db:"credential_profile_id" json:"credential_profile_id" binding:"required"`
-	AutoProvision       bool      `db:"auto_provision" json:"auto_provision"`
-	CreatedAt           time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt           time.Time `db:"updated_at" json:"updated_at"`
+	ID                   int64     `db:"id" json:"id"`
+	Name                 string    `db:"name" json:"name" binding:"required"`
+	Target               string    `db:"target" json:"target" binding:"required"` // CIDR or IP
+	Port                 int       `db:"port" json:"port" binding:"required,min=1,max=65535"`
+	CredentialProfileID  int64     `db:"credential_profile_id" json:"credential_profile_id" binding:"required"`
+	AutoProvision        bool      `db:"auto_provision" json:"auto_provision"`
+	FailurePolicyID      *int64    `db:"failure_policy_id" json:"failure_policy_id,omitempty"`                 // Managed via /discovery_profiles/:id/failure_policy
+	MetricsRetentionDays int       `db:"metrics_retention_days" json:"metrics_retention_days" binding:"min=0"` // 0 uses METRICS_RETENTION_DAYS
+	CreatedAt            time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time `db:"updated_at" json:"updated_at"`
 
 	// CredentialProfile is populated by cache lookup, not DB join
 	CredentialProfile *CredentialProfile `db:"-" json:"credential_profile,omitempty"`
@@ -48,33 +51,124 @@ type DiscoveryProfile struct {
 // Device represents the devices table
 // Note: credential_profile_id and discovery_profile_id are immutable (validated in EntityService)
 type Device struct {
-	ID                     int64     `db:"id" json:"id"`
-	Hostname               string    `db:"hostname" json:"hostname" update:"omitempty"`
-	IPAddress              string    `db:"ip_address" json:"ip_address" binding:"omitempty,ip" update:"omitempty"`
-	PluginID               string    `db:"plugin_id" json:"plugin_id" update:"omitempty"`
-	Port                   int       `db:"port" json:"port" binding:"omitempty,min=1,max=65535" update:"omitempty"`
-	CredentialProfileID    int64     `db:"credential_profile_id" json:"credential_profile_id" update:"omitempty"`
-	DiscoveryProfileID     int64     `db:"discovery_profile_id" json:"discovery_profile_id" update:"omitempty"`
-	PollingIntervalSeconds int       `db:"polling_interval_seconds" json:"polling_interval_seconds" binding:"omitempty,min=60,max=3600" update:"omitempty"`
-	ShouldPing             bool      `db:"should_ping" json:"should_ping"`
-	Status                 string    `db:"status" json:"status" binding:"omitempty,oneof=discovered active inactive error" update:"omitempty"`
-	CreatedAt              time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt              time.Time `db:"updated_at" json:"updated_at"`
+	ID                     int64      `db:"id" json:"id"`
+	Hostname               string     `db:"hostname" json:"hostname" update:"omitempty"`
+	IPAddress              string     `db:"ip_address" json:"ip_address" binding:"omitempty,ip" update:"omitempty"`
+	PluginID               string     `db:"plugin_id" json:"plugin_id" update:"omitempty"`
+	Port                   int        `db:"port" json:"port" binding:"omitempty,min=1,max=65535" update:"omitempty"`
+	CredentialProfileID    int64      `db:"credential_profile_id" json:"credential_profile_id" update:"omitempty"`
+	DiscoveryProfileID     int64      `db:"discovery_profile_id" json:"discovery_profile_id" update:"omitempty"`
+	PollingIntervalSeconds int        `db:"polling_interval_seconds" json:"polling_interval_seconds" binding:"omitempty,min=60,max=3600" update:"omitempty"`
+	ShouldPing             bool       `db:"should_ping" json:"should_ping"`
+	Status                 string     `db:"status" json:"status" binding:"omitempty,oneof=discovered active degraded inactive error" update:"omitempty"`
+	ParentDeviceID         *int64     `db:"parent_device_id" json:"parent_device_id,omitempty"`   // Managed via /devices/:id/parent after creation
+	FailurePolicyID        *int64     `db:"failure_policy_id" json:"failure_policy_id,omitempty"` // Managed via /devices/:id/failure_policy
+	Tags                   StringList `db:"tags" json:"tags" update:"omitempty"`                  // Free-form labels used to scope alert rules
+	CreatedAt              time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt              time.Time  `db:"updated_at" json:"updated_at"`
 
 	// Populated by cache lookup, not DB join
 	CredentialProfile *CredentialProfile `db:"-" json:"credential_profile,omitempty"`
 	DiscoveryProfile  *DiscoveryProfile  `db:"-" json:"discovery_profile,omitempty"`
+
+	// Populated from Scheduler state, not persisted
+	Backoff    *DeviceBackoff    `db:"-" json:"backoff,omitempty"`
+	Dependency *DeviceDependency `db:"-" json:"dependency,omitempty"`
+
+	// Populated from FailureService state, not persisted
+	Flap *DeviceFlap `db:"-" json:"flap,omitempty"`
+}
+
+// Monitored reports whether the device is scheduled for polling (active or degraded).
+func (d *Device) Monitored() bool {
+	return d.Status == "active" || d.Status == "degraded"
+}
+
+// ManualStatusReason is the transition reason of status changes made through the device API.
+// RecoveryService never reactivates a device whose deactivation had this reason.
+const ManualStatusReason = "status updated via API"
+
+// DeviceTransition records a device status change and why it happened
+type DeviceTransition struct {
+	ID         int64     `db:"id" json:"id"`
+	DeviceID   int64     `db:"device_id" json:"device_id"`
+	FromStatus string    `db:"from_status" json:"from_status"`
+	ToStatus   string    `db:"to_status" json:"to_status"`
+	Reason     string    `db:"reason" json:"reason"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// TransitionQuery selects the status transitions of devices within a time range
+type TransitionQuery struct {
+	DeviceIDs []int64 // Empty selects every device
+	Start     time.Time
+	End       time.Time
+	Limit     int // Most transitions returned, newest first
 }
 
 // TableName overrides the default table name logic
 func (CredentialProfile) TableName() string { return "credential_profiles" }
 func (DiscoveryProfile) TableName() string  { return "discovery_profiles" }
 func (Device) TableName() string            { return "devices" }
+func (DeviceTransition) TableName() string  { return "device_transitions" }
 
 // MetricQuery represents a request for metric data
 type MetricQuery struct {
-	Path  string    `json:"path"`  // JSON path (e.g., "cpu" or "cpu.total")
-	Start time.Time `json:"start"` // start timestamp
-	End   time.Time `json:"end"`   // end timestamp
-	Limit int       `json:"limit"`
+	Path       string    `json:"path"`  // JSON path (e.g., "cpu" or "cpu.total")
+	Paths      []string  `json:"paths"` // Further paths; any segment of Path or Paths may be "*" (e.g. "network.interfaces.*.rx")
+	Start      time.Time `json:"start"` // start timestamp
+	End        time.Time `json:"end"`   // end timestamp
+	Limit      int       `json:"limit"`
+	Resolution string    `json:"resolution" binding:"omitempty,oneof=auto raw 1m 1h 1d"` // Storage tier; auto (default) picks the finest rollup that fits Limit
+
+	// Bucketed queries: set Step to get one aggregated value per bucket instead of raw points
+	Step      string `json:"step"`                                                                   // Bucket width as a Go duration, e.g. "5m"
+	Aggregate string `json:"aggregate" binding:"omitempty,oneof=avg min max sum count last p95 p99"` // Defaults to avg
+	GroupBy   string `json:"group_by" binding:"omitempty,oneof=device all discovery_profile plugin"` // Series per device (default) or combined across devices
+}
+
+// LatestQuery selects the newest poll results of devices, optionally the value at a path
+type LatestQuery struct {
+	DeviceIDs          []int64 `json:"device_ids"`
+	DiscoveryProfileID int64   `json:"discovery_profile_id"` // Adds every device of the profile
+	Path               string  `json:"path"`                 // Concrete JSON path; empty returns whole results
+}
+
+// ExtractPath returns the raw JSON at a concrete dotted path, or nil when the data lacks it.
+func ExtractPath(data json.RawMessage, path string) json.RawMessage {
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(current, &object); err != nil {
+			return nil
+		}
+		next, exists := object[segment]
+		if !exists {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// MetricSearch finds the samples whose value at Path satisfies Operator and Value
+type MetricSearch struct {
+	Path               string    `json:"path" binding:"required"`                               // Concrete JSON path, no wildcards
+	Operator           string    `json:"operator" binding:"required,oneof=gt gte lt lte eq ne"` // eq/ne also match strings and booleans
+	Value              any       `json:"value"`                                                 // Number, or string/bool for eq and ne
+	DeviceIDs          []int64   `json:"device_ids"`                                            // Optional scope, with DiscoveryProfileID
+	DiscoveryProfileID int64     `json:"discovery_profile_id"`                                  // Optional scope; neither searches every device
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+	Limit              int       `json:"limit"` // Most matches returned, newest first
+}
+
+// TopMetricsRequest ranks devices by a path's value aggregated over a trailing window
+type TopMetricsRequest struct {
+	Path               string        // Concrete path of a numeric leaf
+	Aggregate          string        // avg, min, max or last
+	Window             time.Duration // Ranks the last Window up to now
+	N                  int           // Devices returned
+	Order              string        // desc (largest first) or asc
+	DiscoveryProfileID int64         // Optional scope
 }
@@ -0,0 +1,146 @@
+package models
+
+import "time"
+
+// Notification kinds
+const (
+	NotificationAlertFiring     = "alert_firing"
+	NotificationAlertResolved   = "alert_resolved"
+	NotificationDeviceStatus    = "device_status"
+	NotificationAlertEscalation = "alert_escalation" // Sent to a step's channel directly, bypassing routes
+)
+
+// Notification channel types
+const (
+	ChannelWebhook = "webhook" // Generic JSON POST, HMAC-signed when a secret is set
+	ChannelSlack   = "slack"   // Slack incoming webhook
+	ChannelTeams   = "teams"   // Microsoft Teams incoming webhook
+	ChannelEmail   = "email"   // SMTP
+)
+
+// Delivery states
+const (
+	DeliveryPending  = "pending"
+	DeliverySent     = "sent"
+	DeliveryRetrying = "retrying"
+	DeliveryFailed   = "failed" // Gave up after the maximum number of attempts
+)
+
+// Severities ordered for routing thresholds (see AlertRule.Severity)
+var severityRank = map[string]int{"info": 0, "warning": 1, "critical": 2}
+
+// SeverityAtLeast reports whether severity is at or above min.
+func SeverityAtLeast(severity, min string) bool {
+	return severityRank[severity] >= severityRank[min]
+}
+
+// Notification is the message handed to NotificationService and the data its templates render.
+type Notification struct {
+	Kind      string    `json:"kind"`
+	Severity  string    `json:"severity"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	DeviceID  int64     `json:"device_id,omitempty"`
+	Hostname  string    `json:"hostname,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	PluginID  string    `json:"plugin_id,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	AlertID   int64     `json:"alert_id,omitempty"`
+	RuleID    int64     `json:"rule_id,omitempty"`
+	State     string    `json:"state"` // Alert state or device status
+	Value     float64   `json:"value,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Direct delivery (escalations): skip routing and send to this channel
+	ChannelID int64  `json:"channel_id,omitempty"`
+	Recipient string `json:"recipient,omitempty"` // On-call recipient; replaces email channel recipients
+	Step      int    `json:"step,omitempty"`      // Escalation step, starting at 1
+}
+
+// NotificationChannel represents the notification_channels table.
+// Templates use text/template over Notification; empty templates fall back to built-in defaults.
+type NotificationChannel struct {
+	ID              int64      `db:"id" json:"id"`
+	Name            string     `db:"name" json:"name" binding:"required"`
+	Type            string     `db:"type" json:"type" binding:"required,oneof=webhook slack teams email"`
+	URL             string     `db:"url" json:"url"`                                        // Webhook, Slack and Teams
+	Secret          string     `db:"secret" json:"secret" gocrypt:"aes" update:"omitempty"` // HMAC key for webhook signatures, encrypted at rest; kept when omitted on update
+	Recipients      StringList `db:"recipients" json:"recipients"`                          // Email addresses
+	SubjectTemplate string     `db:"subject_template" json:"subject_template"`
+	BodyTemplate    string     `db:"body_template" json:"body_template"`
+	Disabled        bool       `db:"disabled" json:"disabled"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (NotificationChannel) TableName() string { return "notification_channels" }
+
+// NotificationRoute represents the notification_routes table: which notifications go to which channel.
+// Empty kind and scope fields match everything.
+type NotificationRoute struct {
+	ID             int64      `db:"id" json:"id"`
+	Name           string     `db:"name" json:"name" binding:"required"`
+	ChannelID      int64      `db:"channel_id" json:"channel_id" binding:"required"`
+	MinSeverity    string     `db:"min_severity" json:"min_severity" binding:"required,oneof=info warning critical"`
+	Kinds          StringList `db:"kinds" json:"kinds"` // alert_firing, alert_resolved, device_status
+	ScopeDeviceIDs Int64List  `db:"scope_device_ids" json:"scope_device_ids"`
+	ScopeTags      StringList `db:"scope_tags" json:"scope_tags"`
+	ScopePluginID  string     `db:"scope_plugin_id" json:"scope_plugin_id"`
+	Disabled       bool       `db:"disabled" json:"disabled"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (NotificationRoute) TableName() string { return "notification_routes" }
+
+// Matches reports whether the route takes a notification.
+func (r *NotificationRoute) Matches(n *Notification) bool {
+	if r.Disabled || !SeverityAtLeast(n.Severity, r.MinSeverity) {
+		return false
+	}
+	if len(r.Kinds) > 0 && !containsString(r.Kinds, n.Kind) {
+		return false
+	}
+	if len(r.ScopeDeviceIDs) > 0 && !containsInt64(r.ScopeDeviceIDs, n.DeviceID) {
+		return false
+	}
+	if r.ScopePluginID != "" && r.ScopePluginID != n.PluginID {
+		return false
+	}
+	for _, tag := range r.ScopeTags {
+		if !containsString(n.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// NotificationDelivery represents the notification_deliveries table: one rendered message
+// for one channel, with its retry state.
+type NotificationDelivery struct {
+	ID            int64      `db:"id" json:"id"`
+	ChannelID     int64      `db:"channel_id" json:"channel_id"`
+	RouteID       int64      `db:"route_id" json:"route_id"`
+	Kind          string     `db:"kind" json:"kind"`
+	Severity      string     `db:"severity" json:"severity"`
+	DeviceID      int64      `db:"device_id" json:"device_id,omitempty"`
+	Recipient     string     `db:"recipient" json:"recipient,omitempty"` // On-call recipient for escalations
+	Subject       string     `db:"subject" json:"subject"`
+	Body          string     `db:"body" json:"body"`
+	Status        string     `db:"status" json:"status"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	LastError     string     `db:"last_error" json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `db:"next_attempt_at" json:"next_attempt_at,omitempty"`
+	SentAt        *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (NotificationDelivery) TableName() string { return "notification_deliveries" }
+
+// DeliveryListRequest is the payload for OpListDeliveries. Zero fields don't filter.
+type DeliveryListRequest struct {
+	Status    string
+	ChannelID int64
+	Limit     int
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"errors"
+	"time"
+
+	"nms/pkg/plugin"
+)
+
 // Operation types for request-reply communication
 const (
 	OpList   = "list"
@@ -9,15 +16,85 @@ const (
 	OpDelete = "delete"
 	OpQuery  = "query" // For metrics
 
+	// Metrics operations
+	OpSearchMetrics   = "search_metrics"    // Find samples matching a value predicate, Payload: *MetricSearch
+	OpTopMetrics      = "top_metrics"       // Rank devices by an aggregated path, Payload: *TopMetricsRequest
+	OpLatestMetrics   = "latest_metrics"    // Newest poll result of every monitored device, Payload: max age time.Duration
+	OpListMetricPaths = "list_metric_paths" // Numeric leaf paths device ID reported within a day, from the 1m rollup tier
+	OpGetLatest       = "get_latest"        // Cached newest poll result of devices, Payload: *LatestQuery
+
 	// Scheduler/Poller operations
 	OpGetBatch         = "get_batch"         // Batch lookup by IDs, returns devices split by should_ping
+	OpLookupDevices    = "lookup_devices"    // Cached devices by IDs whatever their status, returns map[int64]Device copies
 	OpGetCredential    = "get_credential"    // Get credential by profile ID
-	OpDeactivateDevice = "deactivate_device" // Deactivate a device (set status to inactive)
+	OpDeactivateDevice = "deactivate_device" // Deactivate a device (set status to inactive), Payload: reason string
+	OpGetPollStats     = "get_poll_stats"    // Get scheduler dispatch counters (ID=0 for all devices)
+	OpSchedulerStatus  = "scheduler_status"  // Get queue snapshot and last tick timings, Payload: deadline limit int
+	OpGetSchedule      = "get_schedule"      // Get a device's next deadline and last dispatch outcome
+
+	// Poller operations
+	OpPollNow = "poll_now" // Poll a single device immediately, bypassing the deadline queue
+
+	// Recovery operations
+	OpGetInactiveDevices = "get_inactive_devices" // List inactive devices from cache, except those deactivated through the API
+	OpActivateDevice     = "activate_device"      // Reactivate a device (set status to active), Payload: reason string
+	OpListTransitions    = "list_transitions"     // List status transitions for a device
+	OpSearchTransitions  = "search_transitions"   // Status transitions of many devices within a range, Payload: *TransitionQuery
+
+	// Availability operations
+	OpAvailabilityReport = "availability_report" // Uptime/MTTR/MTBF report, Payload: *AvailabilityReportRequest
+
+	// Failure policy operations
+	OpResolveFailurePolicy = "resolve_failure_policy" // Effective policy for device ID (device, then discovery profile); nil if unassigned
+	OpAssignFailurePolicy  = "assign_failure_policy"  // Assign policy to EntityType "Device"/"DiscoveryProfile", Payload: *int64 (nil clears)
+	OpDryRunPolicy         = "dry_run_policy"         // Evaluate a policy against FailureService history, Payload: *PolicyDryRunRequest
+	OpGetDegradedDevices   = "get_degraded_devices"   // List degraded devices from cache
+	OpDegradeDevice        = "degrade_device"         // Set status to degraded (still polled), Payload: reason string
+	OpAlertDevice          = "alert_device"           // Notify that an alert-only policy triggered, status unchanged, Payload: reason string
+
+	// Flap detection operations
+	OpGetFlapStatus   = "get_flap_status"   // Live flap score and recent checks of device ID (FailureService)
+	OpListFlapHistory = "list_flap_history" // Flap start/stop history of device ID (EntityService)
+	OpGetFlapping     = "get_flapping"      // Flap state of flapping devices, restored from history at startup (EntityService)
+
+	// Alerting operations
+	OpListAlerts       = "list_alerts"       // List alert history, Payload: *AlertListRequest
+	OpGetAlert         = "get_alert"         // Get one alert instance by ID
+	OpAcknowledgeAlert = "acknowledge_alert" // Acknowledge an open alert, Payload: username string
+	OpResolveAlert     = "resolve_alert"     // Resolve an open alert by hand, Payload: username string
+	OpCreateSilence    = "create_silence"    // Mute matching alerts, Payload: *AlertSilence
+	OpListSilences     = "list_silences"     // List silences that have not ended
+	OpExpireSilence    = "expire_silence"    // End a silence now
+
+	// Notification operations
+	OpTestChannel    = "test_channel"    // Send a test message through a notification channel
+	OpListDeliveries = "list_deliveries" // List the delivery log, Payload: *DeliveryListRequest
+	OpRetryDelivery  = "retry_delivery"  // Queue a failed delivery for another attempt
+
+	// Anomaly operations
+	OpListAnomalies = "list_anomalies" // List anomaly episodes, Payload: *AnomalyListRequest
+	OpGetBaselines  = "get_baselines"  // Current baselines of device ID
+
+	// Retention operations
+	OpListPartitions = "list_partitions" // List metrics partitions with their sizes
+
+	// Export operations
+	OpExportStats = "export_stats" // Queue and delivery counters of every export sink
+
+	// Escalation operations
+	OpResolveOnCall = "resolve_oncall" // Who is on call for schedule ID, Payload: time.Time
+
+	// Dependency operations
+	OpSetParent    = "set_parent"    // Set or clear a device's parent, Payload: *int64 (nil clears)
+	OpListChildren = "list_children" // List devices whose parent is ID
+
+	// Stream operations
+	OpStreamSubscribe = "stream_subscribe" // Open a live event subscription, Payload: *StreamSubscribeRequest
 )
 
 // Request is a point-to-point message with reply channel for synchronous communication
 type Request struct {
-	Operation  string        // list, get, create, update, delete, query, get_batch, get_credential
+	Operation  string        // list, get, create, update, delete, query, get_batch, get_credential, ...
 	EntityType string        // "Device", "CredentialProfile", "DiscoveryProfile", "Metric"
 	ID         int64         // For get/update/delete
 	IDs        []int64       // For batch operations (get_batch)
@@ -37,3 +114,32 @@ type BatchDeviceResponse struct {
 	ToPing []*Device // Devices that require fping check (should_ping=true)
 	ToSkip []*Device // Devices that skip fping (should_ping=false)
 }
+
+// PollNowRequest is the payload for OpPollNow
+type PollNowRequest struct {
+	Store   bool          // Also write the result through MetricsService
+	Timeout time.Duration // Stop waiting for the plugin after this long (0 waits indefinitely)
+}
+
+// Errors returned for OpPollNow, so the API can tell client errors from poller failures
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrPluginNotFound = errors.New("plugin not found")
+	ErrPluginFailed   = errors.New("plugin failed")  // Returned with the PollNowResponse holding stderr
+	ErrPollTimeout    = errors.New("poll timed out") // The plugin did not finish within PollNowRequest.Timeout
+)
+
+// PollNowResponse reports an on-demand poll: availability, plugin output and timings
+type PollNowResponse struct {
+	DeviceID         int64          `json:"device_id"`
+	PingChecked      bool           `json:"ping_checked"`
+	Reachable        bool           `json:"reachable"`
+	PingDurationMs   int64          `json:"ping_duration_ms"`
+	PluginID         string         `json:"plugin_id"`
+	PluginDurationMs int64          `json:"plugin_duration_ms"`
+	Result           *plugin.Result `json:"result,omitempty"`
+	Stderr           string         `json:"stderr,omitempty"`
+	Error            string         `json:"error,omitempty"`
+	Stored           bool           `json:"stored"`
+	PolledAt         time.Time      `json:"polled_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// MetricPartition describes one partition of the metrics table.
+type MetricPartition struct {
+	Name          string     `db:"name" json:"name"`
+	RangeStart    *time.Time `db:"range_start" json:"range_start"` // Nil for a partition starting at MINVALUE
+	RangeEnd      *time.Time `db:"range_end" json:"range_end"`     // Exclusive
+	TotalBytes    int64      `db:"total_bytes" json:"total_bytes"` // Table, indexes and TOAST
+	EstimatedRows int64      `db:"estimated_rows" json:"estimated_rows"`
+}
+
+// PartitionReport is the admin view of metrics storage.
+type PartitionReport struct {
+	Interval      string             `json:"interval"`       // day or week
+	RetentionDays int                `json:"retention_days"` // Global retention, 0 keeps everything
+	TotalBytes    int64              `json:"total_bytes"`
+	Partitions    []*MetricPartition `json:"partitions"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Scheduler overload policies applied when the Poller cannot accept a batch
+const (
+	OverloadPolicySkip     = "skip"     // Drop the batch and count the polls as skipped
+	OverloadPolicyCoalesce = "coalesce" // Hold the batch and merge it with the next dispatch
+)
+
+// DevicePollStats holds per-device dispatch accounting kept by the Scheduler.
+// Lag is the time between a device's deadline and the moment it was handed to the Poller.
+// Health events are deferred while HealthMonitor is busy and only dropped when the backlog overflows.
+type DevicePollStats struct {
+	DeviceID             int64     `json:"device_id"`
+	Dispatched           int64     `json:"dispatched"`
+	LatePolls            int64     `json:"late_polls"`
+	SkippedPolls         int64     `json:"skipped_polls"`
+	LastLagMs            int64     `json:"last_lag_ms"`
+	MaxLagMs             int64     `json:"max_lag_ms"`
+	LastDispatch         time.Time `json:"last_dispatch,omitzero"`
+	HealthEventsDeferred int64     `json:"health_events_deferred"`
+	HealthEventsDropped  int64     `json:"health_events_dropped"`
+}
+
+// DeviceBackoff describes the adaptive polling interval of a device.
+// The effective interval grows with consecutive ping/poll failures and resets on the first success.
+type DeviceBackoff struct {
+	DeviceID                 int64 `json:"-"`
+	EffectiveIntervalSeconds int   `json:"effective_interval_seconds"`
+	FailureStreak            int   `json:"failure_streak"`
+}
+
+// Last dispatch outcomes reported per device
+const (
+	DispatchOutcomeDispatched = "dispatched"  // Handed to the Poller
+	DispatchOutcomeCoalesced  = "coalesced"   // Held back while the Poller is saturated
+	DispatchOutcomeSkipped    = "skipped"     // Dropped while the Poller is saturated
+	DispatchOutcomePingFailed = "ping_failed" // Not dispatched because fping failed
+	DispatchOutcomeBlocked    = "blocked"     // Not dispatched because a parent device is unreachable
+)
+
+// SchedulerTickStats describes the most recent schedule() run.
+type SchedulerTickStats struct {
+	StartedAt       time.Time `json:"started_at"`
+	DurationMs      int64     `json:"duration_ms"`
+	FpingDurationMs int64     `json:"fping_duration_ms"`
+	Due             int       `json:"due"`
+	StaleDropped    int       `json:"stale_dropped"`
+	Dispatched      int       `json:"dispatched"`
+	AvgLagMs        int64     `json:"avg_lag_ms"`
+	MaxLagMs        int64     `json:"max_lag_ms"`
+}
+
+// DeadlineInfo is a queued deadline for a device.
+type DeadlineInfo struct {
+	DeviceID int64     `json:"device_id"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// SchedulerStatus is a snapshot of the Scheduler's queue and last tick.
+type SchedulerStatus struct {
+	QueueLength      int                 `json:"queue_length"`
+	TrackedDevices   int                 `json:"tracked_devices"`
+	StaleEntries     int                 `json:"stale_entries"` // Duplicate queue entries left by lazy management
+	PendingCoalesced int                 `json:"pending_coalesced"`
+	PendingHealth    int                 `json:"pending_health"` // Health events waiting for HealthMonitor
+	OverloadPolicy   string              `json:"overload_policy"`
+	TickIntervalSec  int                 `json:"tick_interval_sec"`
+	LastTick         *SchedulerTickStats `json:"last_tick,omitempty"`
+	NextDeadlines    []DeadlineInfo      `json:"next_deadlines"`
+}
+
+// DeviceSchedule is the Scheduler's view of a single device.
+type DeviceSchedule struct {
+	DeviceID                 int64            `json:"device_id"`
+	NextDeadline             time.Time        `json:"next_deadline"`
+	EffectiveIntervalSeconds int              `json:"effective_interval_seconds"`
+	FailureStreak            int              `json:"failure_streak"`
+	LastOutcome              string           `json:"last_outcome,omitempty"`
+	LastOutcomeAt            time.Time        `json:"last_outcome_at,omitzero"`
+	Pending                  bool             `json:"pending"`
+	BlockedBy                int64            `json:"blocked_by,omitempty"` // Unreachable ancestor, see DeviceDependency
+	Stats                    *DevicePollStats `json:"stats,omitempty"`
+}
+
+// DependencyUnreachable marks a device whose failures are attributed to an unreachable parent.
+const DependencyUnreachable = "unreachable_by_dependency"
+
+// DeviceDependency is the Scheduler's dependency verdict for a device.
+// BlockedBy is the highest unreachable ancestor (the root cause); 0 means the device is no longer blocked.
+type DeviceDependency struct {
+	DeviceID  int64     `json:"-"`
+	State     string    `json:"state"`
+	BlockedBy int64     `json:"blocked_by"`
+	Since     time.Time `json:"since"`
+}
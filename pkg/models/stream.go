@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Stream event types
+const (
+	StreamPollResult   = "poll_result"   // Data: json.RawMessage poll data, or map of path to value when paths are filtered
+	StreamDeviceStatus = "device_status" // Data: *StreamStatusChange
+	StreamDiscovery    = "discovery"     // Data: *StreamDiscoveryFinding
+	StreamFailure      = "failure"       // Data: *StreamFailureInfo
+)
+
+// StreamEvent is one entry of the live event stream. ID is assigned by StreamService.
+type StreamEvent struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	DeviceID  int64     `json:"device_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// StreamStatusChange is a device status transition.
+type StreamStatusChange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// StreamDiscoveryFinding is a device answering a discovery run.
+type StreamDiscoveryFinding struct {
+	DiscoveryProfileID int64  `json:"discovery_profile_id"`
+	Target             string `json:"target"`
+	Port               int    `json:"port"`
+	Hostname           string `json:"hostname"`
+	New                bool   `json:"new"` // False when the device already existed
+}
+
+// StreamFailureInfo is a ping or poll failure as reported to FailureService.
+type StreamFailureInfo struct {
+	Reason    string `json:"reason"`               // ping, poll or dependency
+	BlockedBy int64  `json:"blocked_by,omitempty"` // Unreachable ancestor (reason dependency)
+}
+
+// StreamFilter selects the events a subscriber receives. Empty lists match everything.
+type StreamFilter struct {
+	DeviceIDs []int64
+	Types     []string
+	Paths     []string // Reduces poll results to these paths; results holding none of them are skipped
+}
+
+// StreamSubscribeRequest opens a subscription, replaying buffered events after LastEventID.
+type StreamSubscribeRequest struct {
+	Filter      StreamFilter
+	LastEventID uint64 // From the client's Last-Event-ID; 0 replays nothing
+}
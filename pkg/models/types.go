@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a []string stored as a JSONB array.
+type StringList []string
+
+// Value implements driver.Valuer; nil is stored as an empty array.
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+// Scan implements sql.Scanner.
+func (l *StringList) Scan(src any) error {
+	return scanJSON(src, (*[]string)(l))
+}
+
+// Int64List is a []int64 stored as a JSONB array.
+type Int64List []int64
+
+// Value implements driver.Valuer; nil is stored as an empty array.
+func (l Int64List) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]int64(l))
+}
+
+// Scan implements sql.Scanner.
+func (l *Int64List) Scan(src any) error {
+	return scanJSON(src, (*[]int64)(l))
+}
+
+// scanJSON decodes a JSONB column into dest.
+func scanJSON(src any, dest any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON list", src)
+	}
+}
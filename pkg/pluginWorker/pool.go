@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os/exec"
 	"sync"
+	"time"
 )
 
 // PluginWorkerPool is a generic pluginWorker pool that executes plugin binaries with batched tasks
@@ -15,14 +17,25 @@ type PluginWorkerPool[T any, R any] struct {
 	poolName    string   // For logging
 	args        []string // Continuous arguments for every execution
 
-	jobChan    chan Job[T]
+	jobChan    chan Job[T, R]
 	resultChan chan []R
 }
 
 // Job represents a batch of tasks for a single plugin
-type Job[T any] struct {
+type Job[T any, R any] struct {
 	BinPath string // Absolute path to plugin binary
 	Tasks   []T
+
+	// ReplyCh receives the execution report instead of the shared result channel (synchronous jobs)
+	ReplyCh chan JobResult[R]
+}
+
+// JobResult is the execution report for a synchronous job
+type JobResult[R any] struct {
+	Results  []R
+	Stderr   string
+	Duration time.Duration
+	Err      error
 }
 
 // NewPool creates a new generic pluginWorker pool
@@ -31,7 +44,7 @@ func NewPool[T any, R any](workerCount int, poolName string, bufferSize int, arg
 		workerCount: workerCount,
 		poolName:    poolName,
 		args:        args,
-		jobChan:     make(chan Job[T], bufferSize),
+		jobChan:     make(chan Job[T, R], bufferSize),
 		resultChan:  make(chan []R, bufferSize),
 	}
 }
@@ -56,12 +69,24 @@ func (pool *PluginWorkerPool[T, R]) Start(ctx context.Context) {
 
 // Submit sends a batch of tasks to the pool with the plugin binary path
 func (pool *PluginWorkerPool[T, R]) Submit(binPath string, tasks []T) {
-	pool.jobChan <- Job[T]{
+	pool.jobChan <- Job[T, R]{
 		BinPath: binPath,
 		Tasks:   tasks,
 	}
 }
 
+// SubmitSync sends a batch of tasks and returns a channel that receives its execution report.
+// The job shares workers with regular submissions but its results bypass Results().
+func (pool *PluginWorkerPool[T, R]) SubmitSync(binPath string, tasks []T) <-chan JobResult[R] {
+	replyCh := make(chan JobResult[R], 1)
+	pool.jobChan <- Job[T, R]{
+		BinPath: binPath,
+		Tasks:   tasks,
+		ReplyCh: replyCh,
+	}
+	return replyCh
+}
+
 // Results returns the channel for receiving results
 func (pool *PluginWorkerPool[T, R]) Results() <-chan []R {
 	return pool.resultChan
@@ -84,7 +109,17 @@ func (pool *PluginWorkerPool[T, R]) worker(ctx context.Context, id int, wg *sync
 				return
 			}
 
-			results := pool.executePlugin(job)
+			start := time.Now()
+			results, stderr, err := pool.executePlugin(job)
+			if job.ReplyCh != nil {
+				job.ReplyCh <- JobResult[R]{
+					Results:  results,
+					Stderr:   stderr,
+					Duration: time.Since(start),
+					Err:      err,
+				}
+				continue
+			}
 			pool.resultChan <- results
 		}
 	}
@@ -92,15 +127,16 @@ func (pool *PluginWorkerPool[T, R]) worker(ctx context.Context, id int, wg *sync
 
 // todo  rename pluginWorker to meaningful name
 
-// executePlugin runs the plugin binary with the batch of tasks
-func (pool *PluginWorkerPool[T, R]) executePlugin(job Job[T]) []R {
+// executePlugin runs the plugin binary with the batch of tasks.
+// Returns the plugin's stderr alongside results so synchronous callers can surface it.
+func (pool *PluginWorkerPool[T, R]) executePlugin(job Job[T, R]) ([]R, string, error) {
 	slog.Debug("Executing plugin", "component", pool.poolName, "bin_path", job.BinPath, "task_count", len(job.Tasks))
 
 	// Marshal tasks to JSON
 	inputJSON, err := json.Marshal(job.Tasks)
 	if err != nil {
 		slog.Error("Failed to marshal tasks", "component", pool.poolName, "error", err)
-		return []R{} // Return empty on error
+		return []R{}, "", fmt.Errorf("failed to marshal tasks: %w", err) // Return empty on error
 	}
 
 	// Execute plugin
@@ -112,16 +148,16 @@ func (pool *PluginWorkerPool[T, R]) executePlugin(job Job[T]) []R {
 
 	if err := cmd.Run(); err != nil {
 		slog.Error("Plugin failed", "component", pool.poolName, "bin_path", job.BinPath, "error", err, "stderr", stderr.String())
-		return []R{} // Return empty on error
+		return []R{}, stderr.String(), fmt.Errorf("plugin failed: %w", err) // Return empty on error
 	}
 
 	// Parse results
 	var results []R
 	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
 		slog.Error("Failed to parse results", "component", pool.poolName, "error", err)
-		return []R{} // Return empty on error
+		return []R{}, stderr.String(), fmt.Errorf("failed to parse results: %w", err) // Return empty on error
 	}
 
 	slog.Debug("Plugin returned results", "component", pool.poolName, "bin_path", job.BinPath, "result_count", len(results))
-	return results
+	return results, stderr.String(), nil
 }
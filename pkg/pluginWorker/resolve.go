@@ -0,0 +1,25 @@
+package pluginWorker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ResolveBinary finds the executable for a plugin ID.
+// Tries pluginDir/ID (standalone) then pluginDir/ID/ID (nested).
+func ResolveBinary(pluginDir, pluginID string) (string, error) {
+	binPath := filepath.Join(pluginDir, pluginID)
+	info, err := os.Stat(binPath)
+	if err == nil && !info.IsDir() {
+		return binPath, nil
+	}
+
+	binPath = filepath.Join(pluginDir, pluginID, pluginID)
+	info, err = os.Stat(binPath)
+	if err == nil && !info.IsDir() {
+		return binPath, nil
+	}
+
+	return "", fmt.Errorf("plugin binary not found for %q in %s", pluginID, pluginDir)
+}
@@ -11,6 +11,19 @@ CREATE TABLE IF NOT EXISTS credential_profiles (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- Failure policies (assigned per device or discovery profile)
+CREATE TABLE IF NOT EXISTS failure_policies (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    mode TEXT NOT NULL, -- sliding, consecutive
+    window_seconds INT NOT NULL DEFAULT 0,
+    ping_threshold INT NOT NULL DEFAULT 0, -- 0 disables ping evaluation
+    poll_threshold INT NOT NULL DEFAULT 0, -- 0 disables poll evaluation
+    action TEXT NOT NULL, -- deactivate, alert, degrade
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
 -- Discovery Profiles
 CREATE TABLE IF NOT EXISTS discovery_profiles (
     id BIGSERIAL PRIMARY KEY,
@@ -19,6 +32,8 @@ CREATE TABLE IF NOT EXISTS discovery_profiles (
     port INT NOT NULL,
     credential_profile_id BIGINT NOT NULL REFERENCES credential_profiles(id),
     auto_provision BOOLEAN DEFAULT FALSE,
+    failure_policy_id BIGINT REFERENCES failure_policies(id) ON DELETE SET NULL,
+    metrics_retention_days INT NOT NULL DEFAULT 0, -- 0 uses METRICS_RETENTION_DAYS
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -35,19 +50,326 @@ CREATE TABLE IF NOT EXISTS devices (
     polling_interval_seconds INT DEFAULT 60,
     should_ping BOOLEAN DEFAULT TRUE,
     status TEXT DEFAULT 'discovered',
+    parent_device_id BIGINT REFERENCES devices(id) ON DELETE SET NULL, -- Upstream device this one is reached through
+    failure_policy_id BIGINT REFERENCES failure_policies(id) ON DELETE SET NULL,
+    tags JSONB NOT NULL DEFAULT '[]',
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
--- Metrics
+-- Existing installs created before device dependencies / failure policies / tags
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS parent_device_id BIGINT REFERENCES devices(id) ON DELETE SET NULL;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS failure_policy_id BIGINT REFERENCES failure_policies(id) ON DELETE SET NULL;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE discovery_profiles ADD COLUMN IF NOT EXISTS failure_policy_id BIGINT REFERENCES failure_policies(id) ON DELETE SET NULL;
+ALTER TABLE discovery_profiles ADD COLUMN IF NOT EXISTS metrics_retention_days INT NOT NULL DEFAULT 0;
+
+-- Existing installs with an unpartitioned metrics table: move it aside so it can be
+-- attached below as the first partition
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'metrics' AND relkind = 'r') THEN
+        ALTER TABLE metrics RENAME TO metrics_legacy;
+        ALTER INDEX IF EXISTS idx_metrics_device_time RENAME TO idx_metrics_legacy_device_time;
+        ALTER SEQUENCE IF EXISTS metrics_id_seq RENAME TO metrics_legacy_id_seq;
+    END IF;
+END $$;
+
+-- Metrics, range-partitioned by timestamp (day or week partitions managed by RetentionService)
 CREATE TABLE IF NOT EXISTS metrics (
-    id BIGSERIAL PRIMARY KEY,
+    id BIGSERIAL,
     device_id BIGINT NOT NULL,
     data JSONB NOT NULL,
-    timestamp TIMESTAMPTZ DEFAULT NOW()
+    timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, timestamp)
+) PARTITION BY RANGE (timestamp);
+
+-- Attach the old rows as one partition ending tomorrow; it is dropped once it ages out
+DO $$
+BEGIN
+    IF to_regclass('metrics_legacy') IS NOT NULL
+       AND NOT EXISTS (SELECT 1 FROM pg_inherits WHERE inhrelid = 'metrics_legacy'::regclass) THEN
+        UPDATE metrics_legacy SET timestamp = NOW() WHERE timestamp IS NULL;
+        ALTER TABLE metrics_legacy ALTER COLUMN timestamp SET NOT NULL;
+        ALTER TABLE metrics_legacy ALTER COLUMN id DROP DEFAULT;
+        PERFORM setval('metrics_id_seq', (SELECT COALESCE(MAX(id), 0) + 1 FROM metrics_legacy), false);
+        EXECUTE format('ALTER TABLE metrics ATTACH PARTITION metrics_legacy FOR VALUES FROM (MINVALUE) TO (%L)',
+            date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' + INTERVAL '1 day');
+    END IF;
+END $$;
+
+-- Metric rollups: numeric leaves of metrics.data aggregated per 1 minute, 1 hour and 1 day
+CREATE TABLE IF NOT EXISTS metrics_1m (
+    device_id BIGINT NOT NULL,
+    path TEXT NOT NULL, -- dotted path of a numeric leaf
+    bucket TIMESTAMPTZ NOT NULL, -- bucket start (UTC-aligned)
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    avg DOUBLE PRECISION NOT NULL,
+    count BIGINT NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    last_at TIMESTAMPTZ NOT NULL, -- timestamp of the last sample
+    PRIMARY KEY (device_id, path, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS metrics_1h (
+    device_id BIGINT NOT NULL,
+    path TEXT NOT NULL, -- dotted path of a numeric leaf
+    bucket TIMESTAMPTZ NOT NULL, -- bucket start (UTC-aligned)
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    avg DOUBLE PRECISION NOT NULL,
+    count BIGINT NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    last_at TIMESTAMPTZ NOT NULL, -- timestamp of the last sample
+    PRIMARY KEY (device_id, path, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS metrics_1d (
+    device_id BIGINT NOT NULL,
+    path TEXT NOT NULL, -- dotted path of a numeric leaf
+    bucket TIMESTAMPTZ NOT NULL, -- bucket start (UTC-aligned)
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    avg DOUBLE PRECISION NOT NULL,
+    count BIGINT NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    last_at TIMESTAMPTZ NOT NULL, -- timestamp of the last sample
+    PRIMARY KEY (device_id, path, bucket)
+);
+
+-- Rollup progress: each tier is complete up to rolled_until
+CREATE TABLE IF NOT EXISTS metric_rollup_state (
+    tier TEXT PRIMARY KEY, -- 1m, 1h, 1d
+    rolled_until TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Device status transitions (activation, deactivation, recovery)
+CREATE TABLE IF NOT EXISTS device_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Device availability history as state-change intervals (ended_at NULL = current state)
+CREATE TABLE IF NOT EXISTS device_availability (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    state TEXT NOT NULL, -- up, down, unreachable
+    reason TEXT NOT NULL, -- ping, poll, dependency
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ
+);
+
+-- Flap detection history (one row per start/stop)
+CREATE TABLE IF NOT EXISTS device_flap_history (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    flapping BOOLEAN NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- On-call rotation schedules
+CREATE TABLE IF NOT EXISTS oncall_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    participants JSONB NOT NULL DEFAULT '[]', -- rotation order
+    rotation_hours INT NOT NULL,
+    handoff_at TIMESTAMPTZ NOT NULL, -- start of the first shift
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- On-call overrides (latest created wins when several overlap)
+CREATE TABLE IF NOT EXISTS oncall_overrides (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL REFERENCES oncall_schedules(id) ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Escalation policies (steps: [{delay_minutes, channel_id, schedule_id}])
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    steps JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Threshold alert rules on metric paths
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    path TEXT NOT NULL, -- dotted JSON path into metrics.data
+    condition TEXT NOT NULL DEFAULT 'threshold', -- threshold, anomaly
+    operator TEXT NOT NULL, -- gt, gte, lt, lte, eq, ne
+    threshold DOUBLE PRECISION NOT NULL,
+    deviations DOUBLE PRECISION NOT NULL DEFAULT 0, -- anomaly: k standard deviations
+    for_seconds INT NOT NULL DEFAULT 0,
+    severity TEXT NOT NULL, -- info, warning, critical
+    scope_device_ids JSONB NOT NULL DEFAULT '[]',
+    scope_tags JSONB NOT NULL DEFAULT '[]',
+    scope_plugin_id TEXT NOT NULL DEFAULT '',
+    escalation_policy_id BIGINT REFERENCES escalation_policies(id) ON DELETE SET NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Alert instances opened and resolved by the evaluator
+CREATE TABLE IF NOT EXISTS alert_instances (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    state TEXT NOT NULL, -- firing, resolved
+    value DOUBLE PRECISION NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    fired_at TIMESTAMPTZ NOT NULL,
+    acknowledged_by TEXT NOT NULL DEFAULT '',
+    acknowledged_at TIMESTAMPTZ,
+    silence_id BIGINT,
+    resolved_by TEXT NOT NULL DEFAULT '', -- empty when resolved automatically
+    resolved_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Alert silences (all set matchers must match)
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT REFERENCES devices(id) ON DELETE CASCADE,
+    rule_id BIGINT REFERENCES alert_rules(id) ON DELETE CASCADE,
+    label TEXT NOT NULL DEFAULT '', -- device tag
+    comment TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Existing installs created before the alert lifecycle
+ALTER TABLE alert_instances ADD COLUMN IF NOT EXISTS acknowledged_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE alert_instances ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMPTZ;
+ALTER TABLE alert_instances ADD COLUMN IF NOT EXISTS silence_id BIGINT;
+ALTER TABLE alert_instances ADD COLUMN IF NOT EXISTS resolved_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT 'threshold';
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS deviations DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS escalation_policy_id BIGINT REFERENCES escalation_policies(id) ON DELETE SET NULL;
+
+-- Escalation progress per alert
+CREATE TABLE IF NOT EXISTS alert_escalations (
+    alert_id BIGINT PRIMARY KEY REFERENCES alert_instances(id) ON DELETE CASCADE,
+    policy_id BIGINT NOT NULL,
+    step INT NOT NULL, -- steps already notified
+    escalated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Rolling per-device metric baselines (EWMA snapshot, restored on start)
+CREATE TABLE IF NOT EXISTS metric_baselines (
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    state JSONB NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (device_id, path)
 );
 
+-- Anomaly episodes (ended_at NULL = still anomalous)
+CREATE TABLE IF NOT EXISTS metric_anomalies (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    mean DOUBLE PRECISION NOT NULL,
+    stddev DOUBLE PRECISION NOT NULL,
+    zscore DOUBLE PRECISION NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Notification channels (webhook, slack, teams, email)
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL DEFAULT '', -- encrypted HMAC key
+    recipients JSONB NOT NULL DEFAULT '[]',
+    subject_template TEXT NOT NULL DEFAULT '',
+    body_template TEXT NOT NULL DEFAULT '',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Notification routing rules (severity, kind and scope to channel)
+CREATE TABLE IF NOT EXISTS notification_routes (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    min_severity TEXT NOT NULL, -- info, warning, critical
+    kinds JSONB NOT NULL DEFAULT '[]',
+    scope_device_ids JSONB NOT NULL DEFAULT '[]',
+    scope_tags JSONB NOT NULL DEFAULT '[]',
+    scope_plugin_id TEXT NOT NULL DEFAULT '',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Notification delivery log with retry state
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    route_id BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    device_id BIGINT NOT NULL DEFAULT 0,
+    recipient TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL, -- pending, sent, retrying, failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE notification_deliveries ADD COLUMN IF NOT EXISTS recipient TEXT NOT NULL DEFAULT '';
+
 -- Indexes
 CREATE INDEX IF NOT EXISTS idx_metrics_device_time ON metrics(device_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_metrics_data ON metrics USING GIN (data jsonb_path_ops); -- equality search by containment
+CREATE INDEX IF NOT EXISTS idx_metrics_1m_bucket ON metrics_1m(bucket);
+CREATE INDEX IF NOT EXISTS idx_metrics_1h_bucket ON metrics_1h(bucket);
+CREATE INDEX IF NOT EXISTS idx_metrics_1d_bucket ON metrics_1d(bucket);
+CREATE INDEX IF NOT EXISTS idx_metrics_1m_path_bucket ON metrics_1m(path, bucket); -- top-N ranking across devices
+CREATE INDEX IF NOT EXISTS idx_metrics_1h_path_bucket ON metrics_1h(path, bucket);
+CREATE INDEX IF NOT EXISTS idx_metrics_1d_path_bucket ON metrics_1d(path, bucket);
 CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(status);
-CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_ip_port ON devices(ip_address, port);
\ No newline at end of file
+CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_ip_port ON devices(ip_address, port);
+CREATE INDEX IF NOT EXISTS idx_devices_parent ON devices(parent_device_id);
+CREATE INDEX IF NOT EXISTS idx_device_transitions_device ON device_transitions(device_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_device_transitions_created ON device_transitions(created_at); -- transitions of all devices in a range (Grafana annotations)
+CREATE INDEX IF NOT EXISTS idx_device_availability_device ON device_availability(device_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_device_flap_history_device ON device_flap_history(device_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_instances_state ON alert_instances(state, fired_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_instances_device ON alert_instances(device_id, fired_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_silences_ends ON alert_silences(ends_at);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due ON notification_deliveries(next_attempt_at) WHERE status IN ('pending', 'retrying');
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_created ON notification_deliveries(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_oncall_overrides_schedule ON oncall_overrides(schedule_id, ends_at);
+CREATE INDEX IF NOT EXISTS idx_metric_anomalies_device ON metric_anomalies(device_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_device_availability_open ON device_availability(device_id) WHERE ended_at IS NULL;
\ No newline at end of file